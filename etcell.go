@@ -5,18 +5,35 @@
 package tcell_ebiten
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
+	"math"
+	"time"
 
 	"github.com/ezrec/tcell_ebiten/font"
 	"github.com/gdamore/tcell/v2"
 	"github.com/hajimehoshi/ebiten/v2"
+	ebiten_text "github.com/hajimehoshi/ebiten/v2/text/v2"
 )
 
+// ErrFontSize is returned for font faces with an invalid cell size.
+var ErrFontSize = errors.New("invalid font cell size")
+
+// ErrGlyph is reported for glyphs that a font face failed to generate.
+var ErrGlyph = errors.New("glyph generation failed")
+
+// default_max_grid_size is the largest grid size, in cells, unless set by
+// SetMaxGridSize.
+var default_max_grid_size = image.Point{X: 1024, Y: 1024}
+
 // ETCell is the ebiten to tcell manager. An empty ETCell is valid,
 // and ready to use. An ETCell should not be copied.
 type ETCell struct {
 	ETCellScreen
+
+	game *ETCellGame // Singleton game, see Game().
 }
 
 // init initializes any default fields.
@@ -30,6 +47,18 @@ func (et *ETCell) init() {
 	if et.blink_cursor_ms == 0 {
 		et.blink_cursor_ms = 750
 	}
+	if et.blink_duty == 0 {
+		et.blink_duty = 0.5
+	}
+	if et.clock == nil {
+		et.clock = time.Now
+	}
+	if et.window_focused == nil {
+		et.window_focused = ebiten.IsFocused
+	}
+	if et.window_closing == nil {
+		et.window_closing = ebiten.IsWindowBeingClosed
+	}
 	if et.rune_fallback == nil {
 		et.rune_fallback = make(map[rune]string)
 	}
@@ -38,6 +67,69 @@ func (et *ETCell) init() {
 	}
 }
 
+// SetCursorFallbackRender sets whether the block cursor is drawn by
+// drawing the cell under it with its foreground and background colors
+// swapped, rather than with a subtractive blend of the cursor color, which
+// some ebiten backends do not render correctly; see
+// ETCellGame.SupportsBlend. The cursor color is not used by the fallback.
+func (et *ETCell) SetCursorFallbackRender(enable bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.cursor_fallback = enable
+
+	return et
+}
+
+// SetRuneWidthFunc sets the function measuring the width of runes, in
+// cells, for environments that disagree on it, such as for East Asian
+// ambiguous width runes. Widths are clamped between 0 and 2. The width is
+// reported by GetContent, and used to lay out text by Print and
+// CursorAdvance, and by DumpHTML. A nil function restores the default,
+// DefaultRuneWidth, with which Print and CursorAdvance measure whole
+// grapheme clusters.
+func (et *ETCell) SetRuneWidthFunc(fn func(r rune) int) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.rune_width = fn
+
+	return et
+}
+
+// SetAttrEnabled sets whether the attributes of attr are drawn, for all
+// cells. Disabled attributes are ignored, as if they were not in the
+// cells' styles; for example, disabling tcell.AttrItalic draws italic
+// text with the normal font style, which is useful when the font has no
+// italic face. All attributes are enabled by default.
+//
+// The change applies to cells as they are next shown; call Sync() to
+// redraw all of the cells.
+func (et *ETCell) SetAttrEnabled(attr tcell.AttrMask, enabled bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if enabled {
+		et.attr_disabled &^= attr
+	} else {
+		et.attr_disabled |= attr
+	}
+
+	return et
+}
+
+// SetHideCursorWhenUnfocused sets whether the cursor is hidden while the
+// window does not have focus, as with native terminals. The default is
+// to always draw the cursor.
+func (et *ETCell) SetHideCursorWhenUnfocused(hide bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.hide_cursor_unfocused = hide
+
+	return et
+}
+
 // SetScreenCursorColor sets the color of the text 'hardware' cursor.
 func (et *ETCell) SetScreenCursorColor(color tcell.Color) *ETCell {
 	et.grid_lock.Lock()
@@ -64,21 +156,396 @@ func (et *ETCell) setScreenSize(cols int, rows int) *ETCell {
 		grid_size.Y = 1
 	}
 
+	max_grid_size := et.max_grid_size
+	if max_grid_size.X <= 0 || max_grid_size.Y <= 0 {
+		max_grid_size = default_max_grid_size
+	}
+	grid_size.X = min(grid_size.X, max_grid_size.X)
+	grid_size.Y = min(grid_size.Y, max_grid_size.Y)
+
 	screenWidth := grid_size.X * et.cell_size.X
 	screenHeight := grid_size.Y * et.cell_size.Y
 	et.layout = image.Rect(0, 0, screenWidth, screenHeight)
 
 	if !grid_size.Eq(et.grid_size) {
 		et.grid_size = grid_size
-		et.grid = make([]cell, et.grid_size.X*et.grid_size.Y)
+		et.grid = make([]cell, et.grid_size.X*et.bufferRows())
+		et.clampScrollOffset()
+
+		et.postResize()
+
+		if et.on_resize != nil {
+			et.on_resize(et.grid_size.X, et.grid_size.Y)
+		}
+	}
+
+	return et
+}
+
+// SetClock sets the time source used for text and cursor blinking.
+// A nil clock restores the default of time.Now.
+func (et *ETCell) SetClock(clock func() time.Time) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if clock == nil {
+		clock = time.Now
+	}
+	et.clock = clock
+
+	return et
+}
+
+// blinkState tracks a blink phase between frames.
+type blinkState struct {
+	valid   bool  // State has been initialized.
+	segment int64 // Count of the 'off' and 'on' segments of the last phase.
+	on      bool  // Last phase.
+}
+
+// blinkOffMs returns the duration of the 'off' segment, which starts each
+// blink cycle, for a blink duty cycle.
+func blinkOffMs(cycle_ms int64, duty float64) int64 {
+	return int64(math.Round(float64(cycle_ms) * (1 - duty)))
+}
+
+// phase returns the blink phase for the time now_ms, of a blink cycle of
+// cycle_ms, that is 'on' for the duty fraction of the cycle. The phase
+// follows the clock, except that it toggles whenever any 'off' or 'on'
+// segment boundary has passed since the last call. This keeps blinking
+// visible at frame rates too low to sample every phase.
+func (bs *blinkState) phase(now_ms int64, cycle_ms int64, duty float64) bool {
+	cycle_ms = max(cycle_ms, 1)
+	off_ms := blinkOffMs(cycle_ms, duty)
+	if off_ms <= 0 {
+		bs.valid = false
+		return false
+	}
+
+	segment := 2 * (now_ms / cycle_ms)
+	if now_ms%cycle_ms >= off_ms {
+		segment++
+	}
+
+	if !bs.valid {
+		bs.valid = true
+		bs.on = segment%2 == 0
+	} else if segment != bs.segment {
+		bs.on = !bs.on
+	}
+	bs.segment = segment
+
+	return bs.on
+}
+
+// blinkFade returns the visibility, from 0 to 1, for the time now_ms, of a
+// fading blink cycle of cycle_ms, that is fully visible for the duty
+// fraction of the cycle. In the 'off' segment, the visibility ramps down to
+// 0, and back up to 1.
+func blinkFade(now_ms int64, cycle_ms int64, duty float64) float32 {
+	cycle_ms = max(cycle_ms, 1)
+	off_ms := blinkOffMs(cycle_ms, duty)
+
+	at := now_ms % cycle_ms
+	if at >= off_ms {
+		return 1
+	}
+
+	return float32(math.Abs(1 - 2*float64(at)/float64(off_ms)))
+}
+
+// blinkPhases returns the text and cursor blink phases for the current
+// clock time. A phase is true during the 'off' segment of its cycle.
+// Fading blinks are never 'off', see blinkFades, nor are disabled blinks.
+func (et *ETCell) blinkPhases() (text_phase bool, cursor_phase bool) {
+	if et.blink_fade || et.blink_disabled {
+		return
+	}
+
+	now := et.clock()
+	now_ms := now.UnixMilli()
+
+	text_phase = et.text_blink.phase(now_ms, et.blink_text_ms, et.blink_duty)
+	cursor_phase = et.cursor_blink.phase(now_ms, et.blink_cursor_ms, et.blink_duty)
+
+	// The cursor is solid while the user is active.
+	if et.cursor_blink_pause > 0 && now.Sub(et.last_activity) < et.cursor_blink_pause {
+		cursor_phase = false
+	}
+
+	return
+}
+
+// blinkFades returns the text and cursor visibility, from 0 to 1, for the
+// current clock time, of fading blinks. Without fading, or with blinking
+// disabled, both are 1.
+func (et *ETCell) blinkFades() (text_fade float32, cursor_fade float32) {
+	if !et.blink_fade || et.blink_disabled {
+		return 1, 1
+	}
+
+	now := et.clock()
+	now_ms := now.UnixMilli()
+
+	text_fade = blinkFade(now_ms, et.blink_text_ms, et.blink_duty)
+	cursor_fade = blinkFade(now_ms, et.blink_cursor_ms, et.blink_duty)
+
+	// The cursor is solid while the user is active.
+	if et.cursor_blink_pause > 0 && now.Sub(et.last_activity) < et.cursor_blink_pause {
+		cursor_fade = 1
+	}
+
+	return
+}
+
+// SetBlinkDutyCycle sets the fraction of the text and cursor blink cycles
+// that they are 'on', from 0 to 1, and whether they fade out and back in
+// during the 'off' part of the cycle, rather than being hidden. The default
+// is 0.5, without fading. A fraction of 0 or less sets the default, and 1
+// or more never blinks 'off'.
+func (et *ETCell) SetBlinkDutyCycle(fraction float64, fade bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
 
-		et.postEvent(tcell.NewEventResize(et.grid_size.X, et.grid_size.Y))
+	if fraction <= 0 {
+		fraction = 0.5
 	}
+	et.blink_duty = min(fraction, 1)
+	et.blink_fade = fade
+
+	return et
+}
+
+// BlinkMode selects how text with tcell.AttrBlink is drawn.
+type BlinkMode int
+
+const (
+	BlinkModeHide      = BlinkMode(iota) // Text is hidden during the 'off' phase.
+	BlinkModeColorSwap                   // Text is drawn in the alert color during the 'off' phase.
+)
+
+// SetBlinkMode sets how text with tcell.AttrBlink is drawn.
+// The default is BlinkModeHide.
+//
+// Blink phases are updated when the game is drawn, and each phase is
+// drawn for at least one frame regardless of the frame rate. If
+// ebiten.SetRunnableOnUnfocused(false) is in effect, the game is not
+// drawn while unfocused, so blinking pauses until focus returns.
+func (et *ETCell) SetBlinkMode(mode BlinkMode) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.blink_mode = mode
+
+	return et
+}
+
+// SetBlinkingEnabled sets whether anything blinks, as a single
+// accessibility switch. When disabled, text with tcell.AttrBlink and
+// blinking cursor styles are drawn steady, regardless of the blink mode
+// and duty cycle. Blinking is enabled by default.
+func (et *ETCell) SetBlinkingEnabled(enabled bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.blink_disabled = !enabled
 
 	return et
 }
 
-// SetScreenSize resizes the text grid layout.
+// EnterKeyMode selects how the Enter key is posted.
+type EnterKeyMode int
+
+const (
+	EnterKeyEnter = EnterKeyMode(iota) // tcell.KeyEnter, with no rune.
+	EnterKeyCR                         // tcell.KeyCR, with the rune '\r'.
+	EnterKeyLF                         // tcell.KeyLF, with the rune '\n'.
+)
+
+// SetEnterKey sets how the Enter key, and the line endings of pasted text,
+// are posted. The default is EnterKeyEnter. As tcell reports control
+// characters as keys, tcell.KeyCR is the same key as tcell.KeyEnter, but
+// with a rune. In all modes, Enter is posted as a single key event.
+func (et *ETCell) SetEnterKey(mode EnterKeyMode) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.enter_key = mode
+
+	return et
+}
+
+// SetEscapeKey sets whether the Escape key is posted. As there are no
+// escape sequences to tell it apart from, unlike in a terminal, Escape is
+// posted as tcell.KeyEscape as soon as it is pressed. Disabling it
+// suppresses Escape key events entirely, for applications that handle
+// Escape themselves, such as by polling ebiten.IsKeyPressed.
+// Escape in pasted text is still posted. The default is enabled.
+func (et *ETCell) SetEscapeKey(enabled bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.no_escape = !enabled
+
+	return et
+}
+
+// SetEscapeDelay does nothing, and is for compatibility with applications
+// ported from terminals, which wait for a delay after Escape to tell it
+// apart from the start of an escape sequence. Escape is always posted
+// immediately; see SetEscapeKey.
+func (et *ETCell) SetEscapeDelay(delay time.Duration) *ETCell {
+	return et
+}
+
+// FocusModel selects how a game gains keyboard focus. Only a game with
+// focus posts key events, and draws its focus indicator.
+type FocusModel int
+
+const (
+	FocusModelHover  = FocusModel(iota) // Focus follows the mouse over the grid.
+	FocusModelClick                     // Focus moves to the game whose grid is clicked or tapped.
+	FocusModelAlways                    // One game always has focus.
+)
+
+// SetFocusModel sets how games gain keyboard focus. The default is
+// FocusModelHover. With FocusModelClick, which suits touch devices, a game
+// keeps focus until the grid of another game of this ETCell is clicked or
+// tapped. With FocusModelAlways, the game with focus keeps it, or if none
+// has it, the first game updated takes it, so that key events are posted
+// once even with several games. Mouse events are posted while the mouse is
+// over the grid, in all models.
+func (et *ETCell) SetFocusModel(model FocusModel) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.focus_model = model
+
+	return et
+}
+
+// SetBlinkAlertColor sets the alert color of blinking text, for
+// BlinkModeColorSwap. The default is tcell.ColorRed.
+func (et *ETCell) SetBlinkAlertColor(color tcell.Color) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.blink_alert_color = color
+
+	return et
+}
+
+// blinkText returns the foreground color, and visibility, of the text of
+// a blinking cell during the given text blink phase.
+func (et *ETCell) blinkText(fg color.RGBA, text_phase bool) (blink_fg color.RGBA, visible bool) {
+	blink_fg = fg
+	visible = true
+
+	if !text_phase {
+		return
+	}
+
+	switch et.blink_mode {
+	case BlinkModeColorSwap:
+		alert := et.blink_alert_color
+		if alert == tcell.ColorDefault {
+			alert = tcell.ColorRed
+		}
+		blink_fg = e_color_of(alert)
+	default:
+		visible = false
+	}
+
+	return
+}
+
+// blinkTextFade returns the foreground color of the text of a fading
+// blinking cell, for a visibility from 0 to 1.
+func (et *ETCell) blinkTextFade(fg color.RGBA, fade float32) color.RGBA {
+	if fade >= 1 {
+		return fg
+	}
+
+	to := color.RGBA{}
+	if et.blink_mode == BlinkModeColorSwap {
+		alert := et.blink_alert_color
+		if alert == tcell.ColorDefault {
+			alert = tcell.ColorRed
+		}
+		to = e_color_of(alert)
+	}
+
+	lerp := func(a, b uint8) uint8 {
+		return uint8(float32(a)*fade + float32(b)*(1-fade))
+	}
+
+	return color.RGBA{R: lerp(fg.R, to.R), G: lerp(fg.G, to.G), B: lerp(fg.B, to.B), A: lerp(fg.A, to.A)}
+}
+
+// SetCursorBlinkPause sets how long the cursor stays solid, rather than
+// blinking, after the last key press, mouse button, or mouse movement.
+// A zero duration (the default) never pauses blinking.
+func (et *ETCell) SetCursorBlinkPause(pause time.Duration) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.cursor_blink_pause = pause
+
+	return et
+}
+
+// SetMagnifier sets a magnifier, which draws the cells within radius
+// cells of the cursor as an inset, scaled by factor and centered on the
+// cursor. This is an accessibility aid, and is disabled by default.
+func (et *ETCell) SetMagnifier(enabled bool, radius int, factor float64) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.magnifier = magnifier{
+		enabled: enabled,
+		radius:  max(radius, 0),
+		factor:  factor,
+	}
+
+	return et
+}
+
+// SetScrollbar sets a scrollbar overlay, drawn width pixels wide on the
+// right edge of the grid, showing the visible rows of the grid buffer, see
+// SetBufferRows. The thumb is drawn in the given color, over a translucent
+// track. It is not drawn while all the buffer rows are visible. The
+// scrollbar is disabled by default, or with a nil color.
+func (et *ETCell) SetScrollbar(enabled bool, width int, color color.Color) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.scrollbar = scrollbar{
+		enabled: enabled && color != nil,
+		width:   max(width, 0),
+		color:   color,
+	}
+
+	return et
+}
+
+// AttachMirror attaches a screen, such as a tcell.SimulationScreen, that
+// mirrors this screen. Subsequent changes to the content and cursor, and
+// calls to Show() and Sync(), are forwarded to the mirror. The mirror is
+// called without this screen locked, and must already be initialized.
+// A nil screen detaches the mirror.
+func (et *ETCell) AttachMirror(mirror tcell.Screen) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.mirror = mirror
+
+	return et
+}
+
+// SetScreenSize resizes the text grid layout. It is safe to call
+// frequently, such as every frame of an animation: the grid, and its
+// content, is only changed if the size is, and resize events are
+// coalesced, see OnResize.
 func (et *ETCell) SetScreenSize(cols int, rows int) *ETCell {
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
@@ -86,6 +553,578 @@ func (et *ETCell) SetScreenSize(cols int, rows int) *ETCell {
 	return et.setScreenSize(cols, rows)
 }
 
+// SetMaxGridSize sets the largest grid size, in cells, so that a huge
+// window or a tiny font does not allocate an enormous grid. Larger sizes,
+// from the window or SetScreenSize, are clamped to it, and the resize
+// event posted is of the clamped size. Layout returns the size of the
+// clamped grid, so ebiten letterboxes it in the window. A size of 0, 0
+// restores the default of 1024x1024 cells. A grid larger than the new
+// size is clamped immediately.
+func (et *ETCell) SetMaxGridSize(cols, rows int) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.max_grid_size = image.Point{X: max(cols, 0), Y: max(rows, 0)}
+	if et.grid_size.X > 0 && et.grid_size.Y > 0 {
+		et.setScreenSize(et.grid_size.X, et.grid_size.Y)
+	}
+
+	return et
+}
+
+// SetLogicalSize fixes the size of the game's render target, in pixels,
+// regardless of the window size, for a crisp pixel-art look. The grid is
+// laid out in the logical size, and Draw scales it to the outside size
+// given to Layout by the largest integer factor that fits, with
+// nearest-neighbor filtering, and centered. A size of 0, 0 (the default)
+// lays out the grid in the outside size.
+func (et *ETCell) SetLogicalSize(width, height int) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.logical_size = image.Point{X: max(width, 0), Y: max(height, 0)}
+
+	return et
+}
+
+// FitToContent shrinks the screen to the bounding box of its non-blank
+// cells, moving them to the top left, which is useful for sizing popups
+// and tooltips drawn as their own game. A blank screen is shrunk to 1x1.
+// The game layout should then be sized to GetGameSize().
+func (et *ETCell) FitToContent() *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	var bounds image.Rectangle
+	for y := 0; y < et.bufferRows(); y++ {
+		for x := 0; x < et.grid_size.X; x++ {
+			if !et.grid[y*et.grid_size.X+x].isBlank() {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	if bounds.Empty() {
+		bounds = image.Rect(0, 0, 1, 1)
+	}
+
+	grid, width := et.grid, et.grid_size.X
+
+	et.setScreenSize(bounds.Dx(), bounds.Dy())
+	et.grid = make([]cell, len(et.grid))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			from := &grid[(bounds.Min.Y+y)*width+bounds.Min.X+x]
+			et.grid[y*et.grid_size.X+x].set(from.Rune, from.Combining, from.Style)
+		}
+	}
+
+	return et
+}
+
+// SetWheelAsKeys sets whether vertical mouse wheel movement is posted as
+// tcell.KeyUp and tcell.KeyDown key events, rather than as the
+// tcell.WheelUp and tcell.WheelDown mouse buttons. This is useful for
+// applications that only handle keyboard navigation.
+func (et *ETCell) SetWheelAsKeys(enable bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.wheel_as_keys = enable
+
+	return et
+}
+
+// SetWheelMultiplier sets the number of vertical wheel events posted per
+// unit of mouse wheel movement, so that fast scrolling moves further.
+// Partial movement is accumulated over frames, and at most 8 events
+// are posted per frame. A multiplier of 0, the default, posts a single
+// event per frame with any vertical wheel movement.
+func (et *ETCell) SetWheelMultiplier(multiplier float64) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.wheel_multiplier = max(0, multiplier)
+	et.wheel_pending = 0
+
+	return et
+}
+
+// SetRasterizeOnDraw sets whether Show() and Sync() always defer resolving
+// glyphs to the next Draw(), as Invalidate() does, so that the font face
+// only rasterizes glyphs for drawing on the ebiten goroutine. Otherwise,
+// glyphs are rasterized on the goroutine calling Show() or Sync().
+func (et *ETCell) SetRasterizeOnDraw(enable bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.rasterize_on_draw = enable
+
+	return et
+}
+
+// SetRedrawOnEvent sets whether the game only draws a new frame when
+// something changed since the last frame, re-blitting the last frame
+// otherwise, to save power on idle screens. Changes are content shown with
+// Show() or Sync(), the cursor, scrolling, blinking, at the blink cadence,
+// and mouse hover and focus. Frames are always drawn while the reveal
+// animation is enabled. After changing other drawing settings, call Sync()
+// to draw a new frame.
+func (et *ETCell) SetRedrawOnEvent(enable bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.redraw_on_event = enable
+
+	return et
+}
+
+// SetRevealAnimation sets a duration over which the text of changed cells
+// fades in when they are shown, for intros and cutscenes. The animation
+// follows the clock set by SetClock. A duration of 0, the default,
+// disables the animation.
+func (et *ETCell) SetRevealAnimation(duration time.Duration) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.reveal_duration = max(0, duration)
+
+	return et
+}
+
+// InvalidateGlyphs drops all cached glyphs, including those generated by
+// the font face, so that they are generated again with the current
+// rendering settings, and repaints the whole screen. The glyphs are dropped
+// on the next Draw(), on the ebiten goroutine, so that no glyph is
+// deallocated while it is being drawn. Glyphs set with
+// font.CacheFont.SetGlyph are kept.
+func (et *ETCell) InvalidateGlyphs() *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.invalidate_glyphs = true
+	et.invalidated = true
+
+	return et
+}
+
+// SetCellCache sets the maximum number of pre-composited cell images to
+// cache, or 0 (the default) to disable caching. Cached cells are rendered
+// once, with their background, glyph and lines, and then drawn with a single
+// image each frame, which is faster when many cells look alike. Content
+// that rarely repeats, such as noise, is slower, as every new cell is
+// rendered twice. Cells are evicted least recently used first. Blinking
+// cells, and cells being revealed, are not cached.
+func (et *ETCell) SetCellCache(entries int) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.cell_cache_entries = max(entries, 0)
+
+	return et
+}
+
+// Paste posts text from the clipboard, or any other source, as pasted
+// input. If paste is enabled, the text is posted as key events bracketed
+// by tcell.EventPaste start and end events, otherwise as plain key events.
+// The keys of the paste are not interleaved with any other input.
+func (et *ETCell) Paste(text string) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.paste(text)
+
+	return et
+}
+
+// SetKeyboardMouse sets whether a virtual mouse is driven by the keyboard,
+// for users without a mouse. Bound keys move the virtual mouse by cells, or
+// click its buttons, posting tcell.EventMouse events, and are not posted
+// as key events. The virtual mouse cell is outlined in the cursor color.
+// This is independent of the real mouse, and works while the window has
+// focus. Without bindings, DefaultKeyboardMouseBindings are used.
+func (et *ETCell) SetKeyboardMouse(enable bool, bindings ...KeyboardMouseBinding) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if len(bindings) == 0 {
+		bindings = DefaultKeyboardMouseBindings
+	}
+
+	et.kbd_mouse = enable
+	et.kbd_mouse_bindings = bindings
+
+	return et
+}
+
+// OnInit sets a callback that is invoked when the screen is initialized by
+// Init(). It is called once per transition, so calling Init() on an already
+// initialized screen does not invoke it again. A nil callback disables
+// notification.
+func (et *ETCell) OnInit(fn func()) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_init = fn
+
+	return et
+}
+
+// OnFini sets a callback that is invoked when the screen is finalized by
+// Fini(). It is called once per transition, so calling Fini() on an already
+// finalized screen does not invoke it again. A nil callback disables
+// notification.
+func (et *ETCell) OnFini(fn func()) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_fini = fn
+
+	return et
+}
+
+// OnBeep sets a callback that is invoked by the screen's Beep(), to sound
+// an alert, such as with the sound package; its error is returned by
+// Beep(). The callback is called without the screen locked. A nil
+// callback makes Beep() silent.
+func (et *ETCell) OnBeep(fn func() error) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_beep = fn
+
+	return et
+}
+
+// OnResize sets a callback that is invoked when the text grid changes size.
+// The callback is in addition to the tcell.EventResize posted to the screen,
+// and is called with the screen locked, so it must not call back into the
+// screen. A nil callback disables notification.
+//
+// The callback is invoked for every change of size, whereas resize events
+// are coalesced: at most one is in the event queue at a time, and it has
+// the size of the grid when it is received.
+func (et *ETCell) OnResize(fn func(cols, rows int)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_resize = fn
+
+	return et
+}
+
+// OnScaleChange sets a callback that is invoked when the device scale
+// factor of the window changes, such as when it is moved to a monitor
+// with a different HiDPI scale. See ETCellGame.MoveToMonitor. Unlike
+// other callbacks, it is called without the screen locked, so that it
+// can replace the font for the new scale with SetFont. A nil callback
+// disables notification.
+func (et *ETCell) OnScaleChange(fn func(scale float64)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_scale_change = fn
+
+	return et
+}
+
+// Trace event names, passed to the function set by SetTraceFunc.
+const (
+	TraceShow     = "show"      // Resolving the styles and glyphs of changed cells, by Show(), Sync() or Draw().
+	TraceGlyph    = "glyph"     // Getting a glyph from the font face, whether generated or cached.
+	TraceGridCopy = "grid_copy" // Copying the grid for drawing, in Draw().
+	TraceDraw     = "draw"      // Drawing the copied grid, cursor and overlays, in Draw().
+)
+
+// SetTraceFunc sets a function that is called with the duration of each
+// render operation, named by the Trace event names, for profiling where
+// the time of drawing large grids goes. Glyphs are traced within show,
+// so their durations are included in it. The function may be called with
+// the screen locked, so it must not call back into the screen. A nil
+// function disables tracing, which then costs nothing.
+func (et *ETCell) SetTraceFunc(fn func(event string, d time.Duration)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.trace = fn
+
+	return et
+}
+
+// OnLinkClick sets a callback that is invoked when the primary mouse button
+// is pressed on a cell whose style has a URL (see tcell.Style.Url). The
+// callback is called with the screen locked, so it must not call back into
+// the screen. A nil callback disables notification.
+func (et *ETCell) OnLinkClick(fn func(url string)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_link_click = fn
+
+	return et
+}
+
+// OnCellHover sets a callback that is invoked when the cell under the mouse
+// changes. When the mouse leaves the grid, the callback is invoked with
+// (-1, -1). The callback is called with the screen locked, so it must not
+// call back into the screen. A nil callback disables notification.
+func (et *ETCell) OnCellHover(fn func(x, y int)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_cell_hover = fn
+
+	return et
+}
+
+// OnRawKey sets a callback that is invoked for every press and release of
+// a key that is not otherwise posted as a tcell event, such as Pause,
+// PrintScreen, or media keys. These bypass the tcell event channel
+// entirely, and are only delivered while the screen has focus. The
+// callback is called with the screen locked, so it must not call back into
+// the screen. A nil callback disables notification.
+func (et *ETCell) OnRawKey(fn func(key ebiten.Key, pressed bool)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_raw_key = fn
+
+	return et
+}
+
+// OnCloseRequest sets a callback that is invoked when the user asks to
+// close the window, such as with its close button, so that the
+// application can confirm before exiting. If the callback returns true,
+// the game exits, as with Exit(nil); if false, the close is cancelled,
+// and the application may call Exit() itself later. A nil callback
+// always exits.
+//
+// ebiten only reports close requests, rather than closing the window
+// itself, after ebiten.SetWindowClosingHandled(true) is called. The
+// callback is called from Update(), without the screen locked, so it
+// may post events, or draw to the screen.
+func (et *ETCell) OnCloseRequest(fn func() bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_close_request = fn
+
+	return et
+}
+
+// SetSuspendInputHandler sets a function that is called on every Update()
+// while the screen is suspended, see ETCellScreen.Suspend, in place of
+// posting input events, so that the host can drive whatever replaced the
+// terminal, such as a native overlay. The function reads the input with
+// ebiten's input functions, such as inpututil.AppendJustPressedKeys. It
+// is called on the ebiten goroutine, without the screen locked, so it may
+// call Resume(). A nil function, the default, ignores input while
+// suspended.
+func (et *ETCell) SetSuspendInputHandler(fn func()) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.suspend_input = fn
+
+	return et
+}
+
+// SetDefaultStyle sets the style drawn for cells in StyleDefault, which
+// includes cells erased by Clear, so that a single call sets the
+// appearance of a cleared screen. It is the same as Screen().SetStyle().
+func (et *ETCell) SetDefaultStyle(style tcell.Style) *ETCell {
+	et.SetStyle(style)
+
+	return et
+}
+
+// SetClearColor sets a color that the grid is cleared to once per frame,
+// before drawing. The backgrounds of cells in the clear color are then
+// not drawn, which is faster for mostly blank screens. This is usually
+// the background color of the default style. A nil color disables clearing.
+func (et *ETCell) SetClearColor(clear color.Color) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.clear_color = clear
+
+	return et
+}
+
+// OnGlyphError sets a callback that is invoked when the font face fails to
+// generate the glyph of a rune, such as when it panics. The rune is then
+// drawn as empty, rather than failing the render. The error wraps ErrGlyph.
+// The callback is called with the screen locked, so it must not call back
+// into the screen. A nil callback disables notification.
+func (et *ETCell) OnGlyphError(fn func(r rune, err error)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_glyph_error = fn
+
+	return et
+}
+
+// OnEventDropped sets a callback that is invoked when an event is dropped,
+// because the event queue is full, such as when the application does not
+// poll events as fast as input arrives. The callback is called with the
+// screen locked, by the goroutine posting the event, so it must not block,
+// nor call back into the screen. A nil callback disables notification.
+func (et *ETCell) OnEventDropped(fn func(ev tcell.Event)) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.on_event_dropped = fn
+
+	return et
+}
+
+// SetGlyphProvider sets a glyph provider, which is consulted before the
+// font face for every rune drawn. The glyph image is tinted with the
+// foreground color, just as a font glyph is, unless the provider's glyphs
+// are set as colored by SetGlyphProviderColored, and must be the cell size.
+//
+// The provider is called on each rune of a changed cell when it is resolved
+// by Show() or Sync(), or by Draw() after Invalidate(), and not on every
+// frame. Caching the returned images is the responsibility of the provider.
+// A nil provider uses only the font face.
+func (et *ETCell) SetGlyphProvider(provider GlyphProvider) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.glyph_provider = provider
+	et.forget()
+
+	return et
+}
+
+// SetGlyphProviderColored sets whether the glyphs of the glyph provider,
+// see SetGlyphProvider, are full color images, such as icons, which are
+// drawn as they are, rather than tinted with the foreground color. The
+// provider can still color its glyphs from the colors it is passed. Color
+// glyphs of the font face are drawn as they are regardless, see
+// font.IsColorGlyph. The default is tinting.
+//
+// The change applies to cells as they are next shown; call Sync() to
+// redraw all of the cells.
+func (et *ETCell) SetGlyphProviderColored(colored bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.glyph_provider_colored = colored
+	et.forget()
+
+	return et
+}
+
+// SetCombiningBlend sets the blend used to composite the glyphs of a
+// rune and its combining runes into a single glyph. The default is
+// BlendMax, which keeps overlapping anti-aliased edges from darkening.
+func (et *ETCell) SetCombiningBlend(blend ebiten.Blend) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.combining_blend = &blend
+	et.forget()
+
+	return et
+}
+
+// SetBoxDrawing sets whether box drawing line and junction runes are drawn
+// synthetically, rather than by the font, so that they join seamlessly
+// across cells. If also connecting, a box drawing rune gains arms towards
+// neighboring box drawing runes that point back into it, so that lines
+// crossing borders form junctions. Connecting requires synthetic drawing.
+func (et *ETCell) SetBoxDrawing(synthetic, connect bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.box_drawing = synthetic
+	et.box_connect = synthetic && connect
+	et.forget()
+
+	return et
+}
+
+// SetLineShaping sets whether the runs of cells of each row that are drawn
+// by the font are shaped as whole lines, rather than glyph by glyph, so
+// that the font's kerning, ligatures and mark positioning apply across the
+// cells. Each cluster is snapped to its cell, so kerning does not drift
+// from the grid, and a ligature of several cells is split across them.
+// Only fonts that can shape text, see [font.ShapeCells], are affected.
+//
+// Shaping is off by default, as it is much slower: a row is shaped again
+// whenever any of its cells changes, and its glyphs are not cached.
+// Cells of glyph providers, synthetic box drawing and enclosing marks, and
+// runes not in the font are drawn glyph by glyph, and break runs.
+func (et *ETCell) SetLineShaping(enable bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.line_shaping = enable
+	et.forget()
+
+	return et
+}
+
+// SetColorFilter sets a function that transforms the foreground and
+// background colors of all cells, after the style's attributes have been
+// applied, such as to simulate or correct for color blindness, or to draw
+// in grayscale, without the application changing its styles. A nil filter
+// restores the default, which leaves the colors unchanged.
+//
+// The change applies to cells as they are next shown; call Sync() to
+// redraw all of the cells.
+func (et *ETCell) SetColorFilter(fn func(color.RGBA) color.RGBA) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.color_filter = fn
+	et.forget()
+
+	return et
+}
+
+// SetBackgroundPattern sets a function that returns an image to fill the
+// background of the cell at x, y of the grid buffer with, in place of its
+// background color, such as a subtle vertical gradient behind a status
+// bar. The image is scaled to the cell. Cells for which it returns nil,
+// and all cells if the function is nil, the default, are filled with
+// their background color.
+//
+// The function is called for every cell drawn, on every frame drawn, from
+// the ebiten goroutine, without the screen locked. It should return
+// images it has already made, rather than making them, and drawing cells
+// with it bypasses the cell cache of SetCellCache, so large grids draw
+// more slowly than with solid colors.
+//
+// The change applies to cells as they are next shown; call Sync() to
+// redraw all of the cells.
+func (et *ETCell) SetBackgroundPattern(fn func(x, y int) *ebiten.Image) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.background_pattern = fn
+	et.forget()
+
+	return et
+}
+
+// SetPixelSnap sets whether the origins of cells, as placed by the GeoM of
+// the game, are rounded to whole pixels. This stops glyphs shimmering as
+// they move between pixels when the grid is scaled or moved by fractional
+// amounts, such as during zoom animations. Rotated or skewed transforms
+// are not rounded. Pixel snapping is disabled by default.
+func (et *ETCell) SetPixelSnap(enabled bool) *ETCell {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.pixel_snap = enabled
+
+	return et
+}
+
 // GetGameSize() returns the size of the image to draw (without GeoM scaling)
 func (et *ETCell) GetGameSize() (width, height int) {
 	width = et.layout.Dx()
@@ -93,7 +1132,22 @@ func (et *ETCell) GetGameSize() (width, height int) {
 	return
 }
 
-// SetFont sets the font for the text cells.
+// Metrics returns the cell size, in pixels, and the metrics of the font,
+// for laying out the application before the screen is initialized or laid
+// out. If no font has been set, the default font is set, as Init would.
+func (et *ETCell) Metrics() (cell_width, cell_height int, metrics ebiten_text.Metrics) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if et.face == nil {
+		et.setFont(nil)
+	}
+
+	return et.cell_size.X, et.cell_size.Y, et.face.Metrics()
+}
+
+// SetFont sets the font for the text cells. A nil face sets the default
+// font, GoMono in its default size.
 func (et *ETCell) SetFont(face font.Face) *ETCell {
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
@@ -103,16 +1157,44 @@ func (et *ETCell) SetFont(face font.Face) *ETCell {
 	return et
 }
 
-func (et *ETCell) setFont(face font.Face) {
+// SetFontChecked sets the font for the text cells, like SetFont, but
+// reports an error wrapping ErrFontSize if the face has an invalid cell
+// size. The cell size is clamped to a minimum of 1x1 pixels regardless.
+func (et *ETCell) SetFontChecked(face font.Face) (err error) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	err = et.setFont(face)
+
+	return
+}
+
+func (et *ETCell) setFont(face font.Face) (err error) {
+	// The default font is GoMono, in its default size.
+	if face == nil {
+		face, err = font.NewMonoFontFromTTF(nil, 0)
+		if err != nil {
+			return
+		}
+	}
+
 	// Make the layout grid based on the width and height (in pixels) given,
 	// based on the font metrics. We use the rune 'O' to determine the nominal
 	// bounding box for the character set.
 	et.face = face
 
 	width, height := et.face.Size()
+	if width < 1 || height < 1 {
+		err = fmt.Errorf("%w: %vx%v", ErrFontSize, width, height)
+		width = max(width, 1)
+		height = max(height, 1)
+	}
+
 	et.cell_size = image.Point{X: width, Y: height}
-	et.cell_image = ebiten.NewImage(width, height)
-	et.cell_image.Fill(color.White)
+
+	et.forget()
+
+	return
 }
 
 // Screen returns the singleton tcell.Screen interface for this ETCell wrapper.
@@ -120,7 +1202,29 @@ func (et *ETCell) Screen() *ETCellScreen {
 	return &et.ETCellScreen
 }
 
-// Game returns the a new ebiten.Game interface for this ETCell wrapper.
+// Game returns the singleton ebiten.Game interface for this ETCell wrapper,
+// created on first use. This is the game for the common case of a single
+// game drawing the grid, and is the game used by Run.
+func (et *ETCell) Game() *ETCellGame {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if et.game == nil {
+		et.game = et.NewGame()
+	}
+
+	return et.game
+}
+
+// NewGame returns a new ebiten.Game interface for this ETCell wrapper, for
+// layouts with several games sharing the grid, ie drawn as sub-panels with
+// different transforms. Each game has its own drawing state, but all games
+// post their input to the same screen. For a single game, use Game instead.
+//
+// Panes with different fonts, or different content, each need their own
+// ETCell, which render independently. The host game calls the Layout,
+// Update and Draw methods of the game of each ETCell, sizing each to its
+// pane, and placing it with its GeoM. See examples/panes.
 func (et *ETCell) NewGame() *ETCellGame {
 	return &ETCellGame{ETCell: et}
 }
@@ -133,7 +1237,14 @@ func (et *ETCell) Run(runner func(screen tcell.Screen) error) error {
 		et.Exit(err)
 	}()
 
-	return ebiten.RunGame(et.NewGame())
+	return ebiten.RunGame(et.Game())
+}
+
+// Interrupt posts a *tcell.EventInterrupt with the given data to the
+// screen's event queue. This may be called from any goroutine, for example
+// to wake the application's event loop for a redraw.
+func (et *ETCell) Interrupt(data any) error {
+	return et.PostEvent(tcell.NewEventInterrupt(data))
 }
 
 // Exit the tcell application.
@@ -0,0 +1,427 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"image"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ansiParse is the state of the WriteANSI escape sequence parser.
+type ansiParse int
+
+const (
+	ansi_ground     = ansiParse(iota) // Text.
+	ansi_escape                       // After ESC.
+	ansi_csi                          // In a CSI sequence.
+	ansi_osc                          // In an OSC string.
+	ansi_osc_escape                   // After ESC, in an OSC string.
+)
+
+// ansiState is the terminal state of WriteANSI, kept between writes.
+type ansiState struct {
+	parse   ansiParse
+	params  []byte // Parameter bytes of the CSI sequence.
+	pending []byte // Incomplete UTF-8 sequence at the end of the last write.
+
+	point image.Point // Cursor, in screen cells.
+	saved image.Point // Saved cursor.
+	wrap  bool        // Next printed rune wraps to the next line.
+	style tcell.Style // Style of printed runes.
+}
+
+// WriteANSI writes text with ANSI/VT escape sequences to the screen, as a
+// minimal terminal would. The terminal cursor and style are kept between
+// writes, and start at the top left in the default style. Sequences split
+// between writes are handled. The results are not displayed until Show()
+// or Sync() is called.
+//
+// The terminal is the screen, so its rows are the visible rows of the grid
+// buffer, see SetScrollOffset. With a buffer larger than the screen, lines
+// scrolled off the top of the screen move up into the rows above it, as
+// scrollback, and the top row of the buffer is dropped.
+//
+// The supported subset is:
+//   - Text, wrapped at the right edge, and scrolling the screen up at the
+//     bottom. Combining marks are added to the previous cell.
+//   - CR, LF (which also returns the carriage), BS and HT.
+//   - SGR (CSI m): reset, bold, dim, italic, underline, blink, reverse and
+//     strike through, and their resets, the 8 and 16 palette colors, 256
+//     palette colors (38;5;n) and RGB colors (38;2;r;g;b), and default colors.
+//   - Cursor movement: CUU, CUD, CUF, CUB (CSI A, B, C, D), CHA (CSI G),
+//     VPA (CSI d) and CUP (CSI H, CSI f).
+//   - Erase: ED (CSI J) and EL (CSI K), modes 0, 1 and 2.
+//   - Save and restore cursor: ESC 7, ESC 8, CSI s and CSI u.
+//
+// Other CSI sequences, and OSC strings, are ignored. The screen cursor is
+// not changed, see ANSICursor.
+func (et *ETCellScreen) WriteANSI(data []byte) {
+	et.grid_lock.Lock()
+	mirror := et.mirror
+
+	et.writeANSI(data)
+
+	var cells [][]BufferCell
+	top := et.scroll_offset
+	if mirror != nil {
+		cells = et.screenCells()
+	}
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		for y, row := range cells {
+			for x := range row {
+				mirror.SetContent(x, top+y, row[x].Rune, row[x].Combining, row[x].Style)
+			}
+		}
+	}
+}
+
+// ANSICursor returns the terminal cursor of WriteANSI.
+func (et *ETCellScreen) ANSICursor() (x, y int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return et.ansi.point.X, et.ansi.point.Y
+}
+
+// screenCells returns the contents of the cells of the screen, the
+// visible rows of the grid buffer.
+// The grid lock must be held.
+func (et *ETCellScreen) screenCells() (cells [][]BufferCell) {
+	cells = make([][]BufferCell, et.grid_size.Y)
+	for y := range cells {
+		cells[y] = make([]BufferCell, et.grid_size.X)
+		for x := range cells[y] {
+			c := &et.grid[(et.scroll_offset+y)*et.grid_size.X+x]
+			cells[y][x] = BufferCell{Rune: c.Rune, Combining: c.Combining, Style: c.Style}
+		}
+	}
+	return
+}
+
+// writeANSI parses and applies text with ANSI/VT escape sequences.
+// The grid lock must be held.
+func (et *ETCellScreen) writeANSI(data []byte) {
+	a := &et.ansi
+
+	// The screen may have been resized since the last write.
+	a.point = et.ansiClamp(a.point)
+	a.saved = et.ansiClamp(a.saved)
+
+	if len(a.pending) > 0 {
+		data = append(a.pending, data...)
+		a.pending = nil
+	}
+
+	for len(data) > 0 {
+		b := data[0]
+
+		switch a.parse {
+		case ansi_escape:
+			data = data[1:]
+			a.parse = ansi_ground
+			switch b {
+			case '[':
+				a.parse = ansi_csi
+				a.params = a.params[:0]
+			case ']':
+				a.parse = ansi_osc
+			case '7':
+				a.saved = a.point
+			case '8':
+				a.point = a.saved
+				a.wrap = false
+			}
+			continue
+		case ansi_csi:
+			data = data[1:]
+			if b >= 0x40 && b <= 0x7e {
+				et.ansiCSI(b, string(a.params))
+				a.parse = ansi_ground
+			} else {
+				a.params = append(a.params, b)
+			}
+			continue
+		case ansi_osc, ansi_osc_escape:
+			data = data[1:]
+			switch {
+			case b == 0x07, b == '\\' && a.parse == ansi_osc_escape:
+				a.parse = ansi_ground
+			case b == 0x1b:
+				a.parse = ansi_osc_escape
+			default:
+				a.parse = ansi_osc
+			}
+			continue
+		}
+
+		if b < utf8.RuneSelf {
+			data = data[1:]
+			switch b {
+			case 0x1b:
+				a.parse = ansi_escape
+			case '\r':
+				a.point.X = 0
+				a.wrap = false
+			case '\n':
+				a.point.X = 0
+				a.wrap = false
+				et.ansiLineFeed()
+			case '\b':
+				a.point.X = max(0, a.point.X-1)
+				a.wrap = false
+			case '\t':
+				a.point.X = max(0, min((a.point.X/8+1)*8, et.grid_size.X-1))
+			default:
+				if b >= ' ' && b != 0x7f {
+					et.ansiPrint(rune(b))
+				}
+			}
+			continue
+		}
+
+		if !utf8.FullRune(data) {
+			a.pending = slices.Clone(data)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		et.ansiPrint(r)
+	}
+}
+
+// ansiPrint prints a rune at the terminal cursor.
+// The grid lock must be held.
+func (et *ETCellScreen) ansiPrint(r rune) {
+	a := &et.ansi
+
+	if unicode.In(r, unicode.Mn, unicode.Me) {
+		x := a.point.X
+		if !a.wrap {
+			x--
+		}
+		y := et.scroll_offset + a.point.Y
+		if x >= 0 && x < et.grid_size.X && a.point.Y < et.grid_size.Y {
+			c := &et.grid[y*et.grid_size.X+x]
+			combining := append(slices.Clone(c.Combining), r)
+			et.setContent(x, y, c.Rune, combining, c.Style)
+		}
+		return
+	}
+
+	if a.wrap {
+		a.point.X = 0
+		a.wrap = false
+		et.ansiLineFeed()
+	}
+
+	et.setContent(a.point.X, et.scroll_offset+a.point.Y, r, nil, a.style)
+
+	if a.point.X+1 < et.grid_size.X {
+		a.point.X++
+	} else {
+		a.wrap = true
+	}
+}
+
+// ansiLineFeed moves the terminal cursor down a line, scrolling the
+// screen up at the bottom. The rows of the buffer above the screen are
+// scrolled with it, so that the top row of the screen is kept above it.
+// The grid lock must be held.
+func (et *ETCellScreen) ansiLineFeed() {
+	a := &et.ansi
+
+	if a.point.Y+1 < et.grid_size.Y {
+		a.point.Y++
+		return
+	}
+
+	// Move the rows up to the bottom of the screen up by one, dropping
+	// the top row of the buffer, to be resolved again at their new rows.
+	width := et.grid_size.X
+	bottom := et.scroll_offset + et.grid_size.Y
+	copy(et.grid[:(bottom-1)*width], et.grid[width:bottom*width])
+	for n := 0; n < (bottom-1)*width; n++ {
+		et.grid[n].synced = false
+	}
+	et.ansiErase(0, et.grid_size.Y-1, et.grid_size.X, et.grid_size.Y-1)
+}
+
+// ansiClamp returns a terminal cursor point, clamped to the screen.
+// The grid lock must be held.
+func (et *ETCellScreen) ansiClamp(point image.Point) image.Point {
+	return image.Point{
+		X: max(0, min(point.X, et.grid_size.X-1)),
+		Y: max(0, min(point.Y, et.grid_size.Y-1)),
+	}
+}
+
+// ansiErase erases the cells from x0, y0 up to, but not including,
+// x1, y1, in reading order, of the screen, to the background of the
+// terminal style. x1 is clipped to the screen width, so that erasing never
+// reaches the next row. The grid lock must be held.
+func (et *ETCellScreen) ansiErase(x0, y0, x1, y1 int) {
+	if et.grid_size.X <= 0 || et.grid_size.Y <= 0 {
+		return
+	}
+	x1 = min(x1, et.grid_size.X)
+
+	_, bg, _ := et.ansi.style.Decompose()
+	style := tcell.StyleDefault.Background(bg)
+
+	for n := y0*et.grid_size.X + x0; n < y1*et.grid_size.X+x1; n++ {
+		et.setContent(n%et.grid_size.X, et.scroll_offset+n/et.grid_size.X, ' ', nil, style)
+	}
+}
+
+// ansiCSI applies a CSI sequence.
+// The grid lock must be held.
+func (et *ETCellScreen) ansiCSI(final byte, params string) {
+	a := &et.ansi
+
+	// Private sequences are not supported.
+	if strings.ContainsAny(params, "?<=>") {
+		return
+	}
+
+	var args []int
+	for _, param := range strings.Split(params, ";") {
+		arg, _ := strconv.Atoi(param)
+		args = append(args, arg)
+	}
+
+	// arg returns the n'th argument, or def if it is missing or zero.
+	arg := func(n, def int) int {
+		if n < len(args) && args[n] != 0 {
+			return args[n]
+		}
+		return def
+	}
+
+	width, height := et.grid_size.X, et.grid_size.Y
+
+	switch final {
+	case 'm':
+		a.style = ansiSGR(a.style, args)
+		return
+	case 'A':
+		a.point.Y -= arg(0, 1)
+	case 'B':
+		a.point.Y += arg(0, 1)
+	case 'C':
+		a.point.X += arg(0, 1)
+	case 'D':
+		a.point.X -= arg(0, 1)
+	case 'G':
+		a.point.X = arg(0, 1) - 1
+	case 'd':
+		a.point.Y = arg(0, 1) - 1
+	case 'H', 'f':
+		a.point = image.Point{X: arg(1, 1) - 1, Y: arg(0, 1) - 1}
+	case 's':
+		a.saved = a.point
+	case 'u':
+		a.point = a.saved
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			et.ansiErase(a.point.X, a.point.Y, width, height-1)
+		case 1:
+			et.ansiErase(0, 0, a.point.X+1, a.point.Y)
+		case 2:
+			et.ansiErase(0, 0, width, height-1)
+		}
+		return
+	case 'K':
+		switch arg(0, 0) {
+		case 0:
+			et.ansiErase(a.point.X, a.point.Y, width, a.point.Y)
+		case 1:
+			et.ansiErase(0, a.point.Y, a.point.X+1, a.point.Y)
+		case 2:
+			et.ansiErase(0, a.point.Y, width, a.point.Y)
+		}
+		return
+	default:
+		return
+	}
+
+	a.point = et.ansiClamp(a.point)
+	a.wrap = false
+}
+
+// ansiSGR applies SGR arguments to a style.
+func ansiSGR(style tcell.Style, args []int) tcell.Style {
+	// color parses an extended color, returning the arguments it used.
+	color := func(args []int) (color tcell.Color, used int) {
+		switch {
+		case len(args) >= 2 && args[0] == 5:
+			return tcell.PaletteColor(args[1]), 2
+		case len(args) >= 4 && args[0] == 2:
+			return tcell.NewRGBColor(int32(args[1]), int32(args[2]), int32(args[3])), 4
+		}
+		return tcell.ColorDefault, len(args)
+	}
+
+	for n := 0; n < len(args); n++ {
+		switch arg := args[n]; {
+		case arg == 0:
+			style = tcell.StyleDefault
+		case arg == 1:
+			style = style.Bold(true)
+		case arg == 2:
+			style = style.Dim(true)
+		case arg == 3:
+			style = style.Italic(true)
+		case arg == 4:
+			style = style.Underline(true)
+		case arg == 5:
+			style = style.Blink(true)
+		case arg == 7:
+			style = style.Reverse(true)
+		case arg == 9:
+			style = style.StrikeThrough(true)
+		case arg == 22:
+			style = style.Bold(false).Dim(false)
+		case arg == 23:
+			style = style.Italic(false)
+		case arg == 24:
+			style = style.Underline(false)
+		case arg == 25:
+			style = style.Blink(false)
+		case arg == 27:
+			style = style.Reverse(false)
+		case arg == 29:
+			style = style.StrikeThrough(false)
+		case arg >= 30 && arg <= 37:
+			style = style.Foreground(tcell.PaletteColor(arg - 30))
+		case arg == 38:
+			fg, used := color(args[n+1:])
+			style = style.Foreground(fg)
+			n += used
+		case arg == 39:
+			style = style.Foreground(tcell.ColorDefault)
+		case arg >= 40 && arg <= 47:
+			style = style.Background(tcell.PaletteColor(arg - 40))
+		case arg == 48:
+			bg, used := color(args[n+1:])
+			style = style.Background(bg)
+			n += used
+		case arg == 49:
+			style = style.Background(tcell.ColorDefault)
+		case arg >= 90 && arg <= 97:
+			style = style.Foreground(tcell.PaletteColor(arg - 90 + 8))
+		case arg >= 100 && arg <= 107:
+			style = style.Background(tcell.PaletteColor(arg - 100 + 8))
+		}
+	}
+
+	return style
+}
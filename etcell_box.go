@@ -0,0 +1,264 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Box drawing arm directions.
+const (
+	box_up = iota
+	box_right
+	box_down
+	box_left
+)
+
+// Box drawing arm weights.
+const (
+	box_none = iota
+	box_light
+	box_heavy
+	box_double
+)
+
+// boxArms are the weights of the up, right, down and left arms of a
+// box drawing rune.
+type boxArms [4]uint8
+
+// box_arms are the arms of the box drawing line and junction runes.
+// Dashed, arc and diagonal runes are left to the font.
+var box_arms = map[rune]boxArms{
+	'─': {0, 1, 0, 1},
+	'━': {0, 2, 0, 2},
+	'│': {1, 0, 1, 0},
+	'┃': {2, 0, 2, 0},
+	'┌': {0, 1, 1, 0},
+	'┍': {0, 2, 1, 0},
+	'┎': {0, 1, 2, 0},
+	'┏': {0, 2, 2, 0},
+	'┐': {0, 0, 1, 1},
+	'┑': {0, 0, 1, 2},
+	'┒': {0, 0, 2, 1},
+	'┓': {0, 0, 2, 2},
+	'└': {1, 1, 0, 0},
+	'┕': {1, 2, 0, 0},
+	'┖': {2, 1, 0, 0},
+	'┗': {2, 2, 0, 0},
+	'┘': {1, 0, 0, 1},
+	'┙': {1, 0, 0, 2},
+	'┚': {2, 0, 0, 1},
+	'┛': {2, 0, 0, 2},
+	'├': {1, 1, 1, 0},
+	'┝': {1, 2, 1, 0},
+	'┞': {2, 1, 1, 0},
+	'┟': {1, 1, 2, 0},
+	'┠': {2, 1, 2, 0},
+	'┡': {2, 2, 1, 0},
+	'┢': {1, 2, 2, 0},
+	'┣': {2, 2, 2, 0},
+	'┤': {1, 0, 1, 1},
+	'┥': {1, 0, 1, 2},
+	'┦': {2, 0, 1, 1},
+	'┧': {1, 0, 2, 1},
+	'┨': {2, 0, 2, 1},
+	'┩': {2, 0, 1, 2},
+	'┪': {1, 0, 2, 2},
+	'┫': {2, 0, 2, 2},
+	'┬': {0, 1, 1, 1},
+	'┭': {0, 1, 1, 2},
+	'┮': {0, 2, 1, 1},
+	'┯': {0, 2, 1, 2},
+	'┰': {0, 1, 2, 1},
+	'┱': {0, 1, 2, 2},
+	'┲': {0, 2, 2, 1},
+	'┳': {0, 2, 2, 2},
+	'┴': {1, 1, 0, 1},
+	'┵': {1, 1, 0, 2},
+	'┶': {1, 2, 0, 1},
+	'┷': {1, 2, 0, 2},
+	'┸': {2, 1, 0, 1},
+	'┹': {2, 1, 0, 2},
+	'┺': {2, 2, 0, 1},
+	'┻': {2, 2, 0, 2},
+	'┼': {1, 1, 1, 1},
+	'┽': {1, 1, 1, 2},
+	'┾': {1, 2, 1, 1},
+	'┿': {1, 2, 1, 2},
+	'╀': {2, 1, 1, 1},
+	'╁': {1, 1, 2, 1},
+	'╂': {2, 1, 2, 1},
+	'╃': {2, 1, 1, 2},
+	'╄': {2, 2, 1, 1},
+	'╅': {1, 1, 2, 2},
+	'╆': {1, 2, 2, 1},
+	'╇': {2, 2, 1, 2},
+	'╈': {1, 2, 2, 2},
+	'╉': {2, 1, 2, 2},
+	'╊': {2, 2, 2, 1},
+	'╋': {2, 2, 2, 2},
+	'═': {0, 3, 0, 3},
+	'║': {3, 0, 3, 0},
+	'╒': {0, 3, 1, 0},
+	'╓': {0, 1, 3, 0},
+	'╔': {0, 3, 3, 0},
+	'╕': {0, 0, 1, 3},
+	'╖': {0, 0, 3, 1},
+	'╗': {0, 0, 3, 3},
+	'╘': {1, 3, 0, 0},
+	'╙': {3, 1, 0, 0},
+	'╚': {3, 3, 0, 0},
+	'╛': {1, 0, 0, 3},
+	'╜': {3, 0, 0, 1},
+	'╝': {3, 0, 0, 3},
+	'╞': {1, 3, 1, 0},
+	'╟': {3, 1, 3, 0},
+	'╠': {3, 3, 3, 0},
+	'╡': {1, 0, 1, 3},
+	'╢': {3, 0, 3, 1},
+	'╣': {3, 0, 3, 3},
+	'╤': {0, 3, 1, 3},
+	'╥': {0, 1, 3, 1},
+	'╦': {0, 3, 3, 3},
+	'╧': {1, 3, 0, 3},
+	'╨': {3, 1, 0, 1},
+	'╩': {3, 3, 0, 3},
+	'╪': {1, 3, 1, 3},
+	'╫': {3, 1, 3, 1},
+	'╬': {3, 3, 3, 3},
+	'╴': {0, 0, 0, 1},
+	'╵': {1, 0, 0, 0},
+	'╶': {0, 1, 0, 0},
+	'╷': {0, 0, 1, 0},
+	'╸': {0, 0, 0, 2},
+	'╹': {2, 0, 0, 0},
+	'╺': {0, 2, 0, 0},
+	'╻': {0, 0, 2, 0},
+	'╼': {0, 2, 0, 1},
+	'╽': {1, 0, 2, 0},
+	'╾': {0, 1, 0, 2},
+	'╿': {2, 0, 1, 0},
+}
+
+// boxArmsAt returns the arms of the box drawing rune at a grid cell, if
+// any. When connecting, arms are added towards neighbors that have an arm
+// pointing back into the cell. The grid lock must be held.
+func (et *ETCellScreen) boxArmsAt(x, y int) (arms boxArms, ok bool) {
+	arms, ok = box_arms[et.grid[y*et.grid_size.X+x].Rune]
+	if !ok || !et.box_connect {
+		return
+	}
+
+	neighbors := [4]image.Point{
+		box_up:    {X: x, Y: y - 1},
+		box_right: {X: x + 1, Y: y},
+		box_down:  {X: x, Y: y + 1},
+		box_left:  {X: x - 1, Y: y},
+	}
+
+	bounds := image.Rect(0, 0, et.grid_size.X, et.bufferRows())
+	for dir, pt := range neighbors {
+		if arms[dir] != box_none || !pt.In(bounds) {
+			continue
+		}
+		neighbor, is_box := box_arms[et.grid[pt.Y*et.grid_size.X+pt.X].Rune]
+		if is_box {
+			arms[dir] = neighbor[(dir+2)%4]
+		}
+	}
+
+	return
+}
+
+// unsyncNeighbors marks the neighbors of a grid cell to be resolved again,
+// as their connected box drawing arms may have changed.
+// The grid lock must be held.
+func (et *ETCellScreen) unsyncNeighbors(x, y int) {
+	bounds := image.Rect(0, 0, et.grid_size.X, et.bufferRows())
+	for _, pt := range []image.Point{{X: x, Y: y - 1}, {X: x + 1, Y: y}, {X: x, Y: y + 1}, {X: x - 1, Y: y}} {
+		if pt.In(bounds) {
+			et.grid[pt.Y*et.grid_size.X+pt.X].synced = false
+		}
+	}
+}
+
+// boxStrips returns the spans of the lines of an arm of a given weight,
+// centered across the arm.
+func boxStrips(center, unit int, weight uint8) (strips [][2]int) {
+	switch weight {
+	case box_light:
+		lo := center - unit/2
+		strips = [][2]int{{lo, lo + unit}}
+	case box_heavy:
+		lo := center - unit
+		strips = [][2]int{{lo, lo + 2*unit}}
+	case box_double:
+		lo := center - (3*unit)/2
+		strips = [][2]int{{lo, lo + unit}, {lo + 2*unit, lo + 3*unit}}
+	}
+
+	return
+}
+
+// boxSpan returns the span covered by the lines of arms of the given weights.
+func boxSpan(center, unit int, weights ...uint8) (lo, hi int) {
+	lo, hi = center, center
+	for _, weight := range weights {
+		strips := boxStrips(center, unit, weight)
+		if len(strips) == 0 {
+			continue
+		}
+		lo = min(lo, strips[0][0])
+		hi = max(hi, strips[len(strips)-1][1])
+	}
+
+	return
+}
+
+// boxGlyph returns a synthetic glyph for box drawing arms, with each arm
+// drawn to the edge of the cell so that adjacent cells join seamlessly.
+// Glyphs are cached until the font changes. The grid lock must be held.
+func (et *ETCellScreen) boxGlyph(arms boxArms) (glyph *ebiten.Image) {
+	glyph, ok := et.box_cache[arms]
+	if ok {
+		return
+	}
+
+	w, h := et.cell_size.X, et.cell_size.Y
+	cx, cy := w/2, h/2
+	unit := max(1, min(w, h)/8)
+
+	// Rows covered by the horizontal arms, and columns by the vertical arms.
+	h_lo, h_hi := boxSpan(cy, unit, arms[box_left], arms[box_right])
+	v_lo, v_hi := boxSpan(cx, unit, arms[box_up], arms[box_down])
+
+	glyph = ebiten.NewImage(w, h)
+	fill := func(r image.Rectangle) {
+		if !r.Empty() {
+			glyph.SubImage(r).(*ebiten.Image).Fill(color.White)
+		}
+	}
+
+	for _, s := range boxStrips(cx, unit, arms[box_up]) {
+		fill(image.Rect(s[0], 0, s[1], h_hi))
+	}
+	for _, s := range boxStrips(cx, unit, arms[box_down]) {
+		fill(image.Rect(s[0], h_lo, s[1], h))
+	}
+	for _, s := range boxStrips(cy, unit, arms[box_left]) {
+		fill(image.Rect(0, s[0], v_hi, s[1]))
+	}
+	for _, s := range boxStrips(cy, unit, arms[box_right]) {
+		fill(image.Rect(v_lo, s[0], w, s[1]))
+	}
+
+	if et.box_cache == nil {
+		et.box_cache = make(map[boxArms](*ebiten.Image))
+	}
+	et.box_cache[arms] = glyph
+
+	return
+}
@@ -0,0 +1,159 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"container/list"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// cellKey identifies the full appearance of a cell.
+type cellKey struct {
+	glyph     *ebiten.Image // Glyph, which identifies the runes and font style.
+	fg, bg    color.RGBA
+	underline bool
+	strike    bool
+}
+
+// cellEntry is a cached cell image.
+type cellEntry struct {
+	key   cellKey
+	image *ebiten.Image
+}
+
+// cellCache is a least recently used cache of pre-composited cell images.
+type cellCache struct {
+	entries   int         // Maximum number of cached images.
+	cell_size image.Point // Size of the cached images.
+
+	lru   *list.List // Cached images, most recently used first.
+	cells map[cellKey]*list.Element
+}
+
+// newCellCache returns a cell cache of at most entries cell images.
+func newCellCache(entries int, cell_size image.Point) *cellCache {
+	return &cellCache{
+		entries:   entries,
+		cell_size: cell_size,
+		lru:       list.New(),
+		cells:     make(map[cellKey]*list.Element),
+	}
+}
+
+// get returns the cached image for a key, or renders one with render,
+// evicting the least recently used image if the cache is full.
+func (cc *cellCache) get(key cellKey, render func(img *ebiten.Image)) (img *ebiten.Image) {
+	if elem, ok := cc.cells[key]; ok {
+		cc.lru.MoveToFront(elem)
+		return elem.Value.(*cellEntry).image
+	}
+
+	if cc.lru.Len() >= cc.entries {
+		elem := cc.lru.Back()
+		entry := cc.lru.Remove(elem).(*cellEntry)
+		delete(cc.cells, entry.key)
+		entry.image.Deallocate()
+	}
+
+	img = ebiten.NewImage(cc.cell_size.X, cc.cell_size.Y)
+	render(img)
+
+	cc.cells[key] = cc.lru.PushFront(&cellEntry{key: key, image: img})
+
+	return
+}
+
+// dispose releases all the cached images.
+func (cc *cellCache) dispose() {
+	for elem := cc.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*cellEntry).image.Deallocate()
+	}
+	cc.lru.Init()
+	clear(cc.cells)
+}
+
+// lruEntry is a cached value.
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// lruCache is a least recently used cache of glyphs, which are passed to
+// evict when dropped.
+type lruCache[K comparable, V any] struct {
+	entries int     // Maximum number of cached values.
+	evict   func(V) // Called on each dropped value.
+
+	lru   *list.List // Cached values, most recently used first.
+	items map[K]*list.Element
+}
+
+// newLRUCache returns a cache of at most entries values.
+func newLRUCache[K comparable, V any](entries int, evict func(V)) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		entries: entries,
+		evict:   evict,
+		lru:     list.New(),
+		items:   make(map[K]*list.Element),
+	}
+}
+
+// get returns the cached value for a key, marking it as recently used.
+func (lc *lruCache[K, V]) get(key K) (value V, ok bool) {
+	if lc == nil {
+		return
+	}
+
+	elem, ok := lc.items[key]
+	if !ok {
+		return
+	}
+	lc.lru.MoveToFront(elem)
+	value = elem.Value.(*lruEntry[K, V]).value
+
+	return
+}
+
+// put caches a value, evicting the least recently used value if the
+// cache is full.
+func (lc *lruCache[K, V]) put(key K, value V) {
+	if elem, ok := lc.items[key]; ok {
+		lc.lru.MoveToFront(elem)
+		elem.Value.(*lruEntry[K, V]).value = value
+		return
+	}
+
+	if lc.lru.Len() >= lc.entries {
+		elem := lc.lru.Back()
+		entry := lc.lru.Remove(elem).(*lruEntry[K, V])
+		delete(lc.items, entry.key)
+		lc.evict(entry.value)
+	}
+
+	lc.items[key] = lc.lru.PushFront(&lruEntry[K, V]{key: key, value: value})
+}
+
+// len returns the number of cached values.
+func (lc *lruCache[K, V]) len() int {
+	if lc == nil {
+		return 0
+	}
+
+	return lc.lru.Len()
+}
+
+// clear evicts all the cached values.
+func (lc *lruCache[K, V]) clear() {
+	if lc == nil {
+		return
+	}
+
+	for elem := lc.lru.Front(); elem != nil; elem = elem.Next() {
+		lc.evict(elem.Value.(*lruEntry[K, V]).value)
+	}
+	lc.lru.Init()
+	clear(lc.items)
+}
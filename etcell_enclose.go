@@ -0,0 +1,141 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// enclosureShape returns the distance of a point from the ink of an
+// enclosing mark, zero or negative on the ink, for a cell of w by h pixels
+// with outlines unit pixels wide.
+type enclosureShape func(x, y, w, h, unit float64) float64
+
+// enclosures are the shapes of the enclosing combining marks that are
+// drawn synthetically, at the full cell size.
+var enclosures = map[rune]enclosureShape{
+	'⃝': enclosureCircle,
+	'⃞': enclosureSquare,
+	'⃟': enclosureDiamond,
+	'⃠': enclosureProhibition,
+	'⃣': enclosureKeycap,
+}
+
+// enclosureCircle is the shape of U+20DD COMBINING ENCLOSING CIRCLE.
+func enclosureCircle(x, y, w, h, unit float64) float64 {
+	return outline(circle(x, y, w, h), unit)
+}
+
+// enclosureSquare is the shape of U+20DE COMBINING ENCLOSING SQUARE.
+func enclosureSquare(x, y, w, h, unit float64) float64 {
+	return outline(roundedRect(x, y, w, h, 0), unit)
+}
+
+// enclosureDiamond is the shape of U+20DF COMBINING ENCLOSING DIAMOND.
+func enclosureDiamond(x, y, w, h, unit float64) float64 {
+	hw, hh := w/2, h/2
+	return outline((math.Abs(x-hw)/hw+math.Abs(y-hh)/hh-1)*min(hw, hh), unit)
+}
+
+// enclosureProhibition is the shape of U+20E0 COMBINING ENCLOSING CIRCLE
+// BACKSLASH, a circle with a diagonal from its top left to bottom right.
+func enclosureProhibition(x, y, w, h, unit float64) float64 {
+	inside := circle(x, y, w, h)
+	slash := math.Abs((x-w/2)-(y-h/2))/math.Sqrt2 - unit/2
+	return min(outline(inside, unit), max(inside, slash))
+}
+
+// enclosureKeycap is the shape of U+20E3 COMBINING ENCLOSING KEYCAP.
+func enclosureKeycap(x, y, w, h, unit float64) float64 {
+	return outline(roundedRect(x, y, w, h, min(w, h)/4), unit)
+}
+
+// outline returns the distance from the ink of an outline, unit pixels
+// wide, just inside the edge of a shape with a signed distance.
+func outline(dist, unit float64) float64 {
+	return math.Abs(dist+unit/2) - unit/2
+}
+
+// circle returns the signed distance of a point from the largest circle
+// centered in a w by h cell, negative inside.
+func circle(x, y, w, h float64) float64 {
+	return math.Hypot(x-w/2, y-h/2) - min(w, h)/2
+}
+
+// roundedRect returns the signed distance of a point from a w by h
+// rectangle at the origin, with corners of a radius, negative inside.
+func roundedRect(x, y, w, h, radius float64) float64 {
+	qx := math.Abs(x-w/2) - (w/2 - radius)
+	qy := math.Abs(y-h/2) - (h/2 - radius)
+	return math.Hypot(max(qx, 0), max(qy, 0)) + min(max(qx, qy), 0) - radius
+}
+
+// enclosureUnit is the outline width of enclosing marks, as that of light
+// box drawing lines.
+func enclosureUnit(w, h int) int {
+	return max(1, min(w, h)/8)
+}
+
+// enclosureScale is the scale of the glyphs enclosed by a mark, so that
+// they fit inside its outline.
+func enclosureScale(r rune, w, h int) float64 {
+	unit := float64(enclosureUnit(w, h))
+	scale := min(1-4*unit/float64(w), 1-4*unit/float64(h))
+
+	switch r {
+	case '⃝', '⃟', '⃠':
+		// Round and diagonal shapes leave less room inside.
+		scale *= 0.75
+	}
+
+	return max(scale, 0)
+}
+
+// enclosureMask rasterizes the outline of an enclosing mark for a cell of
+// w by h pixels, or returns nil if the rune is not a synthetic enclosure.
+func enclosureMask(r rune, w, h int) (mask *image.Alpha) {
+	shape, ok := enclosures[r]
+	if !ok {
+		return
+	}
+
+	unit := float64(enclosureUnit(w, h))
+
+	mask = image.NewAlpha(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if shape(float64(x)+0.5, float64(y)+0.5, float64(w), float64(h), unit) <= 0 {
+				mask.SetAlpha(x, y, color.Alpha{A: 0xff})
+			}
+		}
+	}
+
+	return
+}
+
+// enclosureGlyph returns the synthetic glyph of an enclosing mark, or nil
+// if the rune is not a synthetic enclosure. The grid lock must be held.
+func (et *ETCellScreen) enclosureGlyph(r rune) (glyph *ebiten.Image) {
+	glyph, ok := et.enclosure_cache[r]
+	if ok {
+		return
+	}
+
+	mask := enclosureMask(r, et.cell_size.X, et.cell_size.Y)
+	if mask == nil {
+		return
+	}
+
+	glyph = ebiten.NewImageFromImage(mask)
+
+	if et.enclosure_cache == nil {
+		et.enclosure_cache = make(map[rune](*ebiten.Image))
+	}
+	et.enclosure_cache[r] = glyph
+
+	return
+}
@@ -4,19 +4,68 @@ package tcell_ebiten
 
 import (
 	"image"
+	"image/color"
+	"math"
+	"slices"
 	"time"
 
+	"github.com/ezrec/tcell_ebiten/font"
 	"github.com/gdamore/tcell/v2"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
+// white_image is a single white pixel, scaled to fill rectangles.
+var white_image = func() (img *ebiten.Image) {
+	img = ebiten.NewImage(1, 1)
+	img.Fill(color.White)
+	return
+}()
+
+// cursor_blend subtracts the colors under the block cursor from the
+// cursor color.
+var cursor_blend = ebiten.Blend{
+	BlendFactorSourceRGB:      ebiten.BlendFactorOne,
+	BlendFactorDestinationRGB: ebiten.BlendFactorOne,
+	BlendOperationRGB:         ebiten.BlendOperationSubtract,
+
+	BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorZero,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
 type ETCellGame struct {
 	*ETCell
 
 	GeoM ebiten.GeoM // This should only be set initially, or modified in Draw(), Update(), or Layout() overrides.
 
 	grid_draw []cell // Grid of cells, currently being drawn.
+
+	offset_x, offset_y float64       // Pixel offset of the grid.
+	offset_image       *ebiten.Image // Offscreen image, for clipping the offset grid.
+
+	magnifier_image *ebiten.Image // Offscreen image of the magnified region.
+
+	cell_cache *cellCache // Pre-composited cell images, if cached.
+
+	frame_image *ebiten.Image // Last frame drawn, if redrawing on events.
+	frozen      *ebiten.Image // Frozen frame, drawn in place of the grid.
+	target      *ebiten.Image // Render target, from RenderTarget().
+	logical     *ebiten.Image // Render target of the logical size, if set.
+	drawn       drawState     // State of the last frame drawn.
+
+	frame_padding int         // Frame padding around the grid, in pixels.
+	frame_color   color.Color // Frame color.
+
+	device_scale float64     // Device scale factor, from the last LayoutF().
+	final_geom   ebiten.GeoM // Transform of the render target to the window, from the last Layout().
+
+	grid_overlay *color.RGBA // Color of the debug grid overlay, if enabled.
+
+	has_focus       bool        // The game has keyboard focus.
+	focus_color     color.Color // Focus indicator color, if any.
+	focus_thickness int         // Focus indicator thickness, in pixels.
+	inactive_dim    float64     // Opacity of the dimming while unfocused.
 }
 
 // Validate interface compliance
@@ -26,8 +75,13 @@ var _ interface {
 } = (*ETCellGame)(nil)
 
 // Update processes ebiten.Game events.
-// If Screen.Suspend() has been called, does nothing.
+// If Screen.Suspend() has been called, posts no events, and only calls
+// the handler set by SetSuspendInputHandler, if any.
 func (et *ETCellGame) Update() (err error) {
+	if et.closeRequested() {
+		return ebiten.Termination
+	}
+
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
 
@@ -38,26 +92,39 @@ func (et *ETCellGame) Update() (err error) {
 	}
 
 	if et.suspended {
+		// The handler may resume the screen, so is called unlocked.
+		if fn := et.suspend_input; fn != nil {
+			et.grid_lock.Unlock()
+			fn()
+			et.grid_lock.Lock()
+		}
 		return
 	}
 
 	cursor_x, cursor_y := ebiten.CursorPosition()
+	mouse, mouse_ok := inversePoint(et.gridGeoM(), cursor_x, cursor_y)
+	mouse_in := mouse_ok && mouse.In(et.layout)
 
-	mapping := et.GeoM
-	mapping.Invert()
-	mouse_x, mouse_y := mapping.Apply(float64(cursor_x), float64(cursor_y))
-	mouse := image.Point{X: int(mouse_x), Y: int(mouse_y)}
+	// Mouse movement between cells is user activity.
+	active := et.setHover(mouse_in, image.Point{X: mouse.X / et.cell_size.X, Y: mouse.Y / et.cell_size.Y})
 
-	var in_focus bool
 	var posted bool
 
+	tap, tapped := tapPoint()
+	tap_in := false
+	if tapped {
+		tap, tapped = inversePoint(et.gridGeoM(), tap.X, tap.Y)
+		tap_in = tapped && tap.In(et.layout)
+	}
+	in_focus := et.updateFocus(mouse_in, tap_in)
+	if in_focus && !et.focused {
+		et.postEvent(tcell.NewEventFocus(true))
+		et.focused = true
+		posted = true
+	}
+
 	// Mouse buttons
-	if mouse.In(et.layout) {
-		if !et.focused {
-			et.postEvent(tcell.NewEventFocus(true))
-			et.focused = true
-			posted = true
-		}
+	if mouse_in {
 		var buttons tcell.ButtonMask
 		for e_button, t_button := range ebiten_button_map {
 			if ebiten.IsMouseButtonPressed(e_button) {
@@ -71,35 +138,44 @@ func (et *ETCellGame) Update() (err error) {
 
 		// Mouse wheel movement.
 		xoff, yoff := ebiten.Wheel()
-		if xoff < 0 {
-			buttons |= tcell.WheelLeft
-		}
-		if xoff > 0 {
-			buttons |= tcell.WheelRight
-		}
-		if yoff < 0 {
-			buttons |= tcell.WheelDown
+		wheel_buttons, repeat := et.wheel(xoff, yoff, modMask())
+		buttons |= wheel_buttons
+
+		et.postEvent(tcell.NewEventMouse(mouse_x, mouse_y, buttons, modMask()))
+		for range repeat {
+			et.postEvent(tcell.NewEventMouse(mouse_x, mouse_y, buttons, modMask()))
 		}
-		if yoff > 0 {
-			buttons |= tcell.WheelUp
+		if buttons != tcell.ButtonNone {
+			active = true
 		}
 
-		et.postEvent(tcell.NewEventMouse(mouse_x, mouse_y, buttons, modMask()))
+		// Hyperlink clicks.
+		if et.on_link_click != nil && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			url := et.linkAt(mouse_x, mouse_y)
+			if url != "" {
+				et.on_link_click(url)
+			}
+		}
 
-		in_focus = true
 		posted = true
 	}
 
-	if mouse.In(et.layout) {
-		if !et.focused {
-			et.postEvent(tcell.NewEventFocus(true))
-			et.focused = true
+	// Keyboard mouse emulation, independent of the real mouse.
+	var kbd_mouse_keys []ebiten.Key
+	if et.kbd_mouse && ebiten.IsFocused() {
+		kbd_mouse_keys = et.keyboardMouse(modMask())
+		if len(kbd_mouse_keys) > 0 {
+			posted = true
+			active = true
 		}
+	}
+
+	if in_focus {
 		mods := modMask()
 		if (mods & tcell.ModCtrl) != 0 {
 			keys := make([]ebiten.Key, 0, 16)
 			for _, e_key := range inpututil.AppendPressedKeys(keys) {
-				if !isKeyJustPressedOrRepeating(e_key) {
+				if !isKeyJustPressedOrRepeating(e_key) || slices.Contains(kbd_mouse_keys, e_key) {
 					continue
 				}
 				if e_key >= ebiten.KeyA && e_key <= ebiten.KeyZ {
@@ -107,31 +183,54 @@ func (et *ETCellGame) Update() (err error) {
 					ev := tcell.NewEventKey(t_key, rune(0), mods & ^tcell.ModCtrl)
 					et.postEvent(ev)
 					posted = true
+					active = true
 				}
 			}
 		} else {
 			key_runes := ebiten.AppendInputChars(nil)
 			for _, key_rune := range key_runes {
+				// Control characters are posted as keys, below.
+				if key_rune < ' ' || key_rune == 0x7f {
+					continue
+				}
 				ev := tcell.NewEventKey(tcell.KeyRune, key_rune, mods & ^tcell.ModShift)
 				et.postEvent(ev)
 				posted = true
+				active = true
 			}
 		}
 
 		key_codes := inpututil.AppendPressedKeys(nil)
 		for _, e_key := range key_codes {
-			if !isKeyJustPressedOrRepeating(e_key) {
+			if !isKeyJustPressedOrRepeating(e_key) || slices.Contains(kbd_mouse_keys, e_key) {
 				continue
 			}
 			t_key, ok := ebiten_key_map[e_key]
 			if ok {
-				ev := tcell.NewEventKey(t_key, rune(0), mods)
+				active = true
+				ev := et.keyEvent(t_key, mods)
+				if ev == nil {
+					continue
+				}
 				et.postEvent(ev)
 				posted = true
 			}
 		}
 
-		in_focus = true
+		// Raw key transitions, for keys tcell does not model.
+		if et.on_raw_key != nil {
+			for _, e_key := range inpututil.AppendJustPressedKeys(nil) {
+				if isRawKey(e_key) {
+					et.on_raw_key(e_key, true)
+					active = true
+				}
+			}
+			for _, e_key := range inpututil.AppendJustReleasedKeys(nil) {
+				if isRawKey(e_key) {
+					et.on_raw_key(e_key, false)
+				}
+			}
+		}
 	}
 
 	if !in_focus {
@@ -142,6 +241,12 @@ func (et *ETCellGame) Update() (err error) {
 		}
 	}
 
+	et.has_focus = in_focus
+
+	if active {
+		et.last_activity = et.clock()
+	}
+
 	// Always post a time event, if no other event was fired.
 	if !posted {
 		ev := &tcell.EventTime{}
@@ -152,94 +257,464 @@ func (et *ETCellGame) Update() (err error) {
 	return
 }
 
+// PressedKeys returns the set of mapped tcell keys that are currently held
+// down, so that chords can be polled once per frame. Keys that are typed as
+// runes, and modifiers, are not included; see [ebiten.IsKeyPressed] for those.
+func (et *ETCellGame) PressedKeys() (keys []tcell.Key) {
+	return pressedKeys(inpututil.AppendPressedKeys(nil))
+}
+
+// pressedKeys maps held ebiten keys to tcell keys.
+func pressedKeys(e_keys []ebiten.Key) (keys []tcell.Key) {
+	keys = make([]tcell.Key, 0, len(e_keys))
+	for _, e_key := range e_keys {
+		t_key, ok := ebiten_key_map[e_key]
+		if ok {
+			keys = append(keys, t_key)
+		}
+	}
+
+	return
+}
+
+// wheel_max_events is the most vertical wheel events posted per frame.
+const wheel_max_events = 8
+
+// wheel translates mouse wheel movement into wheel buttons, and the
+// number of times the wheel buttons are to be repeated. If the wheel is
+// set to act as keys, vertical movement is instead posted as up and down
+// arrow key events.
+func (et *ETCellGame) wheel(xoff, yoff float64, mods tcell.ModMask) (buttons tcell.ButtonMask, repeat int) {
+	if xoff < 0 {
+		buttons |= tcell.WheelLeft
+	}
+	if xoff > 0 {
+		buttons |= tcell.WheelRight
+	}
+
+	events := et.wheelEvents(yoff)
+	if events == 0 {
+		return
+	}
+
+	key, button := tcell.KeyUp, tcell.WheelUp
+	if events < 0 {
+		key, button = tcell.KeyDown, tcell.WheelDown
+		events = -events
+	}
+
+	if et.wheel_as_keys {
+		for range events {
+			et.postEvent(tcell.NewEventKey(key, rune(0), mods))
+		}
+	} else {
+		buttons |= button
+		repeat = events - 1
+	}
+
+	return
+}
+
+// wheelEvents returns the number of vertical wheel events for vertical
+// wheel movement, positive for up and negative for down.
+func (et *ETCellGame) wheelEvents(yoff float64) (events int) {
+	if et.wheel_multiplier == 0 {
+		switch {
+		case yoff < 0:
+			events = -1
+		case yoff > 0:
+			events = 1
+		}
+		return
+	}
+
+	// Changing direction discards movement in the old direction.
+	if (yoff < 0 && et.wheel_pending > 0) || (yoff > 0 && et.wheel_pending < 0) {
+		et.wheel_pending = 0
+	}
+
+	et.wheel_pending += yoff * et.wheel_multiplier
+	events = int(et.wheel_pending)
+	et.wheel_pending -= float64(events)
+
+	// Discard movement beyond the maximum, to avoid runaway scrolling.
+	if events > wheel_max_events || events < -wheel_max_events {
+		events = max(-wheel_max_events, min(events, wheel_max_events))
+		et.wheel_pending = 0
+	}
+
+	return
+}
+
+// setHover records the cell under the mouse, if any, and calls the
+// hover callback when it changes. Returns true if it changed.
+func (et *ETCellGame) setHover(hovering bool, hover image.Point) (changed bool) {
+	if !hovering {
+		hover = image.Point{X: -1, Y: -1}
+	}
+
+	if hovering == et.hovering && (!hovering || hover.Eq(et.hover)) {
+		return
+	}
+
+	changed = true
+
+	et.hovering = hovering
+	et.hover = hover
+
+	if et.on_cell_hover != nil {
+		et.on_cell_hover(hover.X, hover.Y)
+	}
+
+	return
+}
+
+// closeRequested returns true if the window is to close, as the user
+// asked to close it, and the close request callback did not cancel it.
+func (et *ETCellGame) closeRequested() bool {
+	et.grid_lock.Lock()
+	et.init()
+	closing, on_close_request := et.window_closing, et.on_close_request
+	et.grid_lock.Unlock()
+
+	if !closing() {
+		return false
+	}
+
+	return on_close_request == nil || on_close_request()
+}
+
+// inversePoint maps a screen position back through a GeoM transform
+// to a pixel position in the game layout. Any affine transform is
+// supported (translation, scale, rotation, and shear). Returns false if
+// the transform is not invertible (ie a zero scale), as then no screen
+// position maps to the game.
+func inversePoint(geom ebiten.GeoM, x, y int) (point image.Point, ok bool) {
+	if !geom.IsInvertible() {
+		return
+	}
+
+	// Map the center of the screen pixel, so that rotations do not
+	// land on the edge between two game pixels.
+	geom.Invert()
+	fx, fy := geom.Apply(float64(x)+0.5, float64(y)+0.5)
+
+	// Floor, rather than truncate, so that positions just above or to
+	// the left of the origin do not map into the first row or column.
+	point = image.Point{X: int(math.Floor(fx)), Y: int(math.Floor(fy))}
+	ok = true
+
+	return
+}
+
 // Draw handles drawing in the game context.
 // Used to implement a custom override for ETCellGame.
 func (et *ETCellGame) Draw(dst *ebiten.Image) {
+	et.grid_lock.Lock()
+	frozen, geom := et.frozen, et.GeoM
+	logical, final_geom := et.logicalImage(), et.final_geom
+	et.grid_lock.Unlock()
+
+	target := dst
+	if logical != nil {
+		target = logical
+		target.Clear()
+	}
+
+	if frozen != nil {
+		var opts ebiten.DrawImageOptions
+		opts.GeoM = geom
+		target.DrawImage(frozen, &opts)
+	} else {
+		et.draw(target, false)
+	}
+
+	if logical != nil {
+		var opts ebiten.DrawImageOptions
+		opts.GeoM = final_geom
+		if final_geom.Element(0, 0) < 1 {
+			opts.Filter = ebiten.FilterLinear
+		}
+		dst.DrawImage(logical, &opts)
+	}
+}
+
+// logicalImage returns the render target of the logical size, or nil if
+// no logical size is set.
+func (et *ETCellGame) logicalImage() (logical *ebiten.Image) {
+	size := et.logical_size
+	logical = et.logical
+	if logical != nil && !logical.Bounds().Size().Eq(size) {
+		logical.Deallocate()
+		logical = nil
+		et.logical = nil
+	}
+	if logical == nil && size.X > 0 && size.Y > 0 {
+		logical = ebiten.NewImage(size.X, size.Y)
+		et.logical = logical
+	}
+
+	return
+}
+
+// Freeze renders the grid once, and returns the image of it, which is
+// drawn in place of rendering the grid until Unfreeze() is called. This
+// is an optimization for static screens, such as splash screens. The
+// image is of the game's layout, including the frame, and is drawn with
+// the GeoM transform. The grid can still be changed while frozen, and is
+// shown when unfrozen. Freezing again renders the grid again, as is
+// needed after a layout change.
+//
+// The image is owned by the game, which deallocates it when unfrozen. It
+// costs 4 bytes of GPU memory per pixel of the layout, for example
+// 8 MiB for 1920x1080. Freeze must be called from the ebiten goroutine,
+// ie in Update() or Draw().
+func (et *ETCellGame) Freeze() (frozen *ebiten.Image) {
+	et.grid_lock.Lock()
+	et.init()
+	size := et.layout.Size().Add(image.Pt(2*et.frame_padding, 2*et.frame_padding))
+	old := et.frozen
+	et.frozen = nil
+	et.grid_lock.Unlock()
+
+	if old != nil {
+		old.Deallocate()
+	}
+
+	frozen = ebiten.NewImage(max(size.X, 1), max(size.Y, 1))
+	et.draw(frozen, true)
+
+	et.grid_lock.Lock()
+	et.frozen = frozen
+	et.grid_lock.Unlock()
+
+	return
+}
+
+// Unfreeze resumes rendering the grid, and deallocates the image returned
+// by Freeze(). Does nothing if not frozen.
+func (et *ETCellGame) Unfreeze() {
+	et.grid_lock.Lock()
+	frozen := et.frozen
+	et.frozen = nil
+	et.grid_lock.Unlock()
+
+	if frozen != nil {
+		frozen.Deallocate()
+	}
+}
+
+// RenderTarget renders the grid into an image owned by the game, and
+// returns it, for the application to draw or sample itself, such as a
+// texture of its own shaders. As with Freeze, the image is of the game's
+// layout, including the frame, without the GeoM transform.
+//
+// The grid is rendered on every call, from the content last shown, so
+// the image is as up to date as the next Draw() would be, and Draw() is
+// not needed for it. The same image is reused by later calls, unless the
+// layout changes size, so it should be sampled before calling again. Call
+// it once per frame, from the ebiten goroutine, ie in Update() or Draw().
+func (et *ETCellGame) RenderTarget() (target *ebiten.Image) {
+	et.grid_lock.Lock()
+	et.init()
+	size := et.layout.Size().Add(image.Pt(2*et.frame_padding, 2*et.frame_padding))
+	size = image.Pt(max(size.X, 1), max(size.Y, 1))
+	target = et.target
+	if target != nil && !target.Bounds().Size().Eq(size) {
+		target.Deallocate()
+		target = nil
+	}
+	if target == nil {
+		target = ebiten.NewImage(size.X, size.Y)
+		et.target = target
+	}
+	et.grid_lock.Unlock()
+
+	target.Clear()
+	et.draw(target, true)
+
+	return
+}
+
+// draw renders the grid to dst. When freezing, the grid is rendered
+// without the GeoM transform, and the frame is always drawn anew.
+func (et *ETCellGame) draw(dst *ebiten.Image, freezing bool) {
 	et.grid_lock.Lock()
 	et.init()
 
+	if et.invalidate_glyphs {
+		et.invalidate_glyphs = false
+		font.Invalidate(et.face)
+		et.forget()
+		for n := 0; n < len(et.grid); n++ {
+			et.grid[n].synced = false
+		}
+	}
+
+	if et.invalidated {
+		et.show()
+	}
+
+	text_blink_phase, cursor_blink_phase := et.blinkPhases()
+	text_blink_fade, cursor_blink_fade := et.blinkFades()
+
+	// Redraw only on changes, re-blitting the last frame otherwise.
+	if et.redraw_on_event && !freezing {
+		state := et.drawState(dst, text_blink_phase, cursor_blink_phase)
+		state.text_blink_fade, state.cursor_blink_fade = text_blink_fade, cursor_blink_fade
+		size := dst.Bounds().Max
+		if et.frame_image != nil && et.frame_image.Bounds().Size().Eq(size) && state == et.drawn {
+			et.grid_lock.Unlock()
+			dst.DrawImage(et.frame_image, nil)
+			return
+		}
+		et.drawn = state
+
+		if et.frame_image == nil || !et.frame_image.Bounds().Size().Eq(size) {
+			et.frame_image = ebiten.NewImage(size.X, size.Y)
+		}
+		et.frame_image.Clear()
+		defer dst.DrawImage(et.frame_image, nil)
+		dst = et.frame_image
+	} else if !freezing {
+		et.frame_image = nil
+	}
+
+	trace := et.trace
+	copy_start := traceStart(trace)
 	if cap(et.grid_draw) < len(et.grid) {
 		et.grid_draw = make([]cell, len(et.grid))
 	}
 	et.grid_draw = et.grid_draw[0:len(et.grid)]
 	copy(et.grid_draw, et.grid)
-	geom := et.GeoM
+	traceSince(trace, TraceGridCopy, copy_start)
+	et.releaseGlyphs()
+	frame_geom := et.GeoM
+	geom := et.gridGeoM()
+	if freezing {
+		frame_geom.Reset()
+		geom.Reset()
+		geom.Translate(float64(et.frame_padding), float64(et.frame_padding))
+	}
+	offset_x, offset_y := et.offset_x, et.offset_y
+	frame_padding, frame_color := et.frame_padding, et.frame_color
+	layout := et.layout
+	cursor := et.cursor
+	magnifier := et.magnifier
+	visible := image.Rect(0, et.scroll_offset, et.grid_size.X, et.scroll_offset+et.grid_size.Y)
+	clear_color := et.clear_color
+	cell_cache_entries := et.cell_cache_entries
+	frame := cellFrame{
+		text_blink_phase: text_blink_phase,
+		text_blink_dim:   1 - text_blink_fade,
+		reveal:           et.reveal_duration,
+		background:       et.background_pattern,
+		pixel_snap:       et.pixel_snap,
+	}
+	if frame.reveal > 0 {
+		frame.now = et.clock()
+	}
+	focus_color, focus_thickness := et.focus_color, et.focus_thickness
+	focus_ring := et.has_focus && focus_color != nil && focus_thickness > 0
+	inactive_dim := et.inactiveDim()
+	grid_overlay := et.grid_overlay
+	kbd_mouse, kbd_mouse_point := et.kbd_mouse, et.kbd_mouse_point
+	scrollbar := et.scrollbar
+	cursor_fallback := et.cursor_fallback
+	baseline := font.Baseline(et.face)
+	cursor_hidden := et.cursorHidden()
+	cursor_width := et.cursorWidth()
+	grid_width := et.grid_size.X
+	var thumb image.Rectangle
+	if scrollbar.enabled {
+		thumb = scrollbarThumb(layout.Size(), scrollbar.width, et.grid_size.Y, et.bufferRows(), et.scroll_offset)
+	}
 	et.grid_lock.Unlock()
 
-	now := time.Now().UnixMilli()
-	text_blink_ms := now % et.blink_text_ms
-	text_blink_phase := text_blink_ms < (et.blink_text_ms / 2)
+	defer traceSince(trace, TraceDraw, traceStart(trace))
 
-	for n := range et.grid_draw {
-		cell := &et.grid_draw[n]
+	// Draw the frame, behind the grid.
+	if frame_padding > 0 && frame_color != nil {
+		var opts ebiten.DrawImageOptions
+		opts.ColorScale.ScaleWithColor(frame_color)
+		opts.GeoM.Scale(
+			float64(layout.Dx()+2*frame_padding),
+			float64(layout.Dy()+2*frame_padding),
+		)
+		opts.GeoM.Concat(frame_geom)
+		dst.DrawImage(white_image, &opts)
+	}
 
-		if !cell.synced {
-			continue
+	// With a pixel offset, the grid is drawn to an offscreen image of the
+	// layout size first, so that cells scrolled past the edges are clipped.
+	screen, screen_geom := dst, geom
+	if offset_x != 0 || offset_y != 0 {
+		size := layout.Size()
+		if et.offset_image == nil || !et.offset_image.Bounds().Size().Eq(size) {
+			et.offset_image = ebiten.NewImage(size.X, size.Y)
 		}
+		et.offset_image.Clear()
+		dst = et.offset_image
 
-		x := float64(cell.point.X * et.cell_size.X)
-		y := float64(cell.point.Y * et.cell_size.Y)
-
-		var bg_options ebiten.DrawImageOptions
-		bg_options.ColorScale.ScaleWithColor(cell.bgColor)
-		bg_options.GeoM.Translate(x, y)
-		bg_options.GeoM.Concat(geom)
+		geom.Reset()
+		geom.Translate(offset_x, offset_y)
+	}
 
-		dst.DrawImage(et.cell_image, &bg_options)
+	// Clear the grid once, rather than drawing the backgrounds of cells in
+	// the clear color.
+	cell_frame := frame
+	if clear_color != nil {
+		clear_bg := color.RGBAModel.Convert(clear_color).(color.RGBA)
+		cell_frame.cleared = &clear_bg
 
-		var fg_options ebiten.DrawImageOptions
-		fg_options.ColorScale.ScaleWithColor(cell.fgColor)
-		fg_options.GeoM.Translate(x, y)
-		fg_options.GeoM.Concat(geom)
+		var opts ebiten.DrawImageOptions
+		opts.ColorScale.ScaleWithColor(clear_bg)
+		opts.GeoM.Scale(float64(layout.Dx()), float64(layout.Dy()))
+		opts.GeoM.Concat(geom)
+		dst.DrawImage(white_image, &opts)
+	}
 
-		_, _, attr := cell.Style.Decompose()
+	// Cache pre-composited cells, if asked to.
+	if et.cell_cache != nil && (et.cell_cache.entries != cell_cache_entries || !et.cell_cache.cell_size.Eq(et.cell_size)) {
+		et.cell_cache.dispose()
+		et.cell_cache = nil
+	}
+	if et.cell_cache == nil && cell_cache_entries > 0 {
+		et.cell_cache = newCellCache(cell_cache_entries, et.cell_size)
+	}
+	cell_frame.cache = et.cell_cache
 
-		// If now blinking, don't draw the text. We _do_ draw underlines and strikethroughs.
-		if (attr&tcell.AttrBlink) == 0 || !text_blink_phase {
-			if cell.glyph != nil {
-				dst.DrawImage(cell.glyph, &fg_options)
-			}
+	// Only the visible rows of the grid buffer are drawn.
+	var view_geom ebiten.GeoM
+	view_geom.Translate(0, -float64(visible.Min.Y*et.cell_size.Y))
+	view_geom.Concat(geom)
+	geom = view_geom
 
-			for _, glyph := range cell.combining {
-				if glyph != nil {
-					dst.DrawImage(glyph, &fg_options)
-				}
-			}
-		}
+	for n := range et.grid_draw {
+		cell := &et.grid_draw[n]
 
-		// Draw underline, if needed.
-		// We define an underline as the top 1/16 of lower 1/8th of the cell.
-		if (attr & tcell.AttrUnderline) != 0 {
-			var opts ebiten.DrawImageOptions
-			opts.ColorScale.ScaleWithColor(cell.fgColor)
-			opts.GeoM.Scale(1.0, 1.0/16.0)
-			opts.GeoM.Translate(x, y)
-			opts.GeoM.Translate(0, float64(et.cell_size.Y)*(1.0-1.0/8.0))
-			opts.GeoM.Concat(geom)
-			dst.DrawImage(et.cell_image, &opts)
+		if !cell.synced || !cell.point.In(visible) {
+			continue
 		}
 
-		// Add strike-through
-		// We define a strike-through as 1/16 of center of the character cell.
-		if (attr & tcell.AttrStrikeThrough) != 0 {
-			var opts ebiten.DrawImageOptions
-			opts.ColorScale.ScaleWithColor(cell.fgColor)
-			opts.GeoM.Scale(1.0, 1.0/16.0)
-			opts.GeoM.Translate(x, y)
-			opts.GeoM.Translate(0, float64(et.cell_size.Y)/2.0-1.0/32.0)
-			opts.GeoM.Concat(geom)
-			dst.DrawImage(et.cell_image, &opts)
-		}
+		et.drawCell(dst, cell, geom, cell_frame)
 	}
 
-	cursor_blink_ms := now % et.blink_cursor_ms
-	cursor_blink_phase := cursor_blink_ms < (et.blink_cursor_ms / 2)
-
 	// Draw cursor
 	opts := ebiten.DrawImageOptions{}
 	opts.ColorScale.ScaleWithColor(e_color_of(et.cursor_color))
+	opts.ColorScale.ScaleAlpha(cursor_blink_fade)
+	opts.GeoM.Scale(float64(cursor_width*et.cell_size.X), float64(et.cell_size.Y))
 
-	metrics := et.face.Metrics()
+	// The fallback block cursor is drawn as its cells, inverted.
+	var inverted []*cell
+	if cursor.X >= 0 && cursor.X < grid_width && cursor.Y >= 0 && !cursor_hidden {
+		for x := cursor.X; x < cursor.X+cursor_width; x++ {
+			if n := cursor.Y*grid_width + x; n < len(et.grid_draw) && et.grid_draw[n].synced {
+				inverted = append(inverted, invertCell(&et.grid_draw[n]))
+			}
+		}
+	}
 
 	switch et.cursor_style {
 	case tcell.CursorStyleDefault:
@@ -249,50 +724,716 @@ func (et *ETCellGame) Draw(dst *ebiten.Image) {
 		fallthrough
 	case tcell.CursorStyleBlinkingUnderline:
 		// Bar is 1/8 of text cell, below baseline.
-		opts.GeoM.Scale(1.0, 1.0/8.0)
-		opts.GeoM.Translate(0, metrics.HAscent+float64(et.cell_size.Y)*1.0/8.0)
+		top, bottom, _ := cursorSpan(et.cursor_style, baseline, et.cell_size.Y)
+		opts.GeoM.Scale(1.0, (bottom-top)/float64(et.cell_size.Y))
+		opts.GeoM.Translate(0, top)
 	case tcell.CursorStyleSteadyBlock:
 		cursor_blink_phase = false
 		fallthrough
 	case tcell.CursorStyleBlinkingBlock:
+		if cursor_fallback {
+			if !cursor_blink_phase {
+				for _, inverted := range inverted {
+					et.renderCell(dst, inverted, geom, cellFrame{pixel_snap: frame.pixel_snap})
+				}
+			}
+			cursor_blink_phase = true // Nothing more to draw.
+			break
+		}
 		// Block is entire text cell.
 		// c_out = c_src x 1 - c_dst x 1
 		// a_out = a_src x 1 + a_dst x 0
-		opts.Blend = ebiten.Blend{
-			BlendFactorSourceRGB:      ebiten.BlendFactorOne,
-			BlendFactorDestinationRGB: ebiten.BlendFactorOne,
-			BlendOperationRGB:         ebiten.BlendOperationSubtract,
-
-			BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
-			BlendFactorDestinationAlpha: ebiten.BlendFactorZero,
-			BlendOperationAlpha:         ebiten.BlendOperationAdd,
-		}
+		opts.Blend = cursor_blend
 	case tcell.CursorStyleSteadyBar:
 		cursor_blink_phase = false
 		fallthrough
 	case tcell.CursorStyleBlinkingBar:
 		// Bar is 1/4 of text cell, above baseline.
-		opts.GeoM.Scale(1.0, 1.0/4.0)
-		opts.GeoM.Translate(0, metrics.HAscent-float64(et.cell_size.Y)*1.0/4.0)
+		top, bottom, _ := cursorSpan(et.cursor_style, baseline, et.cell_size.Y)
+		opts.GeoM.Scale(1.0, (bottom-top)/float64(et.cell_size.Y))
+		opts.GeoM.Translate(0, top)
 	}
 
-	if !cursor_blink_phase {
-		pos := image.Point{X: et.cursor.X * et.cell_size.X,
-			Y: et.cursor.Y * et.cell_size.Y}
-		opts.GeoM.Translate(float64(pos.X), float64(pos.Y))
-		opts.GeoM.Concat(geom)
-		dst.DrawImage(et.cell_image, &opts)
+	if !cursor_blink_phase && !cursor_hidden {
+		pos := image.Point{X: cursor.X * et.cell_size.X,
+			Y: cursor.Y * et.cell_size.Y}
+		opts.GeoM.Concat(cellGeoM(float64(pos.X), float64(pos.Y), geom, frame.pixel_snap))
+		dst.DrawImage(white_image, &opts)
+	}
+
+	// Outline the keyboard mouse cell.
+	if kbd_mouse {
+		pos := image.Point{X: kbd_mouse_point.X * et.cell_size.X,
+			Y: (kbd_mouse_point.Y + visible.Min.Y) * et.cell_size.Y}
+		for _, edge := range ringEdges(et.cell_size, 1) {
+			var opts ebiten.DrawImageOptions
+			opts.ColorScale.ScaleWithColor(e_color_of(et.cursor_color))
+			opts.GeoM.Scale(float64(edge.Dx()), float64(edge.Dy()))
+			opts.GeoM.Translate(float64(pos.X+edge.Min.X), float64(pos.Y+edge.Min.Y))
+			opts.GeoM.Concat(geom)
+			dst.DrawImage(white_image, &opts)
+		}
+	}
+
+	if dst != screen {
+		var opts ebiten.DrawImageOptions
+		opts.GeoM = screen_geom
+		screen.DrawImage(dst, &opts)
+	}
+
+	if magnifier.enabled {
+		et.drawMagnifier(screen, screen_geom, cursor, visible, magnifier, frame)
+	}
+
+	// Draw the scrollbar, over the right edge of the grid.
+	if !thumb.Empty() {
+		track := image.Rect(thumb.Min.X, 0, thumb.Max.X, layout.Dy())
+		for _, bar := range []struct {
+			rect  image.Rectangle
+			alpha float32
+		}{{track, 0.25}, {thumb, 1}} {
+			var opts ebiten.DrawImageOptions
+			opts.ColorScale.ScaleWithColor(scrollbar.color)
+			opts.ColorScale.ScaleAlpha(bar.alpha)
+			opts.GeoM.Scale(float64(bar.rect.Dx()), float64(bar.rect.Dy()))
+			opts.GeoM.Translate(float64(bar.rect.Min.X), float64(bar.rect.Min.Y))
+			opts.GeoM.Concat(screen_geom)
+			screen.DrawImage(white_image, &opts)
+		}
+	}
+
+	// Draw the debug grid overlay, over the cell boundaries.
+	if grid_overlay != nil {
+		for _, line := range gridLines(layout.Size(), et.cell_size) {
+			var opts ebiten.DrawImageOptions
+			opts.ColorScale.ScaleWithColor(*grid_overlay)
+			opts.GeoM.Scale(float64(line.Dx()), float64(line.Dy()))
+			opts.GeoM.Translate(float64(line.Min.X), float64(line.Min.Y))
+			opts.GeoM.Concat(screen_geom)
+			screen.DrawImage(white_image, &opts)
+		}
+	}
+
+	// Dim the whole game while it does not have focus.
+	if inactive_dim > 0 {
+		size := layout.Size().Add(image.Pt(2*frame_padding, 2*frame_padding))
+		var opts ebiten.DrawImageOptions
+		opts.ColorScale.ScaleWithColor(color.Black)
+		opts.ColorScale.ScaleAlpha(float32(inactive_dim))
+		opts.GeoM.Scale(float64(size.X), float64(size.Y))
+		opts.GeoM.Concat(frame_geom)
+		screen.DrawImage(white_image, &opts)
+	}
+
+	// Draw the focus indicator, over the edges of the game.
+	if focus_ring {
+		size := layout.Size().Add(image.Pt(2*frame_padding, 2*frame_padding))
+		for _, edge := range ringEdges(size, focus_thickness) {
+			var opts ebiten.DrawImageOptions
+			opts.ColorScale.ScaleWithColor(focus_color)
+			opts.GeoM.Scale(float64(edge.Dx()), float64(edge.Dy()))
+			opts.GeoM.Translate(float64(edge.Min.X), float64(edge.Min.Y))
+			opts.GeoM.Concat(frame_geom)
+			screen.DrawImage(white_image, &opts)
+		}
 	}
 }
 
+// scrollbarThumb returns the thumb of a scrollbar of the given width, on the
+// right edge of a grid of the given size, showing the visible rows of the
+// grid buffer. There is no thumb if all the buffer rows are visible.
+func scrollbarThumb(size image.Point, width int, rows int, buffer_rows int, offset int) (thumb image.Rectangle) {
+	width = min(width, size.X)
+	if width <= 0 || rows <= 0 || buffer_rows <= rows {
+		return
+	}
+
+	// The thumb is at least as tall as it is wide, to remain visible.
+	height := max(size.Y*rows/buffer_rows, min(width, size.Y))
+	top := (size.Y - height) * offset / (buffer_rows - rows)
+
+	return image.Rect(size.X-width, top, size.X, top+height)
+}
+
+// gridLines returns the one pixel wide lines along the boundaries of the
+// cells of a grid of the given size. The lines of the right and bottom
+// edges are just inside the grid.
+func gridLines(size image.Point, cell_size image.Point) (lines []image.Rectangle) {
+	if cell_size.X <= 0 || cell_size.Y <= 0 {
+		return
+	}
+
+	for x := 0; x <= size.X; x += cell_size.X {
+		x := min(x, size.X-1)
+		lines = append(lines, image.Rect(x, 0, x+1, size.Y))
+	}
+	for y := 0; y <= size.Y; y += cell_size.Y {
+		y := min(y, size.Y-1)
+		lines = append(lines, image.Rect(0, y, size.X, y+1))
+	}
+
+	return
+}
+
+// invertCell returns a copy of a cell, with its colors swapped.
+func invertCell(c *cell) *cell {
+	inverted := *c
+	inverted.fgColor, inverted.bgColor = c.bgColor, c.fgColor
+
+	return &inverted
+}
+
+// ringEdges returns the edges of a ring of a given thickness, just within
+// a rectangle of the given size.
+func ringEdges(size image.Point, thickness int) (edges []image.Rectangle) {
+	thickness = min(thickness, size.X/2, size.Y/2)
+	if thickness <= 0 {
+		return
+	}
+
+	edges = []image.Rectangle{
+		image.Rect(0, 0, size.X, thickness),
+		image.Rect(0, size.Y-thickness, size.X, size.Y),
+		image.Rect(0, thickness, thickness, size.Y-thickness),
+		image.Rect(size.X-thickness, thickness, size.X, size.Y-thickness),
+	}
+
+	return
+}
+
+// drawMagnifier draws a magnified inset of the cells around the cursor,
+// centered on the cursor cell, if it is within the visible rows.
+func (et *ETCellGame) drawMagnifier(dst *ebiten.Image, geom ebiten.GeoM, cursor image.Point, visible image.Rectangle, magnifier magnifier, frame cellFrame) {
+	if !cursor.In(visible) {
+		return
+	}
+
+	region := image.Rectangle{
+		Min: cursor.Sub(image.Pt(magnifier.radius, magnifier.radius)),
+		Max: cursor.Add(image.Pt(magnifier.radius+1, magnifier.radius+1)),
+	}
+
+	size := image.Point{
+		X: region.Dx() * et.cell_size.X,
+		Y: region.Dy() * et.cell_size.Y,
+	}
+	if et.magnifier_image == nil || !et.magnifier_image.Bounds().Size().Eq(size) {
+		et.magnifier_image = ebiten.NewImage(size.X, size.Y)
+	}
+	et.magnifier_image.Clear()
+
+	var region_geom ebiten.GeoM
+	region_geom.Translate(float64(-region.Min.X*et.cell_size.X), float64(-region.Min.Y*et.cell_size.Y))
+
+	for n := range et.grid_draw {
+		cell := &et.grid_draw[n]
+
+		if !cell.synced || !cell.point.In(region) {
+			continue
+		}
+
+		et.drawCell(et.magnifier_image, cell, region_geom, frame)
+	}
+
+	var opts ebiten.DrawImageOptions
+	opts.GeoM.Translate(-float64(size.X)/2, -float64(size.Y)/2)
+	opts.GeoM.Scale(magnifier.factor, magnifier.factor)
+	opts.GeoM.Translate(
+		(float64(cursor.X)+0.5)*float64(et.cell_size.X),
+		(float64(cursor.Y-visible.Min.Y)+0.5)*float64(et.cell_size.Y),
+	)
+	opts.GeoM.Concat(geom)
+	opts.Filter = ebiten.FilterLinear
+	dst.DrawImage(et.magnifier_image, &opts)
+}
+
+// drawState is the state a frame is drawn from, when redrawing on events.
+type drawState struct {
+	serial             uint64 // Content serial, see show().
+	revealing          bool   // Frames are always redrawn when revealing.
+	size               image.Point
+	geom               ebiten.GeoM
+	offset_x, offset_y float64
+	layout             image.Rectangle
+	scroll_offset      int
+	cursor             image.Point
+	cursor_style       tcell.CursorStyle
+	cursor_color       tcell.Color
+	text_blink_phase   bool
+	cursor_blink_phase bool
+	cursor_hidden      bool
+	text_blink_fade    float32
+	cursor_blink_fade  float32
+	magnifier          magnifier
+	hovering           bool
+	hover              image.Point
+	has_focus          bool
+	kbd_mouse          bool
+	kbd_mouse_point    image.Point
+	grid_overlay       color.RGBA
+	inactive_dim       float64
+	pixel_snap         bool
+}
+
+// drawState returns the state a frame to dst would be drawn from.
+// A frame is never the same as the last while revealing.
+// The grid lock must be held.
+func (et *ETCellGame) drawState(dst *ebiten.Image, text_blink_phase, cursor_blink_phase bool) (state drawState) {
+	state = drawState{
+		serial:             et.draw_serial,
+		revealing:          et.reveal_duration > 0,
+		size:               dst.Bounds().Max,
+		geom:               et.GeoM,
+		offset_x:           et.offset_x,
+		offset_y:           et.offset_y,
+		layout:             et.layout,
+		scroll_offset:      et.scroll_offset,
+		cursor:             et.cursor,
+		cursor_style:       et.cursor_style,
+		cursor_color:       et.cursor_color,
+		text_blink_phase:   text_blink_phase,
+		cursor_blink_phase: cursor_blink_phase,
+		cursor_hidden:      et.cursorHidden(),
+		magnifier:          et.magnifier,
+		hovering:           et.hovering,
+		hover:              et.hover,
+		has_focus:          et.has_focus,
+		kbd_mouse:          et.kbd_mouse,
+		kbd_mouse_point:    et.kbd_mouse_point,
+		inactive_dim:       et.inactiveDim(),
+		pixel_snap:         et.pixel_snap,
+	}
+
+	if et.grid_overlay != nil {
+		state.grid_overlay = *et.grid_overlay
+	}
+
+	if state.revealing {
+		state.serial = et.drawn.serial + 1
+	}
+
+	return
+}
+
+// SupportsBlend returns true unless a blend is known not to render
+// correctly, such as the subtractive blend of the block cursor. This is
+// best effort: every graphics library of ebiten v2.8 implements all of
+// the blend operations and factors, so only blends with values that are
+// not valid are reported as unsupported.
+//
+// Some drivers, such as software OpenGL renderers, have been seen to draw
+// subtractive blends incorrectly, but cannot be detected; applications
+// should offer SetCursorFallbackRender as an option for them.
+func (et *ETCellGame) SupportsBlend(blend ebiten.Blend) bool {
+	return supportsBlend(blend)
+}
+
+// supportsBlend returns true if the operations and factors of a blend are
+// all valid.
+func supportsBlend(blend ebiten.Blend) bool {
+	for _, factor := range []ebiten.BlendFactor{
+		blend.BlendFactorSourceRGB, blend.BlendFactorSourceAlpha,
+		blend.BlendFactorDestinationRGB, blend.BlendFactorDestinationAlpha,
+	} {
+		if factor > ebiten.BlendFactorOneMinusDestinationAlpha {
+			return false
+		}
+	}
+
+	for _, operation := range []ebiten.BlendOperation{blend.BlendOperationRGB, blend.BlendOperationAlpha} {
+		if operation > ebiten.BlendOperationMax {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cursorHidden returns true if the cursor is not visible, or is hidden
+// as the window is unfocused. The grid lock must be held.
+func (et *ETCellGame) cursorHidden() bool {
+	return et.cursor_hidden || (et.hide_cursor_unfocused && !et.window_focused())
+}
+
+// tapPoint returns the screen point of a mouse button or touch just pressed.
+func tapPoint() (pt image.Point, ok bool) {
+	for e_button := range ebiten_button_map {
+		if inpututil.IsMouseButtonJustPressed(e_button) {
+			pt.X, pt.Y = ebiten.CursorPosition()
+			return pt, true
+		}
+	}
+
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		pt.X, pt.Y = ebiten.TouchPosition(id)
+		return pt, true
+	}
+
+	return
+}
+
+// updateFocus updates and returns whether the game has keyboard focus,
+// for the focus model, given whether the mouse is over its grid, and
+// whether its grid was just tapped or clicked.
+// The grid lock must be held.
+func (et *ETCellGame) updateFocus(mouse_in bool, tap_in bool) bool {
+	switch et.focus_model {
+	case FocusModelClick:
+		if tap_in {
+			et.focus_game = et
+		}
+		return et.focus_game == et
+	case FocusModelAlways:
+		if et.focus_game == nil {
+			et.focus_game = et
+		}
+		return et.focus_game == et
+	}
+
+	return mouse_in
+}
+
+// cellFrame is the state of a frame for drawing cells.
+type cellFrame struct {
+	text_blink_phase bool          // Blinking text is hidden or swapped.
+	text_blink_dim   float32       // How far fading blinking text is faded out, from 0 to 1.
+	cleared          *color.RGBA   // Background color dst is already cleared to, if any.
+	now              time.Time     // Time of the frame, if revealing.
+	reveal           time.Duration // Duration of the reveal animation, if any.
+	cache            *cellCache    // Pre-composited cell images, if cached.
+
+	background func(x, y int) *ebiten.Image // Cell background images, if set.
+	pixel_snap bool                         // Cell origins are rounded to whole pixels.
+}
+
+// fade returns the opacity of the text of a cell being revealed.
+func (frame cellFrame) fade(cell *cell) float32 {
+	if frame.reveal <= 0 {
+		return 1
+	}
+
+	elapsed := frame.now.Sub(cell.revealed)
+	if elapsed >= frame.reveal {
+		return 1
+	}
+
+	return float32(max(0, elapsed)) / float32(frame.reveal)
+}
+
+// drawCell draws a single cell, with its background, glyphs, and lines.
+// If cells are cached, the cell is drawn from its pre-composited image,
+// unless it is blinking, being revealed, or has a background pattern.
+func (et *ETCellGame) drawCell(dst *ebiten.Image, cell *cell, geom ebiten.GeoM, frame cellFrame) {
+	attr := cell.attr
+
+	if frame.cache == nil || frame.background != nil || (attr&tcell.AttrBlink) != 0 || frame.fade(cell) < 1 {
+		et.renderCell(dst, cell, geom, frame)
+		return
+	}
+
+	key := cellKey{
+		glyph:     cell.glyph,
+		fg:        cell.fgColor,
+		bg:        cell.bgColor,
+		underline: (attr&tcell.AttrUnderline) != 0 || cell.url != "",
+		strike:    (attr & tcell.AttrStrikeThrough) != 0,
+	}
+	img := frame.cache.get(key, func(img *ebiten.Image) {
+		origin := *cell
+		origin.point = image.Point{}
+		et.renderCell(img, &origin, ebiten.GeoM{}, cellFrame{})
+	})
+
+	var opts ebiten.DrawImageOptions
+	opts.GeoM = cellGeoM(float64(cell.point.X*et.cell_size.X), float64(cell.point.Y*et.cell_size.Y), geom, frame.pixel_snap)
+	dst.DrawImage(img, &opts)
+}
+
+// renderCell renders a single cell, with its background, glyphs, and lines.
+func (et *ETCellGame) renderCell(dst *ebiten.Image, cell *cell, geom ebiten.GeoM, frame cellFrame) {
+	x := float64(cell.point.X * et.cell_size.X)
+	y := float64(cell.point.Y * et.cell_size.Y)
+	cell_geom := cellGeoM(x, y, geom, frame.pixel_snap)
+
+	var pattern *ebiten.Image
+	if frame.background != nil {
+		pattern = frame.background(cell.point.X, cell.point.Y)
+	}
+
+	if pattern != nil {
+		size := pattern.Bounds().Size()
+		var bg_options ebiten.DrawImageOptions
+		bg_options.GeoM.Scale(float64(et.cell_size.X)/float64(max(size.X, 1)), float64(et.cell_size.Y)/float64(max(size.Y, 1)))
+		bg_options.GeoM.Concat(cell_geom)
+
+		dst.DrawImage(pattern, &bg_options)
+	} else if frame.cleared == nil || *frame.cleared != cell.bgColor {
+		var bg_options ebiten.DrawImageOptions
+		bg_options.ColorScale.ScaleWithColor(cell.bgColor)
+		bg_options.GeoM.Scale(float64(et.cell_size.X), float64(et.cell_size.Y))
+		bg_options.GeoM.Concat(cell_geom)
+
+		dst.DrawImage(white_image, &bg_options)
+	}
+
+	attr := cell.attr
+
+	fg := cell.fgColor
+	visible := true
+	if (attr & tcell.AttrBlink) != 0 {
+		fg, visible = et.blinkText(fg, frame.text_blink_phase)
+		if frame.text_blink_dim > 0 {
+			fg = et.blinkTextFade(fg, 1-frame.text_blink_dim)
+		}
+	}
+
+	// Fade in the text of cells being revealed.
+	if fade := frame.fade(cell); fade < 1 {
+		fg = color.RGBA{
+			R: uint8(float32(fg.R) * fade),
+			G: uint8(float32(fg.G) * fade),
+			B: uint8(float32(fg.B) * fade),
+			A: uint8(float32(fg.A) * fade),
+		}
+	}
+
+	var fg_options ebiten.DrawImageOptions
+	fg_options.ColorScale = glyphColorScale(cell, fg)
+	fg_options.GeoM.Concat(cell_geom)
+
+	// If now blinking, don't draw the text. We _do_ draw underlines and strikethroughs.
+	if visible {
+		if cell.glyph != nil {
+			dst.DrawImage(cell.glyph, &fg_options)
+		}
+	}
+
+	// Draw underline, if needed. Hyperlinks are always underlined.
+	// We define an underline as the top 1/16 of lower 1/8th of the cell.
+	if (attr&tcell.AttrUnderline) != 0 || cell.url != "" {
+		var opts ebiten.DrawImageOptions
+		opts.ColorScale.ScaleWithColor(fg)
+		opts.GeoM.Scale(float64(et.cell_size.X), float64(et.cell_size.Y)/16.0)
+		opts.GeoM.Translate(0, float64(et.cell_size.Y)*(1.0-1.0/8.0))
+		opts.GeoM.Concat(cell_geom)
+		dst.DrawImage(white_image, &opts)
+	}
+
+	// Add strike-through
+	// We define a strike-through as 1/16 of center of the character cell.
+	if (attr & tcell.AttrStrikeThrough) != 0 {
+		var opts ebiten.DrawImageOptions
+		opts.ColorScale.ScaleWithColor(fg)
+		opts.GeoM.Scale(float64(et.cell_size.X), float64(et.cell_size.Y)/16.0)
+		opts.GeoM.Translate(0, float64(et.cell_size.Y)/2.0-1.0/32.0)
+		opts.GeoM.Concat(cell_geom)
+		dst.DrawImage(white_image, &opts)
+	}
+}
+
+// cursorSpan returns the vertical span, in a cell, of the underline and bar
+// cursors, which are placed relative to the baseline of the glyphs, and
+// kept inside the cell. ok is false for the other cursor styles.
+func cursorSpan(style tcell.CursorStyle, baseline float64, height int) (top, bottom float64, ok bool) {
+	h := float64(height)
+
+	switch style {
+	case tcell.CursorStyleSteadyUnderline, tcell.CursorStyleBlinkingUnderline:
+		// 1/8 of the cell, 1/8 of the cell below the baseline, or
+		// closer if that is past the cell, but never over the glyphs.
+		top = min(baseline+h/8, h-h/8)
+		top = max(top, min(baseline, h-1))
+		bottom = min(top+h/8, h)
+		ok = true
+	case tcell.CursorStyleSteadyBar, tcell.CursorStyleBlinkingBar:
+		// 1/4 of the cell, up from the baseline.
+		bottom = min(max(baseline, h/4), h)
+		top = bottom - h/4
+		ok = true
+	}
+
+	return
+}
+
+// cellGeoM returns the transform of a cell drawn at x, y through geom. If
+// snapping, and geom is not rotated or skewed, the origin of the cell is
+// rounded to a whole pixel.
+func cellGeoM(x, y float64, geom ebiten.GeoM, snap bool) (cell_geom ebiten.GeoM) {
+	cell_geom.Translate(x, y)
+	cell_geom.Concat(geom)
+
+	if snap && geom.Element(0, 1) == 0 && geom.Element(1, 0) == 0 {
+		tx, ty := cell_geom.Element(0, 2), cell_geom.Element(1, 2)
+		cell_geom.Translate(math.Round(tx)-tx, math.Round(ty)-ty)
+	}
+
+	return
+}
+
+// glyphColorScale returns the color scale a cell's glyph is drawn with:
+// tinted with the foreground color, or for color glyphs, only faded by its
+// alpha, so that they are drawn as they are.
+func glyphColorScale(cell *cell, fg color.RGBA) (scale ebiten.ColorScale) {
+	if cell.color_glyph {
+		scale.ScaleAlpha(float32(fg.A) / 0xff)
+	} else {
+		scale.ScaleWithColor(fg)
+	}
+
+	return
+}
+
+// SetFrame sets a frame of padding pixels around the grid, drawn in the
+// given color. The padding is taken from the layout size, reducing the
+// size of the grid. A nil color leaves the frame transparent.
+func (et *ETCellGame) SetFrame(padding int, color color.Color) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.frame_padding = max(padding, 0)
+	et.frame_color = color
+}
+
+// SetFocusIndicator sets a ring of thickness pixels, drawn in the given
+// color around the edges of the game while it has focus, see
+// SetFocusModel. This shows which of several games is active. A nil color
+// or zero thickness disables the indicator.
+func (et *ETCellGame) SetFocusIndicator(color color.Color, thickness int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.focus_color = color
+	et.focus_thickness = max(thickness, 0)
+}
+
+// SetInactiveDim sets the opacity, from 0 to 1, of a dark overlay drawn
+// over the whole game while it does not have focus, see SetFocusModel, so
+// that the active one of several panes stands out. Dimming is off, with
+// an opacity of 0, by default.
+func (et *ETCellGame) SetInactiveDim(alpha float64) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.inactive_dim = max(0, min(alpha, 1))
+}
+
+// inactiveDim returns the opacity of the dimming of the game, which is
+// only dimmed while it does not have focus. The grid lock must be held.
+func (et *ETCellGame) inactiveDim() float64 {
+	if et.has_focus {
+		return 0
+	}
+
+	return et.inactive_dim
+}
+
+// SetGridOverlay sets whether thin lines are drawn in a color along the
+// boundaries of every cell, for debugging layout, padding and spacing.
+// The lines are transformed by GeoM, as the grid is. A nil color is a
+// translucent gray. The overlay is off by default.
+func (et *ETCellGame) SetGridOverlay(enable bool, overlay_color color.Color) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if !enable {
+		et.grid_overlay = nil
+		return
+	}
+
+	if overlay_color == nil {
+		overlay_color = color.RGBA{0x40, 0x40, 0x40, 0x80}
+	}
+	rgba := color.RGBAModel.Convert(overlay_color).(color.RGBA)
+	et.grid_overlay = &rgba
+}
+
+// gridGeoM returns the transform of the grid, which is inset from the
+// GeoM transform by the frame padding.
+func (et *ETCellGame) gridGeoM() (geom ebiten.GeoM) {
+	geom.Translate(float64(et.frame_padding), float64(et.frame_padding))
+	geom.Concat(et.GeoM)
+
+	return
+}
+
+// windowGeoM returns the transform of the grid to the window, in device
+// independent pixels.
+func (et *ETCellGame) windowGeoM() (geom ebiten.GeoM) {
+	geom = et.gridGeoM()
+	geom.Concat(et.final_geom)
+	if et.device_scale > 0 {
+		geom.Scale(1/et.device_scale, 1/et.device_scale)
+	}
+
+	return
+}
+
+// WindowToCell returns the screen cell at a window position, in device
+// independent pixels, such as those of ebiten.WindowSize(). The position
+// is mapped through the GeoM transform, the frame padding, the scaling of
+// a logical size (see SetLogicalSize), and the device scale factor. inside
+// is false if the position is outside of the grid, or the GeoM transform
+// is not invertible.
+func (et *ETCellGame) WindowToCell(px, py int) (x, y int, inside bool) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	point, ok := inversePoint(et.windowGeoM(), px, py)
+	if !ok {
+		return
+	}
+
+	x = int(math.Floor(float64(point.X) / float64(et.cell_size.X)))
+	y = int(math.Floor(float64(point.Y) / float64(et.cell_size.Y)))
+	inside = point.In(et.layout)
+
+	return
+}
+
+// CellToWindow returns the window position, in device independent
+// pixels, of the top left corner of a screen cell. This is the inverse of
+// WindowToCell; with a rotating GeoM transform, the corner is the one at
+// the cell's origin.
+func (et *ETCellGame) CellToWindow(x, y int) image.Point {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	geom := et.windowGeoM()
+	fx, fy := geom.Apply(float64(x*et.cell_size.X), float64(y*et.cell_size.Y))
+
+	return image.Point{X: int(math.Round(fx)), Y: int(math.Round(fy))}
+}
+
+// SetPixelOffset sets a pixel offset for drawing the grid, for smooth
+// scrolling of the content by less than a cell. The offset is applied
+// before the GeoM transform, and cells moved past the edges of the
+// layout are clipped.
+func (et *ETCellGame) SetPixelOffset(dx, dy float64) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.offset_x = dx
+	et.offset_y = dy
+}
+
 // LayoutF returns the floating point layout.
 func (et *ETCellGame) LayoutF(outsideWidth, outsideHeight float64) (screenWidth, screenHeight float64) {
-	monitor_scale := ebiten.Monitor().DeviceScaleFactor()
+	monitor_scale := deviceScale(ebiten.Monitor())
+	et.setDeviceScale(monitor_scale)
 	ow := int(float64(outsideWidth) * monitor_scale)
 	oh := int(float64(outsideHeight) * monitor_scale)
 	sw, sh := et.Layout(ow, oh)
 	screenWidth = float64(sw)
 	screenHeight = float64(sh)
+
+	// ebiten fits the layout to the window; a logical size is already
+	// scaled by Draw.
+	et.grid_lock.Lock()
+	if et.logical_size.X <= 0 || et.logical_size.Y <= 0 {
+		et.final_geom = fitGeoM(image.Pt(sw, sh), image.Pt(ow, oh))
+	}
+	et.grid_lock.Unlock()
+
 	return
 }
 
@@ -301,13 +1442,66 @@ func (et *ETCellGame) Layout(outsideWidth, outsideHeight int) (screenWidth, scre
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
 
-	screen_rows := outsideWidth / et.cell_size.X
-	screen_cols := outsideHeight / et.cell_size.Y
+	// ebiten calls Layout before Draw, so the default font may not be set.
+	et.init()
+
+	padding := 2 * et.frame_padding
+	outside := image.Pt(outsideWidth, outsideHeight)
+
+	logical := et.logical_size.X > 0 && et.logical_size.Y > 0
+	if logical {
+		outsideWidth, outsideHeight = et.logical_size.X, et.logical_size.Y
+	}
+
+	screen_rows := (outsideWidth - padding) / et.cell_size.X
+	screen_cols := (outsideHeight - padding) / et.cell_size.Y
 
 	et.setScreenSize(screen_rows, screen_cols)
 
-	screenWidth = et.layout.Dx()
-	screenHeight = et.layout.Dy()
+	screenWidth = et.layout.Dx() + padding
+	screenHeight = et.layout.Dy() + padding
+
+	// The logical size is scaled to the outside size by Draw.
+	et.final_geom.Reset()
+	if logical {
+		et.final_geom = integerGeoM(fitGeoM(et.logical_size, outside), et.logical_size, outside)
+		screenWidth, screenHeight = outside.X, outside.Y
+	}
+
+	return
+}
+
+// fitGeoM returns the transform of an offscreen image to fit the center
+// of the screen, as ebiten draws the final screen.
+func fitGeoM(offscreen, screen image.Point) (geom ebiten.GeoM) {
+	if offscreen.X <= 0 || offscreen.Y <= 0 {
+		return
+	}
+
+	scale := min(float64(screen.X)/float64(offscreen.X), float64(screen.Y)/float64(offscreen.Y))
+	geom.Scale(scale, scale)
+	geom.Translate(
+		(float64(screen.X)-float64(offscreen.X)*scale)/2,
+		(float64(screen.Y)-float64(offscreen.Y)*scale)/2,
+	)
+
+	return
+}
+
+// integerGeoM returns the transform of an offscreen image to the center
+// of the screen, scaled down to an integer factor from that of geom.
+// Images scaled down are unchanged.
+func integerGeoM(geom ebiten.GeoM, offscreen, screen image.Point) (integer ebiten.GeoM) {
+	scale := math.Floor(geom.Element(0, 0))
+	if scale < 1 {
+		return geom
+	}
+
+	integer.Scale(scale, scale)
+	integer.Translate(
+		math.Floor((float64(screen.X)-float64(offscreen.X)*scale)/2),
+		math.Floor((float64(screen.Y)-float64(offscreen.Y)*scale)/2),
+	)
 
 	return
 }
@@ -0,0 +1,168 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// htmlStyle is the appearance of a run of cells in DumpHTML.
+type htmlStyle struct {
+	fg, bg color.RGBA
+	attr   tcell.AttrMask
+	url    string
+}
+
+// DumpHTML writes the cells of the screen to w as an HTML <pre> element,
+// with inline CSS for the colors and attributes the cells are drawn with.
+// Runs of cells of the same appearance are coalesced into a single <span>,
+// or an <a> for OSC 8 hyperlinks with an http, https, mailto or file URL;
+// hyperlinks with other URLs, such as javascript:, are underlined spans.
+// The <pre> element has the colors of the default style, and spans only set
+// the colors that differ from them.
+//
+// Bold, italic, underline, strike through and blink attributes map to CSS;
+// reverse, dim and the color filter are applied to the colors, as when
+// drawn. Cells that were never set are spaces, and the cell after a wide
+// rune is skipped. The content set by SetContent is written, whether or
+// not it has been shown.
+func (et *ETCellScreen) DumpHTML(w io.Writer) (err error) {
+	et.grid_lock.Lock()
+	text := et.dumpHTML()
+	et.grid_lock.Unlock()
+
+	_, err = io.WriteString(w, text)
+
+	return
+}
+
+// dumpHTML returns the cells of the screen as an HTML <pre> element.
+// The grid lock must be held.
+func (et *ETCellScreen) dumpHTML() string {
+	var sb strings.Builder
+
+	style_default := withoutAttrs(et.style_default, et.attr_disabled)
+	def_fg, def_bg, _ := et.resolveStyle(tcell.StyleDefault, style_default)
+	fmt.Fprintf(&sb, `<pre style="color:%s;background-color:%s">`, cssColor(def_fg), cssColor(def_bg))
+
+	for y := 0; y < et.grid_size.Y; y++ {
+		if y > 0 {
+			sb.WriteString("\n")
+		}
+
+		var run htmlStyle
+		var text strings.Builder
+		flush := func() {
+			if text.Len() > 0 {
+				writeHTMLRun(&sb, run, def_fg, def_bg, text.String())
+				text.Reset()
+			}
+		}
+
+		for x := 0; x < et.grid_size.X; x++ {
+			c := &et.grid[y*et.grid_size.X+x]
+
+			style := withoutAttrs(c.Style, et.attr_disabled)
+			fg, bg, attr := et.resolveStyle(style, style_default)
+			cell_style := htmlStyle{fg: fg, bg: bg, attr: attr, url: urlOf(style)}
+			if cell_style != run {
+				flush()
+				run = cell_style
+			}
+
+			cluster := " "
+			if c.Rune != 0 {
+				cluster = string(append([]rune{c.Rune}, c.Combining...))
+			}
+			text.WriteString(html.EscapeString(cluster))
+
+			// Wide runes cover the next cell.
+			if et.runeWidth(c.Rune) > 1 {
+				x++
+			}
+		}
+		flush()
+	}
+
+	sb.WriteString("</pre>\n")
+
+	return sb.String()
+}
+
+// writeHTMLRun writes a run of escaped text of a style, in a <span> (or
+// an <a> for hyperlinks) if the style is not the default.
+func writeHTMLRun(sb *strings.Builder, run htmlStyle, def_fg, def_bg color.RGBA, text string) {
+	var css []string
+	if run.fg != def_fg {
+		css = append(css, "color:"+cssColor(run.fg))
+	}
+	if run.bg != def_bg {
+		css = append(css, "background-color:"+cssColor(run.bg))
+	}
+	if (run.attr & tcell.AttrBold) != 0 {
+		css = append(css, "font-weight:bold")
+	}
+	if (run.attr & tcell.AttrItalic) != 0 {
+		css = append(css, "font-style:italic")
+	}
+
+	var decorations []string
+	if (run.attr&tcell.AttrUnderline) != 0 || run.url != "" {
+		decorations = append(decorations, "underline")
+	}
+	if (run.attr & tcell.AttrStrikeThrough) != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if (run.attr & tcell.AttrBlink) != 0 {
+		decorations = append(decorations, "blink")
+	}
+	if len(decorations) > 0 {
+		css = append(css, "text-decoration:"+strings.Join(decorations, " "))
+	}
+
+	tag, attrs := "span", ""
+	if isSafeURL(run.url) {
+		tag, attrs = "a", fmt.Sprintf(` href="%s"`, html.EscapeString(run.url))
+	}
+
+	if len(css) == 0 && attrs == "" {
+		sb.WriteString(text)
+		return
+	}
+	if len(css) > 0 {
+		attrs += fmt.Sprintf(` style="%s"`, strings.Join(css, ";"))
+	}
+
+	fmt.Fprintf(sb, "<%s%s>%s</%s>", tag, attrs, text, tag)
+}
+
+// html_url_schemes are the URL schemes of hyperlinks written as links.
+var html_url_schemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"file":   true,
+}
+
+// isSafeURL returns true if a hyperlink URL has a scheme that is safe to
+// link to from HTML.
+func isSafeURL(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return html_url_schemes[strings.ToLower(parsed.Scheme)]
+}
+
+// cssColor returns the CSS hex notation of a color.
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -44,6 +44,18 @@ var ebiten_key_map = map[ebiten.Key]tcell.Key{
 	ebiten.KeyF10:        tcell.KeyF10,
 	ebiten.KeyF11:        tcell.KeyF11,
 	ebiten.KeyF12:        tcell.KeyF12,
+	ebiten.KeyF13:        tcell.KeyF13,
+	ebiten.KeyF14:        tcell.KeyF14,
+	ebiten.KeyF15:        tcell.KeyF15,
+	ebiten.KeyF16:        tcell.KeyF16,
+	ebiten.KeyF17:        tcell.KeyF17,
+	ebiten.KeyF18:        tcell.KeyF18,
+	ebiten.KeyF19:        tcell.KeyF19,
+	ebiten.KeyF20:        tcell.KeyF20,
+	ebiten.KeyF21:        tcell.KeyF21,
+	ebiten.KeyF22:        tcell.KeyF22,
+	ebiten.KeyF23:        tcell.KeyF23,
+	ebiten.KeyF24:        tcell.KeyF24,
 	ebiten.KeyHome:       tcell.KeyHome,
 	ebiten.KeyInsert:     tcell.KeyInsert,
 	ebiten.KeyPageDown:   tcell.KeyPgDn,
@@ -53,6 +65,38 @@ var ebiten_key_map = map[ebiten.Key]tcell.Key{
 
 var tcell_key_map = map[tcell.Key]ebiten.Key{}
 
+// ebiten_typed_keys are keys that are posted as typed runes, or that
+// are modifiers of other keys.
+var ebiten_typed_keys = map[ebiten.Key]bool{
+	ebiten.KeyAltLeft:        true,
+	ebiten.KeyAltRight:       true,
+	ebiten.KeyControlLeft:    true,
+	ebiten.KeyControlRight:   true,
+	ebiten.KeyMetaLeft:       true,
+	ebiten.KeyMetaRight:      true,
+	ebiten.KeyShiftLeft:      true,
+	ebiten.KeyShiftRight:     true,
+	ebiten.KeyBackquote:      true,
+	ebiten.KeyBackslash:      true,
+	ebiten.KeyBracketLeft:    true,
+	ebiten.KeyBracketRight:   true,
+	ebiten.KeyComma:          true,
+	ebiten.KeyEqual:          true,
+	ebiten.KeyIntlBackslash:  true,
+	ebiten.KeyMinus:          true,
+	ebiten.KeyNumpadAdd:      true,
+	ebiten.KeyNumpadDecimal:  true,
+	ebiten.KeyNumpadDivide:   true,
+	ebiten.KeyNumpadEqual:    true,
+	ebiten.KeyNumpadMultiply: true,
+	ebiten.KeyNumpadSubtract: true,
+	ebiten.KeyPeriod:         true,
+	ebiten.KeyQuote:          true,
+	ebiten.KeySemicolon:      true,
+	ebiten.KeySlash:          true,
+	ebiten.KeySpace:          true,
+}
+
 func init() {
 	for e_key, t_key := range ebiten_key_map {
 		tcell_key_map[t_key] = e_key
@@ -97,3 +141,20 @@ func isKeyJustPressedOrRepeating(key ebiten.Key) bool {
 
 	return false
 }
+
+// isRawKey is true for keys that are not posted as tcell key events.
+func isRawKey(key ebiten.Key) bool {
+	switch {
+	case key >= ebiten.KeyA && key <= ebiten.KeyZ:
+		return false
+	case key >= ebiten.KeyDigit0 && key <= ebiten.KeyDigit9:
+		return false
+	case key >= ebiten.KeyNumpad0 && key <= ebiten.KeyNumpad9:
+		return false
+	}
+
+	_, mapped := ebiten_key_map[key]
+	_, modifier := ebiten_mod_map[key]
+
+	return !mapped && !modifier && !ebiten_typed_keys[key]
+}
@@ -0,0 +1,96 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// deviceScale returns the device scale factor of a monitor, or 1 if there
+// is no monitor, as before the game starts on some platforms.
+func deviceScale(monitor *ebiten.MonitorType) float64 {
+	if monitor == nil {
+		return 1
+	}
+
+	scale := monitor.DeviceScaleFactor()
+	if scale <= 0 {
+		return 1
+	}
+
+	return scale
+}
+
+// setDeviceScale records the device scale factor of the window, from
+// LayoutF, and calls the OnScaleChange callback when it changes after the
+// first layout. The grid itself is resized by the layout, as the cells
+// keep their size in device pixels.
+func (et *ETCellGame) setDeviceScale(scale float64) {
+	et.grid_lock.Lock()
+	changed := et.device_scale > 0 && et.device_scale != scale
+	et.device_scale = scale
+	fn := et.on_scale_change
+	et.grid_lock.Unlock()
+
+	if changed && fn != nil {
+		fn(scale)
+	}
+}
+
+// WindowSizeFor returns the window size, in device independent pixels,
+// that fits a grid of cols by rows cells and the frame padding on a
+// monitor, at its device scale factor, for ebiten.SetWindowSize. A nil
+// monitor is the current monitor.
+func (et *ETCellGame) WindowSizeFor(monitor *ebiten.MonitorType, cols, rows int) (width, height int) {
+	if monitor == nil {
+		monitor = ebiten.Monitor()
+	}
+
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.init()
+
+	size := et.windowSizeAt(deviceScale(monitor), cols, rows)
+
+	return size.X, size.Y
+}
+
+// windowSizeAt returns the window size, in device independent pixels, of
+// a grid of cols by rows cells and the frame padding, at a device scale.
+// The grid lock must be held.
+func (et *ETCellGame) windowSizeAt(scale float64, cols, rows int) image.Point {
+	padding := 2 * et.frame_padding
+	width := float64(max(cols, 0)*et.cell_size.X+padding) / scale
+	height := float64(max(rows, 0)*et.cell_size.Y+padding) / scale
+
+	return image.Point{X: int(math.Ceil(width)), Y: int(math.Ceil(height))}
+}
+
+// MoveToMonitor moves the window to a monitor, at a position in device
+// independent pixels from the top left of the monitor. If cols and rows
+// are positive, the window is also resized to fit a grid of that many
+// cells at the device scale factor of the monitor, see WindowSizeFor.
+//
+// Cells keep their size in device pixels, so on a monitor with a different
+// scale the next layout recomputes the grid size for the window, posting
+// a resize event, and calls the OnScaleChange callback, which may set a
+// font sized for the new scale. This also happens when the user drags the
+// window between monitors.
+func (et *ETCellGame) MoveToMonitor(monitor *ebiten.MonitorType, x, y, cols, rows int) {
+	if monitor == nil {
+		monitor = ebiten.Monitor()
+	}
+
+	if monitor != nil {
+		ebiten.SetMonitor(monitor)
+	}
+	ebiten.SetWindowPosition(x, y)
+
+	if cols > 0 && rows > 0 {
+		ebiten.SetWindowSize(et.WindowSizeFor(monitor, cols, rows))
+	}
+}
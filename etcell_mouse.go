@@ -0,0 +1,66 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"image"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// KeyboardMouseBinding binds a key, with exactly the given modifiers, to an
+// action of the keyboard driven virtual mouse: moving it by cells, and then
+// clicking its buttons, if any.
+type KeyboardMouseBinding struct {
+	Key    ebiten.Key
+	Mods   tcell.ModMask
+	Move   image.Point      // Cells to move the virtual mouse by.
+	Button tcell.ButtonMask // Buttons to click.
+}
+
+// DefaultKeyboardMouseBindings move the virtual mouse with Alt and the
+// arrow keys, click the primary button with Alt+Enter, and the secondary
+// button with Alt+Shift+Enter.
+var DefaultKeyboardMouseBindings = []KeyboardMouseBinding{
+	{Key: ebiten.KeyArrowUp, Mods: tcell.ModAlt, Move: image.Pt(0, -1)},
+	{Key: ebiten.KeyArrowDown, Mods: tcell.ModAlt, Move: image.Pt(0, 1)},
+	{Key: ebiten.KeyArrowLeft, Mods: tcell.ModAlt, Move: image.Pt(-1, 0)},
+	{Key: ebiten.KeyArrowRight, Mods: tcell.ModAlt, Move: image.Pt(1, 0)},
+	{Key: ebiten.KeyEnter, Mods: tcell.ModAlt, Button: tcell.ButtonPrimary},
+	{Key: ebiten.KeyEnter, Mods: tcell.ModAlt | tcell.ModShift, Button: tcell.ButtonSecondary},
+}
+
+// keyboardMouse applies the keyboard mouse bindings of the keys just pressed
+// or repeating, and returns the keys that were bound.
+// The grid lock must be held.
+func (et *ETCellGame) keyboardMouse(mods tcell.ModMask) (bound []ebiten.Key) {
+	for _, binding := range et.kbd_mouse_bindings {
+		if binding.Mods != mods || !isKeyJustPressedOrRepeating(binding.Key) {
+			continue
+		}
+
+		et.keyboardMouseAction(binding)
+		bound = append(bound, binding.Key)
+	}
+
+	return
+}
+
+// keyboardMouseAction moves the virtual mouse, clamped to the screen, and
+// posts its movement, and any click, as mouse events.
+// The grid lock must be held.
+func (et *ETCellGame) keyboardMouseAction(binding KeyboardMouseBinding) {
+	point := et.kbd_mouse_point.Add(binding.Move)
+	point.X = max(0, min(point.X, et.grid_size.X-1))
+	point.Y = max(0, min(point.Y, et.grid_size.Y-1))
+	et.kbd_mouse_point = point
+
+	if binding.Button == tcell.ButtonNone {
+		et.postEvent(tcell.NewEventMouse(point.X, point.Y, tcell.ButtonNone, tcell.ModNone))
+		return
+	}
+
+	et.postEvent(tcell.NewEventMouse(point.X, point.Y, binding.Button, tcell.ModNone))
+	et.postEvent(tcell.NewEventMouse(point.X, point.Y, tcell.ButtonNone, tcell.ModNone))
+}
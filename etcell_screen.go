@@ -3,14 +3,21 @@
 package tcell_ebiten
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/ezrec/tcell_ebiten/font"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/rivo/uniseg"
 )
 
 type cell struct {
@@ -18,13 +25,46 @@ type cell struct {
 	Rune      rune
 	Combining []rune
 
-	synced    bool
-	glyph     *ebiten.Image
-	combining [](*ebiten.Image)
+	synced bool
+	glyph  *ebiten.Image // Primary glyph, composited with any combining glyphs.
+
+	// Content that the glyphs and colors were last resolved for.
+	shown           bool
+	shown_rune      rune
+	shown_combining []rune
+	shown_style     tcell.Style
 
 	point   image.Point
 	fgColor color.RGBA
 	bgColor color.RGBA
+	attr    tcell.AttrMask // Attributes drawn, see SetAttrEnabled.
+	url     string         // OSC 8 hyperlink URL, if any.
+
+	revealed time.Time // When the content was resolved, for the reveal animation.
+
+	face_glyph  bool // The glyph is of the font face, so may be shaped with its line.
+	color_glyph bool // The glyph is a color image, drawn without the foreground color.
+}
+
+// GlyphProvider supplies custom glyph images for runes, in place of the
+// font face. The fg and bg colors are those the cell will be drawn with,
+// except for combining runes, which are passed opaque white on transparent
+// as their clusters are tinted when drawn.
+// If ok is false, the font face is used instead.
+type GlyphProvider func(r rune, style font.FontStyle, fg, bg color.RGBA) (glyph *ebiten.Image, ok bool)
+
+// scrollbar is the configuration of the scrollbar overlay.
+type scrollbar struct {
+	enabled bool
+	width   int         // Width, in pixels.
+	color   color.Color // Color of the thumb. The track is translucent.
+}
+
+// magnifier is the configuration of the cursor magnifier.
+type magnifier struct {
+	enabled bool
+	radius  int     // Radius of the magnified region, in cells.
+	factor  float64 // Magnification factor.
 }
 
 type ETCellScreen struct {
@@ -33,37 +73,165 @@ type ETCellScreen struct {
 	// on_beep is called when the tcell.Screen.Beep() is invoked.
 	on_beep func() error
 
+	// on_init and on_fini are called when the screen is initialized
+	// and finalized.
+	on_init func()
+	on_fini func()
+
+	// on_resize is called when the grid size changes.
+	on_resize func(cols, rows int)
+
+	// on_scale_change is called when the device scale factor changes.
+	on_scale_change func(scale float64)
+
+	// trace is called with the durations of render operations.
+	trace func(event string, d time.Duration)
+
+	// on_link_click is called when a hyperlinked cell is clicked.
+	on_link_click func(url string)
+
+	// on_cell_hover is called when the cell under the mouse changes.
+	on_cell_hover func(x, y int)
+
+	// on_raw_key is called for transitions of keys tcell does not model.
+	on_raw_key func(key ebiten.Key, pressed bool)
+
+	// on_glyph_error is called when the font face fails to generate a glyph.
+	on_glyph_error func(r rune, err error)
+
+	// on_close_request is called when the window close button is clicked.
+	on_close_request func() bool
+
+	// on_event_dropped is called when an event is dropped from a full queue.
+	on_event_dropped func(ev tcell.Event)
+
+	// cluster_cache caches composited glyph clusters.
+	cluster_cache *lruCache[clusterKey, *ebiten.Image]
+
+	// released are glyphs dropped from the caches, which are deallocated
+	// once no cell uses them.
+	released [](*ebiten.Image)
+
+	// shaped_cache caches the glyphs of shaped runs of cells.
+	shaped_cache *lruCache[shapedKey, [](*ebiten.Image)]
+
+	// combining_blend composites combining glyphs, if not BlendMax.
+	combining_blend *ebiten.Blend
+
+	box_drawing bool                        // Draw box drawing runes synthetically.
+	box_connect bool                        // Connect box drawing runes to their neighbors.
+	box_cache   map[boxArms](*ebiten.Image) // Synthetic box drawing glyphs.
+
+	enclosure_cache map[rune](*ebiten.Image) // Synthetic enclosing mark glyphs.
+
+	line_shaping bool // Shape the runs of font glyphs of each row as lines.
+
+	color_filter func(color.RGBA) color.RGBA // Transforms resolved colors, if set.
+
+	background_pattern func(x, y int) *ebiten.Image // Cell background images, if set.
+	pixel_snap         bool                         // Cell origins are rounded to whole pixels.
+
+	// mirror is a screen that content changes are forwarded to.
+	mirror tcell.Screen
+
+	// glyph_provider is consulted for glyphs before the font face.
+	glyph_provider GlyphProvider
+
+	// glyph_provider_colored draws the provider's glyphs untinted.
+	glyph_provider_colored bool
+
 	layout image.Rectangle
 
+	logical_size image.Point // Fixed render target size, if any.
+
+	max_grid_size image.Point // Largest grid size, in cells, if not the default.
+
 	face      font.Face   // Font face used for this screen.
 	grid_size image.Point // Size of the grid, in cells.
 	cell_size image.Point // Size of a single cell, in pixels.
 
+	buffer_rows   int // Rows of the grid buffer, if more than the grid.
+	scroll_offset int // First visible row of the grid buffer.
+
+	scrollbar scrollbar // Scrollbar overlay.
+
 	grid []cell // Grid of cells, not yet visible.
 
 	cursor image.Point // Position of cursor, in grid cells
 
-	style_default tcell.Style // Default text style
+	style_default tcell.Style    // Default text style
+	rune_width    func(rune) int // Width of runes, in cells, if not DefaultRuneWidth.
+	attr_disabled tcell.AttrMask // Attributes ignored when drawing.
 
 	cursor_color    tcell.Color       // Color of the cursor
 	blink_cursor_ms int64             // Cursor blink _cycle_ duration in ms.
 	cursor_style    tcell.CursorStyle // Cursor style
+	cursor_fallback bool              // Block cursor inverts its cell, rather than blending.
+	cursor_hidden   bool              // Cursor is hidden, wherever it is.
+
+	hide_cursor_unfocused bool        // Cursor is hidden while the window is unfocused.
+	window_focused        func() bool // Window focus source, for hiding the cursor.
+	window_closing        func() bool // Window close request source.
+
+	cursor_blink_pause time.Duration // Cursor blink pause after activity.
+	last_activity      time.Time     // Time of the last user input.
+
+	blink_text_ms     int64       // Text blink _cycle_ duration in ms.
+	blink_duty        float64     // Fraction of blink cycles that are 'on'.
+	blink_fade        bool        // Blinks fade, rather than being hidden.
+	blink_mode        BlinkMode   // Text blink mode.
+	blink_disabled    bool        // Text and cursor are drawn steady.
+	blink_alert_color tcell.Color // Text blink alert color, for BlinkModeColorSwap.
+
+	clock        func() time.Time // Time source for blinking.
+	text_blink   blinkState       // Text blink phase.
+	cursor_blink blinkState       // Cursor blink phase.
+
+	magnifier magnifier // Cursor magnifier
+
+	clear_color color.Color // Color the grid is cleared to before drawing, if any.
+
+	cell_cache_entries int // Maximum pre-composited cell images, if cached.
 
-	blink_text_ms int64 // Text blink _cycle_ duration in ms.
+	focused       bool
+	hovering      bool        // Mouse is over the grid.
+	hover         image.Point // Cell under the mouse, if hovering.
+	mouse_flags   tcell.MouseFlags
+	wheel_as_keys bool // Vertical mouse wheel is posted as arrow keys.
+	enable_focus  bool
+	enable_paste  bool
+	enter_key     EnterKeyMode // How the Enter key is posted.
+	no_escape     bool         // The Escape key is not posted.
+	focus_model   FocusModel   // How games gain keyboard focus.
+	focus_game    *ETCellGame  // Game with keyboard focus, for FocusModelClick.
 
-	cell_image *ebiten.Image // All-white image of a single cell
+	kbd_mouse          bool                   // Keyboard mouse emulation is enabled.
+	kbd_mouse_bindings []KeyboardMouseBinding // Keyboard mouse emulation key bindings.
+	kbd_mouse_point    image.Point            // Virtual mouse cell, in screen coordinates.
 
-	focused      bool
-	mouse_flags  tcell.MouseFlags
-	enable_focus bool
-	enable_paste bool
+	wheel_multiplier float64 // Vertical wheel events per unit of wheel movement, if accelerated.
+	wheel_pending    float64 // Accumulated vertical wheel movement, not yet posted.
+
+	reveal_duration time.Duration // Duration of the reveal animation of changed cells, if any.
+
+	invalidate_glyphs bool // Cached glyphs are to be dropped on the next Draw().
+
+	redraw_on_event bool   // Frames are only redrawn when changed.
+	draw_serial     uint64 // Incremented whenever cells are resolved or forgotten.
+
+	invalidated       bool // Unsynced cells are to be resolved on the next Draw().
+	rasterize_on_draw bool // Show() and Sync() always defer to the next Draw().
+
+	ansi ansiState // WriteANSI terminal state.
 
 	event_channel chan tcell.Event
+	resize_event  *tcell.EventResize // Coalesced resize event in the event channel, if any.
 
 	rune_fallback map[rune]string
 
-	suspended   bool  // Input/output is suspended.
-	close_error error // Closing error. ebiten.ErrTermination is used for clean shutdown.
+	suspended     bool   // Input/output is suspended.
+	suspend_input func() // Called by Update() while suspended, if set.
+	close_error   error  // Closing error. ebiten.ErrTermination is used for clean shutdown.
 }
 
 // Validate interface compliance
@@ -71,21 +239,42 @@ var _ tcell.Screen = (*ETCellScreen)(nil)
 
 // Init initializes the screen for use.
 func (et *ETCellScreen) Init() (err error) {
+	et.grid_lock.Lock()
+	was_init := et.event_channel != nil
 	et.event_channel = make(chan tcell.Event, 128)
+	et.resize_event = nil
+	et.grid_lock.Unlock()
 
 	et.Clear()
 
+	if !was_init && et.on_init != nil {
+		et.on_init()
+	}
+
 	return
 }
 
 // Fini finalizes the screen also releasing resources.
 func (et *ETCellScreen) Fini() {
+	et.grid_lock.Lock()
+	if et.event_channel == nil {
+		et.grid_lock.Unlock()
+		return
+	}
+
 	close(et.event_channel)
 	et.event_channel = nil
+	et.resize_event = nil
+	et.grid_lock.Unlock()
+
+	if et.on_fini != nil {
+		et.on_fini()
+	}
 }
 
 // Clear logically erases the screen.
-// This is effectively a short-cut for Fill(' ', StyleDefault).
+// This is effectively a short-cut for Fill(' ', StyleDefault), where
+// StyleDefault is drawn in the style set by SetStyle, or SetDefaultStyle.
 func (et *ETCellScreen) Clear() {
 	et.Fill(' ', tcell.StyleDefault)
 }
@@ -95,10 +284,11 @@ func (et *ETCellScreen) Clear() {
 // is called (or Sync).
 func (et *ETCellScreen) Fill(r rune, style tcell.Style) {
 	for n := 0; n < len(et.grid); n++ {
-		et.grid[n] = cell{
-			Style: style,
-			Rune:  r,
-		}
+		et.grid[n].set(r, nil, style)
+	}
+
+	if mirror := et.mirrored(); mirror != nil {
+		mirror.Fill(r, style)
 	}
 }
 
@@ -122,10 +312,10 @@ func (et *ETCellScreen) GetContent(x, y int) (primary rune, combining []rune, st
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
 
-	if x >= et.grid_size.X {
+	if x < 0 || x >= et.grid_size.X {
 		return
 	}
-	if y >= et.grid_size.Y {
+	if y < 0 || y >= et.bufferRows() {
 		return
 	}
 
@@ -135,7 +325,34 @@ func (et *ETCellScreen) GetContent(x, y int) (primary rune, combining []rune, st
 	primary = cell.Rune
 	combining = cell.Combining
 	style = cell.Style
-	width = 1
+	width = max(et.runeWidth(primary), 1)
+
+	return
+}
+
+// VisibleRunes returns the runes of the cells on the screen, the visible
+// rows of the grid buffer, with the number of times each is used, for
+// debugging which characters are drawn, such as for font coverage or
+// fallbacks. Combining runes are counted as well as primary runes, and
+// empty cells are not counted. As with GetContent, these are the logical
+// contents, which are displayed once Show() or Sync() is called.
+func (et *ETCellScreen) VisibleRunes() (runes map[rune]int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	runes = map[rune]int{}
+
+	start := et.scroll_offset * et.grid_size.X
+	end := min(start+et.grid_size.X*et.grid_size.Y, len(et.grid))
+	for n := start; n < end; n++ {
+		cell := &et.grid[n]
+		if cell.Rune != 0 {
+			runes[cell.Rune]++
+		}
+		for _, r := range cell.Combining {
+			runes[r]++
+		}
+	}
 
 	return
 }
@@ -154,30 +371,264 @@ func (et *ETCellScreen) GetContent(x, y int) (primary rune, combining []rune, st
 // undefined effects.  Wide runes that are printed in the
 // last column will be replaced with a single width space on output.
 func (et *ETCellScreen) SetContent(x int, y int, primary rune, combining []rune, style tcell.Style) {
+	et.grid_lock.Lock()
+	mirror := et.mirror
+	et.setContent(x, y, primary, combining, style)
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		mirror.SetContent(x, y, primary, combining, style)
+	}
+}
+
+// BufferCell is the content of a cell, for SetBuffer.
+type BufferCell struct {
+	Rune      rune
+	Combining []rune
+	Style     tcell.Style
+}
+
+// SetBuffer sets the contents of the cells from a buffer of rows of cells,
+// starting from the top left, with a single lock of the screen. This is
+// much faster than SetContent for each cell. Cells of the buffer beyond
+// the grid are ignored, and cells of the grid beyond the buffer are not
+// changed. The results are not displayed until Show() or Sync() is called.
+func (et *ETCellScreen) SetBuffer(cells [][]BufferCell) {
+	et.grid_lock.Lock()
+	mirror := et.mirror
+	for y, row := range cells {
+		for x := range row {
+			et.setContent(x, y, row[x].Rune, row[x].Combining, row[x].Style)
+		}
+	}
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		for y, row := range cells {
+			for x := range row {
+				mirror.SetContent(x, y, row[x].Rune, row[x].Combining, row[x].Style)
+			}
+		}
+	}
+}
+
+// CellBuffer returns a snapshot of the visible cells of the screen, as a
+// tcell CellBuffer of the screen size, for use with tcell's views and
+// other CellBuffer based code. Each cell of the buffer has:
+//   - The primary rune, combining runes and style set with SetContent.
+//     Cells that were never set have a primary rune of 0, which tcell
+//     reports as a space.
+//   - The width tcell computes for the primary rune.
+//   - A dirty flag, true if the cell was changed since it was last made
+//     visible by Show() or Sync(), or by the Draw() after Invalidate().
+//
+// Changes to the buffer do not affect the screen.
+func (et *ETCellScreen) CellBuffer() (cb *tcell.CellBuffer) {
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
 
-	if x >= et.grid_size.X {
+	cb = &tcell.CellBuffer{}
+	cb.Resize(et.grid_size.X, et.grid_size.Y)
+
+	for y := 0; y < et.grid_size.Y; y++ {
+		for x := 0; x < et.grid_size.X; x++ {
+			c := &et.grid[(y+et.scroll_offset)*et.grid_size.X+x]
+			cb.SetContent(x, y, c.Rune, c.Combining, c.Style)
+			cb.SetDirty(x, y, !c.synced)
+		}
+	}
+
+	return
+}
+
+// DefaultRuneWidth returns the width of a rune, in cells, as the screen
+// measures it by default: 2 for wide runes, such as CJK and emoji, 0 for
+// combining marks and control runes, and 1 otherwise. East Asian
+// ambiguous width runes are narrow. See ETCell.SetRuneWidthFunc.
+func DefaultRuneWidth(r rune) int {
+	return min(uniseg.StringWidth(string(r)), 2)
+}
+
+// cursorWidth returns the width of the cursor, in cells, which is the
+// width of the rune under it, so that it spans both cells of a wide rune.
+// The grid lock must be held.
+func (et *ETCellScreen) cursorWidth() int {
+	cursor := et.cursor
+	if cursor.X < 0 || cursor.X >= et.grid_size.X || cursor.Y < 0 {
+		return 1
+	}
+
+	n := cursor.Y*et.grid_size.X + cursor.X
+	if n >= len(et.grid) {
+		return 1
+	}
+
+	return max(1, min(et.runeWidth(et.grid[n].Rune), et.grid_size.X-cursor.X))
+}
+
+// runeWidth returns the width of a rune, in cells, between 0 and 2.
+// The grid lock must be held.
+func (et *ETCellScreen) runeWidth(r rune) int {
+	if et.rune_width == nil {
+		return DefaultRuneWidth(r)
+	}
+
+	return max(0, min(et.rune_width(r), 2))
+}
+
+// CursorAdvance returns the cursor position after printing s from x, y,
+// as Print does. Wide runes, such as CJK and emoji, advance two cells, and
+// combining marks none. Text wraps at the grid width, so that text ending
+// at the right edge leaves the cursor at the start of the next row, and
+// wide runes that do not fit at the end of a row are moved to the next.
+// Newlines move to the start of the next row. Rows past the bottom are
+// returned as is.
+func (et *ETCellScreen) CursorAdvance(x, y int, s string) (nx, ny int) {
+	et.grid_lock.Lock()
+	width := et.grid_size.X
+	rune_width := et.rune_width
+	et.grid_lock.Unlock()
+
+	return layoutText(x, y, width, s, rune_width, nil)
+}
+
+// Print sets the contents of the cells from x, y to the text of s, in the
+// given style, and returns the cursor position after it, as CursorAdvance.
+// The cell after each wide rune is set to a space. The results are not
+// displayed until Show() or Sync() is called.
+func (et *ETCellScreen) Print(x, y int, s string, style tcell.Style) (nx, ny int) {
+	type content struct {
+		x, y    int
+		primary rune
+		runes   []rune
+	}
+	var contents []content
+
+	et.grid_lock.Lock()
+	mirror := et.mirror
+	nx, ny = layoutText(x, y, et.grid_size.X, s, et.rune_width, func(x, y int, cluster []rune, width int) {
+		contents = append(contents, content{x, y, cluster[0], cluster[1:]})
+		if width > 1 {
+			contents = append(contents, content{x + 1, y, ' ', nil})
+		}
+	})
+	for _, c := range contents {
+		et.setContent(c.x, c.y, c.primary, c.runes, style)
+	}
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		for _, c := range contents {
+			mirror.SetContent(c.x, c.y, c.primary, c.runes, style)
+		}
+	}
+
+	return
+}
+
+// layoutText lays out the grapheme clusters of s from x, y in rows of
+// width cells, calling place, if not nil, for each, and returns the cursor
+// position after the text. See CursorAdvance. Clusters are measured by
+// rune_width of their first rune, or by uniseg if nil.
+func layoutText(x, y, width int, s string, rune_width func(rune) int, place func(x, y int, cluster []rune, width int)) (nx, ny int) {
+	state := -1
+	for len(s) > 0 {
+		var cluster string
+		var cells int
+		cluster, s, cells, state = uniseg.FirstGraphemeClusterInString(s, state)
+
+		if cluster == "\n" || cluster == "\r\n" {
+			x, y = 0, y+1
+			continue
+		}
+		if rune_width != nil {
+			cells = rune_width([]rune(cluster)[0])
+		}
+		if cells <= 0 {
+			continue
+		}
+		cells = min(cells, 2)
+
+		if width > 0 && x+cells > width {
+			x, y = 0, y+1
+		}
+		if place != nil {
+			place(x, y, []rune(cluster), cells)
+		}
+		x += cells
+		if width > 0 && x >= width {
+			x, y = 0, y+1
+		}
+	}
+
+	return x, y
+}
+
+// setContent sets the contents of the given cell location.
+// The grid lock must be held.
+func (et *ETCellScreen) setContent(x int, y int, primary rune, combining []rune, style tcell.Style) {
+	if x < 0 || x >= et.grid_size.X {
 		return
 	}
-	if y >= et.grid_size.Y {
+	if y < 0 || y >= et.bufferRows() {
 		return
 	}
 
 	n := y*et.grid_size.X + x
 
-	et.grid[n] = cell{
-		Rune:      primary,
-		Combining: combining,
-		Style:     style,
+	et.grid[n].set(primary, combining, style)
+
+	if et.box_connect {
+		et.unsyncNeighbors(x, y)
 	}
 }
 
+// set sets the content of a cell, to be resolved by the next show().
+// The previously resolved glyphs and colors are kept, so that they can
+// be reused if the cell is set back to the content last shown.
+func (c *cell) set(primary rune, combining []rune, style tcell.Style) {
+	c.Rune = primary
+	c.Combining = combining
+	c.Style = style
+	c.synced = false
+}
+
+// isShown returns true if the cell's glyphs and colors were resolved for
+// its current content, with the style given.
+func (c *cell) isShown(style tcell.Style) bool {
+	return c.shown && c.shown_rune == c.Rune && c.shown_style == style && slices.Equal(c.shown_combining, c.Combining)
+}
+
+// isBlank returns true if the cell has no content, other than a
+// space in the default style.
+func (c *cell) isBlank() bool {
+	return (c.Rune == 0 || c.Rune == ' ') && len(c.Combining) == 0 && c.Style == tcell.StyleDefault
+}
+
 // SetStyle sets the default style to use when clearing the screen
 // or when StyleDefault is specified.  If it is also StyleDefault,
 // then whatever system/terminal default is relevant will be used.
 func (et *ETCellScreen) SetStyle(style tcell.Style) {
+	et.grid_lock.Lock()
+	et.setStyle(style)
+	et.grid_lock.Unlock()
+
+	if mirror := et.mirrored(); mirror != nil {
+		mirror.SetStyle(style)
+	}
+}
+
+// setStyle sets the default style. Cells in StyleDefault, such as
+// those cleared, are drawn in the new style on the next Show.
+// The grid lock must be held.
+func (et *ETCellScreen) setStyle(style tcell.Style) {
 	et.style_default = style
+
+	for n := 0; n < len(et.grid); n++ {
+		if et.grid[n].Style == tcell.StyleDefault {
+			et.grid[n].synced = false
+		}
+	}
 }
 
 // ShowCursor is used to display the cursor at a given location.
@@ -185,6 +636,10 @@ func (et *ETCellScreen) SetStyle(style tcell.Style) {
 // dimensions of the screen, the cursor will be hidden.
 func (et *ETCellScreen) ShowCursor(x int, y int) {
 	et.cursor = image.Point{X: x, Y: y}
+
+	if mirror := et.mirrored(); mirror != nil {
+		mirror.ShowCursor(x, y)
+	}
 }
 
 // HideCursor is used to hide the cursor.  It's an alias for
@@ -193,11 +648,102 @@ func (et *ETCellScreen) HideCursor() {
 	et.ShowCursor(-1, -1)
 }
 
+// SetCursorVisible sets whether the cursor is drawn, independently of its
+// position, so that it can be hidden temporarily, such as while a modal
+// dialog is open, and shown again where it was. The cursor is only drawn
+// if it is both visible and, set by ShowCursor(), on the screen. The
+// cursor is visible by default.
+func (et *ETCellScreen) SetCursorVisible(visible bool) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.cursor_hidden = !visible
+}
+
+// CursorVisible returns true if the cursor is visible, as set by
+// SetCursorVisible(), whether or not it is on the screen.
+func (et *ETCellScreen) CursorVisible() bool {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return !et.cursor_hidden
+}
+
 // SetCursorStyle is used to set the cursor style.  If the style
 // is not supported (or cursor styles are not supported at all),
 // then this will have no effect.
 func (et *ETCellScreen) SetCursorStyle(cs tcell.CursorStyle) {
 	et.cursor_style = cs
+
+	if mirror := et.mirrored(); mirror != nil {
+		mirror.SetCursorStyle(cs)
+	}
+}
+
+// SetBufferRows sets the number of rows in the grid buffer. If this is
+// more than the rows of the screen, then SetContent() and GetContent()
+// address the entire buffer, and SetScrollOffset() selects which rows are
+// visible. Size() reports the visible screen size. The content of the
+// rows common to the old and new buffers is kept.
+func (et *ETCellScreen) SetBufferRows(rows int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if rows == et.buffer_rows {
+		return
+	}
+
+	et.buffer_rows = rows
+	et.resizeGrid()
+}
+
+// BufferRows returns the number of rows in the grid buffer, which is
+// never less than the rows of the screen.
+func (et *ETCellScreen) BufferRows() (rows int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return et.bufferRows()
+}
+
+// SetScrollOffset sets the first row of the grid buffer that is visible
+// on the screen. The offset is clamped so that the screen is always
+// entirely within the buffer, ie between 0 and BufferRows() minus the
+// screen rows.
+func (et *ETCellScreen) SetScrollOffset(row int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	et.scroll_offset = row
+	et.clampScrollOffset()
+}
+
+// ScrollOffset returns the first row of the grid buffer that is visible.
+func (et *ETCellScreen) ScrollOffset() (row int) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return et.scroll_offset
+}
+
+// bufferRows returns the number of rows in the grid buffer.
+func (et *ETCellScreen) bufferRows() int {
+	return max(et.grid_size.Y, et.buffer_rows)
+}
+
+// resizeGrid reallocates the grid buffer for the current buffer rows,
+// keeping the content of rows common to both.
+func (et *ETCellScreen) resizeGrid() {
+	grid := make([]cell, et.grid_size.X*et.bufferRows())
+	copy(grid, et.grid)
+	et.grid = grid
+
+	et.clampScrollOffset()
+}
+
+// clampScrollOffset clamps the scroll offset to the grid buffer.
+func (et *ETCellScreen) clampScrollOffset() {
+	et.scroll_offset = max(0, min(et.scroll_offset, et.bufferRows()-et.grid_size.Y))
 }
 
 // Size returns the screen size as width, height.  This changes in
@@ -222,11 +768,12 @@ func (et *ETCellScreen) Size() (width, height int) {
 //
 // NOTE: PollEvent should not be called while this method is running.
 func (et *ETCellScreen) ChannelEvents(ch chan<- tcell.Event, quit <-chan struct{}) {
+	events := et.events()
 	go func() {
 		for {
 			select {
-			case ev := <-et.event_channel:
-				ch <- ev
+			case ev := <-events:
+				ch <- et.received(ev)
 			case <-quit:
 				close(ch)
 				return
@@ -239,17 +786,44 @@ func (et *ETCellScreen) ChannelEvents(ch chan<- tcell.Event, quit <-chan struct{
 // must spin on this to prevent the application from stalling.
 // Furthermore, this will return nil if the Screen is finalized.
 func (et *ETCellScreen) PollEvent() (ev tcell.Event) {
-	ev = <-et.event_channel
+	ev = et.received(<-et.events())
 	return ev
 }
 
+// PollEventTimeout waits for events to arrive, as PollEvent does, but for
+// at most a duration, so that applications can do periodic work, such as
+// updating clocks or animations, between events. It returns nil if no
+// event arrived in time, or if the Screen is finalized.
+func (et *ETCellScreen) PollEventTimeout(d time.Duration) (ev tcell.Event) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case ev = <-et.events():
+		ev = et.received(ev)
+	case <-timer.C:
+	}
+
+	return
+}
+
 // HasPendingEvent returns true if PollEvent would return an event
 // without blocking.  If the screen is stopped and PollEvent would
 // return nil, then the return value from this function is unspecified.
 // The purpose of this function is to allow multiple events to be collected
 // at once, to minimize screen redraws.
 func (et *ETCellScreen) HasPendingEvent() (has bool) {
-	return len(et.event_channel) != 0
+	return len(et.events()) != 0
+}
+
+// events returns the event channel, which is nil before Init() and after
+// Fini(). The channel is replaced by Init() and Fini() under the grid
+// lock, so that it can be read from any goroutine.
+func (et *ETCellScreen) events() (events chan tcell.Event) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return et.event_channel
 }
 
 // PostEvent tries to post an event into the event stream.  This
@@ -264,6 +838,13 @@ func (et *ETCellScreen) PostEvent(ev tcell.Event) (err error) {
 	return
 }
 
+// PostError posts a *tcell.EventError wrapping err into the event stream.
+// This allows the application to observe recoverable errors without
+// terminating the game, as Exit() would.
+func (et *ETCellScreen) PostError(err error) error {
+	return et.PostEvent(tcell.NewEventError(err))
+}
+
 // Deprecated: PostEventWait is unsafe, and will be removed
 // in the future.
 //
@@ -276,7 +857,22 @@ func (et *ETCellScreen) PostEvent(ev tcell.Event) (err error) {
 // For this reason, when using this function, the use of a
 // Goroutine is recommended to ensure no deadlock can occur.
 func (et *ETCellScreen) PostEventWait(ev tcell.Event) {
-	et.PostEvent(ev)
+	for {
+		et.grid_lock.Lock()
+		if et.event_channel == nil || !et.wantsEvent(ev) {
+			et.grid_lock.Unlock()
+			return
+		}
+		select {
+		case et.event_channel <- ev:
+			et.grid_lock.Unlock()
+			return
+		default:
+		}
+		et.grid_lock.Unlock()
+
+		time.Sleep(time.Millisecond)
+	}
 }
 
 // EnableMouse enables the mouse.  (If your terminal supports it.)
@@ -293,6 +889,11 @@ func (et *ETCellScreen) DisableMouse() {
 	et.mouse_flags = 0
 }
 
+// MouseFlags returns the currently enabled mouse flags.
+func (et *ETCellScreen) MouseFlags() (flags tcell.MouseFlags) {
+	return et.mouse_flags
+}
+
 // EnablePaste enables bracketed paste mode, if supported.
 func (et *ETCellScreen) EnablePaste() {
 	et.enable_paste = true
@@ -303,6 +904,11 @@ func (et *ETCellScreen) DisablePaste() {
 	et.enable_paste = false
 }
 
+// PasteEnabled returns true if bracketed paste mode is enabled.
+func (et *ETCellScreen) PasteEnabled() (enabled bool) {
+	return et.enable_paste
+}
+
 // EnableFocus enables reporting of focus events, if your terminal supports it.
 func (et *ETCellScreen) EnableFocus() {
 	et.enable_focus = true
@@ -313,6 +919,11 @@ func (et *ETCellScreen) DisableFocus() {
 	et.enable_focus = false
 }
 
+// FocusEnabled returns true if reporting of focus events is enabled.
+func (et *ETCellScreen) FocusEnabled() (enabled bool) {
+	return et.enable_focus
+}
+
 // HasMouse returns true if the terminal (apparently) supports a
 // mouse.  Note that the return value of true doesn't guarantee that
 // a mouse/pointing device is present; a false return definitely
@@ -335,6 +946,118 @@ func e_color_of(c tcell.Color) color.RGBA {
 	return color.RGBA{uint8(r), uint8(g), uint8(b), 255}
 }
 
+// withoutAttrs returns a style with some attributes cleared.
+func withoutAttrs(style tcell.Style, attrs tcell.AttrMask) tcell.Style {
+	if attrs == 0 {
+		return style
+	}
+
+	_, _, attr := style.Decompose()
+	return style.Attributes(attr &^ attrs)
+}
+
+// ResolveStyle resolves a style to the foreground and background colors,
+// and attributes, that the screen draws it with. If style is
+// tcell.StyleDefault, the defaults style is used in its place.
+//
+// Default colors are white on black, tcell.AttrReverse swaps the colors,
+// tcell.AttrBold intensifies the foreground, and tcell.AttrDim dims it.
+// A style with tcell.AttrInvalid has all attributes ignored.
+func ResolveStyle(style, defaults tcell.Style) (fg, bg color.RGBA, attr tcell.AttrMask) {
+	if style == tcell.StyleDefault {
+		style = defaults
+	}
+
+	t_fg, t_bg, attr := style.Decompose()
+
+	if (attr & tcell.AttrInvalid) != 0 {
+		// Ignore all attributes.
+		attr = tcell.AttrNone
+	}
+
+	if t_fg == tcell.ColorDefault {
+		t_fg = tcell.ColorWhite
+	}
+
+	if t_bg == tcell.ColorDefault {
+		t_bg = tcell.ColorBlack
+	}
+
+	// Reverse fg & bg if asked to.
+	if (attr & tcell.AttrReverse) != 0 {
+		t_fg, t_bg = t_bg, t_fg
+	}
+
+	// For Bold, intensify the color.
+	if (attr & tcell.AttrBold) != 0 {
+		r, g, b := t_fg.TrueColor().RGB()
+		t_fg = tcell.NewRGBColor(
+			min(255, int32(float32(r)*2)),
+			min(255, int32(float32(g)*2)),
+			min(255, int32(float32(b)*2)),
+		)
+	}
+
+	// For Dim, de-intensify the color.
+	if (attr & tcell.AttrDim) != 0 {
+		r, g, b := t_fg.TrueColor().RGB()
+		t_fg = tcell.NewRGBColor(
+			min(255, int32(float32(r)/2)),
+			min(255, int32(float32(g)/2)),
+			min(255, int32(float32(b)/2)),
+		)
+	}
+
+	fg = e_color_of(t_fg)
+	bg = e_color_of(t_bg)
+
+	return
+}
+
+// resolveStyle resolves a style as ResolveStyle does, then applies the
+// color filter, if any, to the colors. The grid lock must be held.
+func (et *ETCellScreen) resolveStyle(style, defaults tcell.Style) (fg, bg color.RGBA, attr tcell.AttrMask) {
+	fg, bg, attr = ResolveStyle(style, defaults)
+
+	if et.color_filter != nil {
+		fg = et.color_filter(fg)
+		bg = et.color_filter(bg)
+	}
+
+	return
+}
+
+// urlOf returns the OSC 8 hyperlink URL of a style.
+// tcell does not export an accessor for it, so reflection is used.
+func urlOf(style tcell.Style) string {
+	return styleString(style, "url")
+}
+
+// styleString returns an unexported string field of a style, or "" if
+// tcell no longer has the field, rather than the text of an invalid value.
+func styleString(style tcell.Style, name string) string {
+	field := reflect.ValueOf(style).FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+
+	return field.String()
+}
+
+// linkAt returns the hyperlink URL of the visible cell at x, y.
+func (et *ETCellScreen) linkAt(x, y int) (url string) {
+	if x < 0 || x >= et.grid_size.X || y < 0 || y >= et.grid_size.Y {
+		return
+	}
+
+	cell := &et.grid[(y+et.scroll_offset)*et.grid_size.X+x]
+	if cell.synced {
+		url = cell.url
+	}
+
+	return
+}
+
 // Show makes all the content changes made using SetContent() visible
 // on the display.
 //
@@ -342,106 +1065,307 @@ func e_color_of(c tcell.Color) color.RGBA {
 // manner possible.
 func (et *ETCellScreen) Show() {
 	et.grid_lock.Lock()
-	defer et.grid_lock.Unlock()
+	mirror := et.mirror
+	if et.rasterize_on_draw {
+		et.invalidated = true
+	} else {
+		et.show()
+	}
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		mirror.Show()
+	}
+}
+
+// Invalidate makes all the content changes made using SetContent() visible
+// on the display, like Show(), but defers resolving the glyphs of the
+// changed cells to the next Draw() on the render thread. This keeps glyph
+// rasterization off of the application's goroutine.
+func (et *ETCellScreen) Invalidate() {
+	et.grid_lock.Lock()
+	mirror := et.mirror
+	et.invalidated = true
+	et.grid_lock.Unlock()
+
+	if mirror != nil {
+		mirror.Show()
+	}
+}
+
+// show resolves the styles and glyphs of all unsynced cells.
+// The grid lock must be held.
+func (et *ETCellScreen) show() {
+	defer traceSince(et.trace, TraceShow, traceStart(et.trace))
+
+	et.invalidated = false
+	et.draw_serial++
+
+	var now time.Time
+	if et.reveal_duration > 0 {
+		now = et.clock()
+	}
 
 	pt := image.Point{}
 	n := 0
 	pt.Y = 0
-	for y := 0; y < et.grid_size.Y; y++ {
+	for y := 0; y < et.bufferRows(); y++ {
 		pt.Y = y
 		pt.X = 0
+		row_changed := false
 		for x := 0; x < et.grid_size.X; x++ {
 			pt.X = x
 			cell := &et.grid[n]
 			n++
 
+			if cell.synced {
+				continue
+			}
+			row_changed = true
+
 			style := cell.Style
 			if style == tcell.StyleDefault {
 				style = et.style_default
 			}
-			fg, bg, attr := style.Decompose()
-
-			if cell.synced {
+			style = withoutAttrs(style, et.attr_disabled)
+
+			// Reuse the resolved glyphs and colors if the content is
+			// back to what was last shown, ie after a Clear() and redraw.
+			// Connected box drawing depends on the neighbors, so is not reused.
+			_, is_box := box_arms[cell.Rune]
+			if cell.isShown(style) && cell.point == pt && !(is_box && et.box_connect) {
+				cell.synced = true
 				continue
 			}
 
-			if (attr & tcell.AttrInvalid) != 0 {
-				// Ignore all attributes.
-				attr = tcell.AttrNone
-			}
+			var attr tcell.AttrMask
+			cell.point = pt
+			cell.fgColor, cell.bgColor, attr = et.resolveStyle(style, withoutAttrs(et.style_default, et.attr_disabled))
+			cell.attr = attr
+			cell.url = urlOf(style)
 
-			if fg == tcell.ColorDefault {
-				fg = tcell.ColorWhite
-			}
+			font_style := fontStyleOf(attr)
 
-			if bg == tcell.ColorDefault {
-				bg = tcell.ColorBlack
-			}
+			runes := append([]rune{cell.Rune}, cell.Combining...)
 
-			// Reverse fg & bg if asked to.
-			if (attr & tcell.AttrReverse) != 0 {
-				fg, bg = bg, fg
+			// Does the glyph provider have this rune?
+			var provided bool
+			cell.color_glyph = false
+			if et.glyph_provider != nil {
+				cell.glyph, provided = et.glyph_provider(runes[0], font_style, cell.fgColor, cell.bgColor)
+				cell.color_glyph = provided && et.glyph_provider_colored
 			}
 
-			// For Bold, intensify the color.
-			if (attr & tcell.AttrBold) != 0 {
-				r, g, b := fg.TrueColor().RGB()
-				fg = tcell.NewRGBColor(
-					min(255, int32(float32(r)*2)),
-					min(255, int32(float32(g)*2)),
-					min(255, int32(float32(b)*2)),
-				)
+			// Is this a synthetic box drawing rune?
+			if !provided && et.box_drawing {
+				var arms boxArms
+				arms, provided = et.boxArmsAt(x, y)
+				if provided {
+					cell.glyph = et.boxGlyph(arms)
+				}
 			}
 
-			// For Dim, de-intensify the color.
-			if (attr & tcell.AttrDim) != 0 {
-				r, g, b := fg.TrueColor().RGB()
-				fg = tcell.NewRGBColor(
-					min(255, int32(float32(r)/2)),
-					min(255, int32(float32(g)/2)),
-					min(255, int32(float32(b)/2)),
-				)
-			}
+			cell.face_glyph = !provided
+			if !provided {
+				// Is this a rune that can be displayed?
+				if !et.canDisplay(runes[0], false) {
+					cell.face_glyph = false
+					str, ok := et.rune_fallback[cell.Rune]
+					if !ok {
+						runes[0] = ' '
+					} else {
+						runes = []rune(str)
+					}
+				}
 
-			cell.point = pt
-			cell.bgColor = e_color_of(bg)
-			cell.fgColor = e_color_of(fg)
+				cell.glyph, _ = et.faceGlyph(runes[0], font_style)
 
-			// Is this a rune that can be displayed?
-			runes := append([]rune{cell.Rune}, cell.Combining...)
-			if !et.CanDisplay(runes[0], false) {
-				str, ok := et.rune_fallback[cell.Rune]
-				if !ok {
-					runes[0] = ' '
-				} else {
-					runes = []rune(str)
+				// Color glyphs are not shaped, as shaping draws outlines.
+				if font.IsColorGlyph(et.face, runes[0], font_style) {
+					cell.face_glyph = false
+					cell.color_glyph = true
 				}
 			}
 
-			font_style := font.FontStyleNormal
-			if (attr & (tcell.AttrItalic | tcell.AttrBold)) == (tcell.AttrItalic | tcell.AttrBold) {
-				font_style = font.FontStyleBoldItalic
-			} else if (attr & tcell.AttrItalic) != 0 {
-				font_style = font.FontStyleItalic
-			} else if (attr & tcell.AttrBold) != 0 {
-				font_style = font.FontStyleBold
-			}
-
-			cell.glyph, _ = et.face.Glyph(runes[0], font_style)
-
 			if len(runes) > 1 {
-				// Draw the combining runes
-				cell.combining = make([](*ebiten.Image), len(runes[1:]))
-				for n, char := range runes[1:] {
-					glyph, _ := et.face.Glyph(char, font_style)
-					cell.combining[n] = glyph
+				// Synthetic enclosures are not in the font.
+				for _, r := range runes[1:] {
+					if _, ok := enclosures[r]; ok {
+						cell.face_glyph = false
+					}
 				}
-			} else {
-				cell.combining = nil
+
+				// Composite the combining runes into a single glyph.
+				key := clusterKey{
+					glyph: cell.glyph,
+					runes: string(runes),
+					style: font_style,
+				}
+				cell.glyph = et.clusterGlyph(key)
 			}
 
+			cell.revealed = now
+
+			cell.shown = true
+			cell.shown_rune = cell.Rune
+			cell.shown_combining = cell.Combining
+			cell.shown_style = style
+
 			cell.synced = true
 		}
+
+		if et.line_shaping && row_changed {
+			et.shapeRow(y)
+		}
+	}
+}
+
+// shapeRow replaces the font glyphs of a row of the grid buffer with those
+// of its runs of cells in the same font style, shaped as single lines.
+// The grid lock must be held.
+func (et *ETCellScreen) shapeRow(y int) {
+	row := et.grid[y*et.grid_size.X : (y+1)*et.grid_size.X]
+
+	for start := 0; start < len(row); {
+		if !row[start].face_glyph {
+			start++
+			continue
+		}
+
+		style := fontStyleOf(row[start].attr)
+		end := start + 1
+		for end < len(row) && row[end].face_glyph && fontStyleOf(row[end].attr) == style {
+			end++
+		}
+
+		cells := make([]string, end-start)
+		for n := range cells {
+			c := &row[start+n]
+			r := c.Rune
+			if r == 0 {
+				r = ' '
+			}
+			cells[n] = string(append([]rune{r}, c.Combining...))
+		}
+
+		glyphs, ok := et.shapedGlyphs(cells, style)
+		if ok {
+			for n, glyph := range glyphs {
+				row[start+n].glyph = glyph
+			}
+		}
+
+		start = end
+	}
+}
+
+// shaped_cache_runs is the most shaped runs of cells that are cached.
+const shaped_cache_runs = 1024
+
+// shapedKey identifies a shaped run of cells.
+type shapedKey struct {
+	cells string // Text of the cells, each ending with a NUL.
+	style font.FontStyle
+}
+
+// shapedGlyphs returns the glyphs of a run of cells shaped as a single
+// line, cached so that unchanged runs keep their glyphs, and so their
+// entries in the cell cache.
+// The grid lock must be held.
+func (et *ETCellScreen) shapedGlyphs(cells []string, style font.FontStyle) (glyphs [](*ebiten.Image), ok bool) {
+	var text strings.Builder
+	for _, cell := range cells {
+		text.WriteString(cell)
+		text.WriteByte(0)
+	}
+	key := shapedKey{cells: text.String(), style: style}
+
+	glyphs, ok = et.shaped_cache.get(key)
+	if ok {
+		return
+	}
+
+	glyphs, ok = font.ShapeCells(et.face, cells, style)
+	if !ok {
+		return
+	}
+
+	// The least recently used runs are dropped once there are many;
+	// their glyphs are released once no cell uses them.
+	if et.shaped_cache == nil {
+		et.shaped_cache = newLRUCache[shapedKey](shaped_cache_runs, et.releaseRun)
+	}
+	et.shaped_cache.put(key, glyphs)
+
+	return
+}
+
+// fontStyleOf returns the font style of drawn attributes.
+func fontStyleOf(attr tcell.AttrMask) (font_style font.FontStyle) {
+	switch {
+	case (attr & (tcell.AttrItalic | tcell.AttrBold)) == (tcell.AttrItalic | tcell.AttrBold):
+		font_style = font.FontStyleBoldItalic
+	case (attr & tcell.AttrItalic) != 0:
+		font_style = font.FontStyleItalic
+	case (attr & tcell.AttrBold) != 0:
+		font_style = font.FontStyleBold
+	}
+
+	return
+}
+
+// glyphOf returns the glyph for a rune, from the glyph provider if it has
+// one, or the font face otherwise.
+func (et *ETCellScreen) glyphOf(r rune, style font.FontStyle, fg, bg color.RGBA) (glyph *ebiten.Image) {
+	if et.glyph_provider != nil {
+		var ok bool
+		glyph, ok = et.glyph_provider(r, style, fg, bg)
+		if ok {
+			return
+		}
+	}
+
+	glyph, _ = et.faceGlyph(r, style)
+
+	return
+}
+
+// faceGlyph returns the glyph for a rune from the font face. If the face
+// fails to generate it, the glyph is nil, and so drawn as empty, and the
+// failure is reported to the glyph error callback. The grid lock must be held.
+func (et *ETCellScreen) faceGlyph(r rune, style font.FontStyle) (glyph *ebiten.Image, is_empty bool) {
+	defer func() {
+		failure := recover()
+		if failure == nil {
+			return
+		}
+
+		glyph, is_empty = nil, true
+		if et.on_glyph_error != nil {
+			et.on_glyph_error(r, fmt.Errorf("%w: %q: %v", ErrGlyph, r, failure))
+		}
+	}()
+
+	defer traceSince(et.trace, TraceGlyph, traceStart(et.trace))
+
+	return et.face.Glyph(r, style)
+}
+
+// traceStart returns the start time of a traced operation, or the zero
+// time if there is no trace function, so tracing costs nothing when off.
+func traceStart(trace func(event string, d time.Duration)) (start time.Time) {
+	if trace != nil {
+		start = time.Now()
+	}
+
+	return
+}
+
+// traceSince calls a trace function, if any, with the duration of an
+// operation since its traceStart.
+func traceSince(trace func(event string, d time.Duration), event string, start time.Time) {
+	if trace != nil {
+		trace(event, time.Since(start))
 	}
 }
 
@@ -455,12 +1379,209 @@ func (et *ETCellScreen) Show() {
 // or during a resize event.
 func (et *ETCellScreen) Sync() {
 	et.grid_lock.Lock()
+	mirror := et.mirror
 	for n := 0; n < len(et.grid); n++ {
 		et.grid[n].synced = false
+		et.grid[n].shown = false
+	}
+	if et.rasterize_on_draw {
+		et.invalidated = true
+	} else {
+		et.show()
 	}
 	et.grid_lock.Unlock()
 
-	et.Show()
+	if mirror != nil {
+		mirror.Sync()
+	}
+}
+
+// InvalidateCell marks the cell at x, y of the grid buffer to be redrawn
+// on the next Show(), even if its content has not changed, to correct a
+// cell without the expense of a Sync().
+func (et *ETCellScreen) InvalidateCell(x, y int) {
+	et.InvalidateRect(image.Rect(x, y, x+1, y+1))
+}
+
+// InvalidateRect marks the cells of the grid buffer in a rectangle to be
+// redrawn on the next Show(), even if their content has not changed.
+// Cells of the rectangle outside of the grid buffer are ignored.
+func (et *ETCellScreen) InvalidateRect(r image.Rectangle) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	r = r.Intersect(image.Rect(0, 0, et.grid_size.X, et.bufferRows()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			n := y*et.grid_size.X + x
+			et.grid[n].synced = false
+			et.grid[n].shown = false
+		}
+	}
+}
+
+// forget forgets the resolved glyphs of all cells, so that they are
+// resolved again when next changed, even if back to their shown content.
+// The grid lock must be held.
+func (et *ETCellScreen) forget() {
+	et.draw_serial++
+	for n := 0; n < len(et.grid); n++ {
+		et.grid[n].shown = false
+	}
+
+	et.cluster_cache.clear()
+	et.shaped_cache.clear()
+	et.box_cache = nil
+	et.enclosure_cache = nil
+}
+
+// releaseGlyphs deallocates the released glyphs that no cell uses.
+// The grid lock must be held.
+func (et *ETCellScreen) releaseGlyphs() {
+	if len(et.released) == 0 {
+		return
+	}
+
+	used := make(map[*ebiten.Image]bool)
+	for n := range et.grid {
+		used[et.grid[n].glyph] = true
+	}
+
+	kept := et.released[:0]
+	for _, glyph := range et.released {
+		if used[glyph] {
+			kept = append(kept, glyph)
+		} else {
+			glyph.Deallocate()
+		}
+	}
+	clear(et.released[len(kept):])
+	et.released = kept
+}
+
+// release queues a glyph dropped from a cache to be deallocated.
+func (et *ETCellScreen) release(glyph *ebiten.Image) {
+	if glyph != nil {
+		et.released = append(et.released, glyph)
+	}
+}
+
+// releaseRun queues the glyphs of a shaped run dropped from the cache to
+// be deallocated.
+func (et *ETCellScreen) releaseRun(glyphs [](*ebiten.Image)) {
+	for _, glyph := range glyphs {
+		et.release(glyph)
+	}
+}
+
+// cluster_cache_entries is the most composited glyph clusters cached.
+const cluster_cache_entries = 1024
+
+// clusterKey identifies a composited glyph cluster.
+type clusterKey struct {
+	glyph *ebiten.Image // Primary glyph.
+	runes string
+	style font.FontStyle
+}
+
+// cluster_fg and cluster_bg are the colors the glyph provider is passed for
+// combining runes, as clusters are composited untinted.
+var (
+	cluster_fg = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	cluster_bg = color.RGBA{}
+)
+
+// BlendMax composites images by taking the maximum of each channel.
+// When used for glyphs, the overlapping anti-aliased edges of stacked
+// combining glyphs do not double-darken, as they would with source-over.
+var BlendMax = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorOne,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorOne,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOne,
+	BlendOperationRGB:           ebiten.BlendOperationMax,
+	BlendOperationAlpha:         ebiten.BlendOperationMax,
+}
+
+// clusterGlyph returns a single glyph of the primary glyph of the key
+// composited with the glyphs of its combining runes. Enclosing marks, such
+// as U+20E3 COMBINING ENCLOSING KEYCAP, are drawn at the full cell size over
+// the other glyphs, which are scaled down to fit inside. Clusters are
+// composited untinted, so are tinted when drawn, and the most recently used
+// are cached until the font changes. The grid lock must be held.
+func (et *ETCellScreen) clusterGlyph(key clusterKey) (cluster *ebiten.Image) {
+	cluster, ok := et.cluster_cache.get(key)
+	if ok {
+		return
+	}
+
+	runes := []rune(key.runes)
+	var combining, enclosing [](*ebiten.Image)
+	var enclosed rune
+	for _, char := range runes[1:] {
+		if enclosure := et.enclosureGlyph(char); enclosure != nil {
+			enclosing = append(enclosing, enclosure)
+			if enclosed == 0 {
+				enclosed = char
+			}
+			continue
+		}
+		combining = append(combining, et.glyphOf(char, key.style, cluster_fg, cluster_bg))
+	}
+
+	blend := BlendMax
+	if et.combining_blend != nil {
+		blend = *et.combining_blend
+	}
+
+	cluster = ebiten.NewImage(et.cell_size.X, et.cell_size.Y)
+	for _, image := range append([](*ebiten.Image){key.glyph}, combining...) {
+		if image == nil {
+			continue
+		}
+		var opts ebiten.DrawImageOptions
+		opts.Blend = blend
+		cluster.DrawImage(image, &opts)
+	}
+
+	if len(enclosing) > 0 {
+		// Scale the enclosed glyphs to fit, about the center of the cell.
+		inner := cluster
+		cluster = ebiten.NewImage(et.cell_size.X, et.cell_size.Y)
+
+		w, h := float64(et.cell_size.X), float64(et.cell_size.Y)
+		scale := enclosureScale(enclosed, et.cell_size.X, et.cell_size.Y)
+		var opts ebiten.DrawImageOptions
+		opts.GeoM.Translate(-w/2, -h/2)
+		opts.GeoM.Scale(scale, scale)
+		opts.GeoM.Translate(w/2, h/2)
+		opts.Filter = ebiten.FilterLinear
+		cluster.DrawImage(inner, &opts)
+		inner.Deallocate()
+
+		for _, image := range enclosing {
+			var opts ebiten.DrawImageOptions
+			opts.Blend = blend
+			cluster.DrawImage(image, &opts)
+		}
+	}
+
+	if et.cluster_cache == nil {
+		et.cluster_cache = newLRUCache[clusterKey](cluster_cache_entries, et.release)
+	}
+	et.cluster_cache.put(key, cluster)
+
+	return
+}
+
+// mirrored returns the mirror screen, if one is attached.
+func (et *ETCellScreen) mirrored() (mirror tcell.Screen) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	mirror = et.mirror
+
+	return
 }
 
 // CharacterSet returns information about the character set.
@@ -500,6 +1621,7 @@ func (et *ETCellScreen) RegisterRuneFallback(r rune, subst string) {
 		et.rune_fallback = make(map[rune]string, 16)
 	}
 	et.rune_fallback[r] = subst
+	et.forget()
 }
 
 // UnregisterRuneFallback unmaps a replacement.  It will unmap
@@ -509,7 +1631,11 @@ func (et *ETCellScreen) RegisterRuneFallback(r rune, subst string) {
 // to "disable" the use of alternate characters that are supported
 // by your terminal except by changing the terminal database.
 func (et *ETCellScreen) UnregisterRuneFallback(r rune) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
 	delete(et.rune_fallback, r)
+	et.forget()
 }
 
 // CanDisplay returns true if the given rune can be displayed on
@@ -522,10 +1648,23 @@ func (et *ETCellScreen) UnregisterRuneFallback(r rune) {
 // also return true if the terminal can replace the glyph with
 // one that is visually indistinguishable from the one requested.
 func (et *ETCellScreen) CanDisplay(r rune, checkFallbacks bool) (can bool) {
-	_, is_empty := et.face.Glyph(r, font.FontStyleNormal)
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	return et.canDisplay(r, checkFallbacks)
+}
+
+// canDisplay returns true if the given rune can be displayed.
+// The grid lock must be held.
+func (et *ETCellScreen) canDisplay(r rune, checkFallbacks bool) (can bool) {
+	_, is_empty := et.faceGlyph(r, font.FontStyleNormal)
 
 	can = !is_empty
 
+	if !can && et.box_drawing {
+		_, can = box_arms[r]
+	}
+
 	if !can && checkFallbacks {
 		_, can = et.rune_fallback[r]
 	}
@@ -533,6 +1672,32 @@ func (et *ETCellScreen) CanDisplay(r rune, checkFallbacks bool) (can bool) {
 	return
 }
 
+// CanDisplayString returns true if every rune of a string can be
+// displayed, including with registered fallbacks, see CanDisplay, and
+// the runes that can not, once each, in the order they first appear.
+// Combining marks are drawn as glyphs of their own over the rune before
+// them, so are checked as any other rune. Runes that draw nothing, such
+// as spaces, control characters, and format characters like the zero
+// width joiner, are always displayable.
+func (et *ETCellScreen) CanDisplayString(s string) (ok bool, missing []rune) {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		if slices.Contains(missing, r) || et.canDisplay(r, true) {
+			continue
+		}
+		missing = append(missing, r)
+	}
+
+	ok = len(missing) == 0
+
+	return
+}
+
 // Resize does nothing, since it's generally not possible to
 // ask a screen to resize, but it allows the Screen to implement
 // the View interface.
@@ -564,7 +1729,9 @@ func (et *ETCellScreen) HasKey(key tcell.Key) (has bool) {
 
 // Suspend pauses input and output processing.  It also restores the
 // terminal settings to what they were when the application started.
-// This can be used to, for example, run a sub-shell.
+// This can be used to, for example, run a sub-shell. While suspended,
+// no input events are posted; see SetSuspendInputHandler to handle input
+// another way.
 func (et *ETCellScreen) Suspend() (err error) {
 	et.grid_lock.Lock()
 	defer et.grid_lock.Unlock()
@@ -585,8 +1752,12 @@ func (et *ETCellScreen) Resume() (err error) {
 // Beep attempts to sound an OS-dependent audible alert and returns an error
 // when unsuccessful.
 func (et *ETCellScreen) Beep() (err error) {
-	if et.on_beep != nil {
-		err = et.on_beep()
+	et.grid_lock.Lock()
+	on_beep := et.on_beep
+	et.grid_lock.Unlock()
+
+	if on_beep != nil {
+		err = on_beep()
 	}
 	return
 }
@@ -619,26 +1790,120 @@ func (et *ETCellScreen) Tty() (tty tcell.Tty, is_tty bool) {
 
 // postEvent helper
 func (et *ETCellScreen) postEvent(ev tcell.Event) (err error) {
-	if et.event_channel == nil {
+	if et.event_channel == nil || !et.wantsEvent(ev) {
+		return
+	}
+
+	select {
+	case et.event_channel <- ev:
+	default:
+		err = tcell.ErrEventQFull
+		if et.on_event_dropped != nil {
+			et.on_event_dropped(ev)
+		}
+	}
+
+	return
+}
+
+// postResize posts a resize event of the grid size, unless one is already
+// in the event queue, to be updated when received.
+// The grid lock must be held.
+func (et *ETCellScreen) postResize() {
+	if et.resize_event != nil {
+		return
+	}
+
+	ev := tcell.NewEventResize(et.grid_size.X, et.grid_size.Y)
+	if et.event_channel == nil || !et.wantsEvent(ev) {
 		return
 	}
 
+	if et.postEvent(ev) == nil {
+		et.resize_event = ev
+	}
+}
+
+// received returns an event received from the event channel, replacing
+// the coalesced resize event with one of the current grid size.
+func (et *ETCellScreen) received(ev tcell.Event) tcell.Event {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	if ev == nil || ev != tcell.Event(et.resize_event) {
+		return ev
+	}
+	et.resize_event = nil
+
+	cols, rows := ev.(*tcell.EventResize).Size()
+	if cols != et.grid_size.X || rows != et.grid_size.Y {
+		ev = tcell.NewEventResize(et.grid_size.X, et.grid_size.Y)
+	}
+
+	return ev
+}
+
+// wantsEvent returns false for events of types that are not enabled.
+func (et *ETCellScreen) wantsEvent(ev tcell.Event) bool {
 	switch ev.(type) {
 	case *tcell.EventFocus:
-		if !et.enable_focus {
-			return
-		}
+		return et.enable_focus
 	case *tcell.EventPaste:
-		if !et.enable_paste {
-			return
-		}
+		return et.enable_paste
 	case *tcell.EventMouse:
-		if et.mouse_flags == tcell.MouseFlags(0) {
-			return
+		return et.mouse_flags != tcell.MouseFlags(0)
+	}
+
+	return true
+}
+
+// enterEvent returns the key event of the Enter key, for the enter key mode.
+func (et *ETCellScreen) enterEvent(mods tcell.ModMask) *tcell.EventKey {
+	switch et.enter_key {
+	case EnterKeyCR:
+		return tcell.NewEventKey(tcell.KeyCR, '\r', mods)
+	case EnterKeyLF:
+		return tcell.NewEventKey(tcell.KeyLF, '\n', mods)
+	}
+
+	return tcell.NewEventKey(tcell.KeyEnter, 0, mods)
+}
+
+// keyEvent returns the key event of a pressed key, or nil if the key is
+// not posted.
+func (et *ETCellScreen) keyEvent(key tcell.Key, mods tcell.ModMask) *tcell.EventKey {
+	switch {
+	case key == tcell.KeyEnter:
+		return et.enterEvent(mods)
+	case key == tcell.KeyEscape && et.no_escape:
+		return nil
+	}
+
+	return tcell.NewEventKey(key, 0, mods)
+}
+
+// paste posts text as a bracketed paste, if paste is enabled.
+// As tcell.EventPaste carries no data, the text is posted as key events
+// between the start and end of the paste, each rune exactly once.
+// Line endings are posted as the Enter key, and other control characters
+// as their control keys.
+func (et *ETCellScreen) paste(text string) {
+	et.postEvent(tcell.NewEventPaste(true))
+
+	last := rune(0)
+	for _, r := range text {
+		switch {
+		case r == '\n' && last == '\r':
+			// CR LF is a single line ending.
+		case r == '\r' || r == '\n':
+			et.postEvent(et.enterEvent(tcell.ModNone))
+		case r < ' ' || r == 0x7f:
+			et.postEvent(tcell.NewEventKey(tcell.Key(r), 0, tcell.ModNone))
+		default:
+			et.postEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
 		}
-	default:
+		last = r
 	}
 
-	et.event_channel <- ev
-	return
+	et.postEvent(tcell.NewEventPaste(false))
 }
@@ -0,0 +1,218 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ErrState is returned when restoring a screen state that is not valid.
+var ErrState = errors.New("invalid screen state")
+
+// state_magic starts a screen state, followed by its format version.
+const state_magic = "ETCS"
+
+// state_version is the format version of screen states.
+const state_version = 1
+
+// stateStyle is the content of a tcell.Style in a screen state.
+type stateStyle struct {
+	fg, bg tcell.Color
+	attrs  tcell.AttrMask
+	url    string
+	url_id string
+}
+
+// SaveState returns the content of the cells of the screen, including
+// the rows of the grid buffer scrolled out of view, as set by SetContent,
+// so that it can be restored with RestoreState, such as for undo or a
+// previous view.
+//
+// The state is a versioned binary format: the magic "ETCS" and a version
+// byte, then unsigned varints for the columns and rows, a table of the
+// distinct styles, and the rune, combining runes and style index of each
+// cell.
+func (et *ETCellScreen) SaveState() []byte {
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(state_magic)
+	buf.WriteByte(state_version)
+
+	putUvarint := func(v uint64) {
+		buf.Write(binary.AppendUvarint(nil, v))
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		buf.WriteString(s)
+	}
+
+	rows := et.bufferRows()
+	putUvarint(uint64(et.grid_size.X))
+	putUvarint(uint64(rows))
+
+	// Cells refer to a table of their distinct styles.
+	cells := et.grid[:et.grid_size.X*rows]
+	indices := map[tcell.Style]uint64{}
+	var styles []tcell.Style
+	for n := range cells {
+		style := cells[n].Style
+		if _, ok := indices[style]; !ok {
+			indices[style] = uint64(len(styles))
+			styles = append(styles, style)
+		}
+	}
+
+	putUvarint(uint64(len(styles)))
+	for _, style := range styles {
+		fg, bg, attrs := style.Decompose()
+		putUvarint(uint64(fg))
+		putUvarint(uint64(bg))
+		putUvarint(uint64(attrs))
+		putString(urlOf(style))
+		putString(urlIdOf(style))
+	}
+
+	for n := range cells {
+		c := &cells[n]
+		putUvarint(uint64(c.Rune))
+		putUvarint(uint64(len(c.Combining)))
+		for _, r := range c.Combining {
+			putUvarint(uint64(r))
+		}
+		putUvarint(indices[c.Style])
+	}
+
+	return buf.Bytes()
+}
+
+// RestoreState sets the content of the cells of the screen from a state
+// returned by SaveState, as SetBuffer does: cells of the state beyond the
+// grid buffer are ignored, and cells of the grid buffer beyond the state
+// are not changed. The results are not displayed until Show() or Sync()
+// is called. ErrState is returned, and the screen is not changed, if the
+// state is not valid.
+func (et *ETCellScreen) RestoreState(state []byte) (err error) {
+	cells, err := decodeState(state)
+	if err != nil {
+		return
+	}
+
+	et.SetBuffer(cells)
+
+	return
+}
+
+// decodeState decodes a screen state into rows of cells.
+func decodeState(state []byte) (cells [][]BufferCell, err error) {
+	r := bytes.NewReader(state)
+
+	magic := make([]byte, len(state_magic)+1)
+	_, err = io.ReadFull(r, magic)
+	if err != nil || string(magic[:len(state_magic)]) != state_magic {
+		err = fmt.Errorf("%w: not a screen state", ErrState)
+		return
+	}
+	if magic[len(state_magic)] != state_version {
+		err = fmt.Errorf("%w: unknown version %v", ErrState, magic[len(state_magic)])
+		return
+	}
+
+	getUvarint := func() (v uint64) {
+		if err != nil {
+			return
+		}
+		v, err = binary.ReadUvarint(r)
+		return
+	}
+	getString := func() string {
+		n := getUvarint()
+		if err != nil || n > uint64(r.Len()) {
+			err = io.ErrUnexpectedEOF
+			return ""
+		}
+		s := make([]byte, n)
+		_, err = io.ReadFull(r, s)
+		return string(s)
+	}
+
+	cols, rows := getUvarint(), getUvarint()
+	count := getUvarint()
+
+	// Every style and cell takes at least a byte, which bounds the
+	// allocations of corrupt states.
+	size := uint64(r.Len())
+	if err == nil && (count > size || cols > size || rows > size || cols*rows > size) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	var styles []tcell.Style
+	for n := uint64(0); err == nil && n < count; n++ {
+		s := stateStyle{
+			fg:    tcell.Color(getUvarint()),
+			bg:    tcell.Color(getUvarint()),
+			attrs: tcell.AttrMask(getUvarint()),
+		}
+		s.url = getString()
+		s.url_id = getString()
+
+		style := tcell.StyleDefault.Foreground(s.fg).Background(s.bg).Attributes(s.attrs).Url(s.url)
+		if s.url_id != "" {
+			id, ok := strings.CutPrefix(s.url_id, "id=")
+			if !ok && err == nil {
+				err = fmt.Errorf("hyperlink ID %q", s.url_id)
+			}
+			style = style.UrlId(id)
+		}
+		styles = append(styles, style)
+	}
+
+	for y := uint64(0); err == nil && y < rows; y++ {
+		row := make([]BufferCell, cols)
+		for x := range row {
+			row[x].Rune = rune(getUvarint())
+			combining := getUvarint()
+			if err == nil && combining > uint64(r.Len()) {
+				err = io.ErrUnexpectedEOF
+			}
+			for n := uint64(0); err == nil && n < combining; n++ {
+				row[x].Combining = append(row[x].Combining, rune(getUvarint()))
+			}
+			index := getUvarint()
+			if err == nil && index >= uint64(len(styles)) {
+				err = fmt.Errorf("style %v of %v", index, len(styles))
+			}
+			if err != nil {
+				break
+			}
+			row[x].Style = styles[index]
+		}
+		cells = append(cells, row)
+	}
+
+	if err == nil && r.Len() > 0 {
+		err = fmt.Errorf("%v trailing bytes", r.Len())
+	}
+
+	if err != nil {
+		cells = nil
+		err = fmt.Errorf("%w: %w", ErrState, err)
+	}
+
+	return
+}
+
+// urlIdOf returns the OSC 8 hyperlink ID of a style, as stored by
+// tcell.Style.UrlId, with an "id=" prefix. tcell does not export an
+// accessor for it, so reflection is used.
+func urlIdOf(style tcell.Style) string {
+	return styleString(style, "urlId")
+}
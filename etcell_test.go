@@ -3,7 +3,19 @@
 package tcell_ebiten
 
 import (
+	"errors"
+	"image"
+	"image/color"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ezrec/tcell_ebiten/font"
 
@@ -15,7 +27,9 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
 
+	"github.com/hajimehoshi/ebiten/v2"
 	ebiten_text "github.com/hajimehoshi/ebiten/v2/text/v2"
 )
 
@@ -96,3 +110,4247 @@ func TestETCellResizeAny(t *testing.T) {
 		assert.Equal(entry.sy, sy)
 	}
 }
+
+func TestETCellMaxGridSize(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	var cols, rows int
+	et.OnResize(func(c, r int) {
+		cols, rows = c, r
+	})
+
+	game := et.NewGame()
+
+	// A tiny font in a huge window is clamped to the default.
+	w, h := game.Layout(100000, 100000)
+	assert.Equal(image.Point{X: 1024, Y: 1024}, et.grid_size)
+	assert.Equal(1024*1024, len(et.grid))
+	assert.Equal(2048, w)
+	assert.Equal(3072, h)
+
+	// Clamping a larger grid resizes it immediately.
+	et.SetMaxGridSize(80, 25)
+	assert.Equal(image.Point{X: 80, Y: 25}, et.grid_size)
+	assert.Equal(80, cols)
+	assert.Equal(25, rows)
+
+	w, h = game.Layout(1000, 1000)
+	assert.Equal(160, w)
+	assert.Equal(75, h)
+
+	et.SetScreenSize(100, 10)
+	assert.Equal(image.Point{X: 80, Y: 10}, et.grid_size)
+	assert.Equal(80*10, len(et.grid))
+
+	// Smaller grids are not grown.
+	et.SetMaxGridSize(0, 0)
+	assert.Equal(image.Point{X: 80, Y: 10}, et.grid_size)
+	et.SetScreenSize(100, 10)
+	assert.Equal(image.Point{X: 100, Y: 10}, et.grid_size)
+}
+
+func TestETCellOnResize(t *testing.T) {
+	assert := assert.New(t)
+
+	face := &font.CacheFont{
+		FontMetrics: ebiten_text.Metrics{HAscent: 2.5, HDescent: 0.5},
+		Width:       2,
+		Height:      3,
+	}
+
+	et := &ETCell{}
+	et.SetFont(face)
+
+	var calls int
+	var cols, rows int
+	et.OnResize(func(c, r int) {
+		calls++
+		cols, rows = c, r
+	})
+
+	game := et.NewGame()
+
+	game.Layout(20, 30)
+	assert.Equal(1, calls)
+	assert.Equal(10, cols)
+	assert.Equal(10, rows)
+
+	// Same size; no callback.
+	game.Layout(21, 31)
+	assert.Equal(1, calls)
+
+	game.Layout(40, 30)
+	assert.Equal(2, calls)
+	assert.Equal(20, cols)
+	assert.Equal(10, rows)
+
+	// Nil callback is safe.
+	et.OnResize(nil)
+	game.Layout(2, 3)
+	assert.Equal(2, calls)
+}
+
+func TestInversePoint(t *testing.T) {
+	assert := assert.New(t)
+
+	// Rotate a 100x50 game by 90 degrees, clockwise, then move it into
+	// view. Game pixel (x, y) is now at screen pixel (50 - y, x).
+	var geom ebiten.GeoM
+	geom.Rotate(math.Pi / 2)
+	geom.Translate(50, 0)
+
+	table := [](struct {
+		sx, sy int
+		gx, gy int
+	}){
+		{sx: 49, sy: 0, gx: 0, gy: 0},
+		{sx: 40, sy: 20, gx: 20, gy: 9},
+		{sx: 0, sy: 99, gx: 99, gy: 49},
+		{sx: 51, sy: 0, gx: 0, gy: -2},
+	}
+
+	for _, entry := range table {
+		pt, ok := inversePoint(geom, entry.sx, entry.sy)
+		assert.True(ok)
+		assert.Equal(image.Point{X: entry.gx, Y: entry.gy}, pt, "screen %v,%v", entry.sx, entry.sy)
+	}
+
+	// Shear and scale.
+	geom.Reset()
+	geom.Scale(2, 2)
+	geom.Skew(0.5, 0)
+	for _, pt := range []image.Point{{0, 0}, {10, 5}, {33, 17}} {
+		sx, sy := geom.Apply(float64(pt.X)+0.5, float64(pt.Y)+0.5)
+		got, ok := inversePoint(geom, int(sx), int(sy))
+		assert.True(ok)
+		assert.Equal(pt, got)
+	}
+
+	// Zero scale is not invertible.
+	geom.Reset()
+	geom.Scale(0, 0)
+	_, ok := inversePoint(geom, 0, 0)
+	assert.False(ok)
+}
+
+func TestETCellClock(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.UnixMilli(0)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetClock(func() time.Time { return now })
+	et.init()
+
+	table := [](struct {
+		ms     int64
+		text   bool
+		cursor bool
+	}){
+		{ms: 0, text: true, cursor: true},
+		{ms: 374, text: true, cursor: true},
+		{ms: 375, text: true, cursor: false},
+		{ms: 450, text: false, cursor: false},
+		{ms: 750, text: false, cursor: true},
+		{ms: 900, text: true, cursor: true},
+	}
+
+	for _, entry := range table {
+		now = time.UnixMilli(entry.ms)
+		text, cursor := et.blinkPhases()
+		assert.Equal(entry.text, text, "text at %vms", entry.ms)
+		assert.Equal(entry.cursor, cursor, "cursor at %vms", entry.ms)
+	}
+}
+
+func TestETCellPostError(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	bad := errors.New("bad thing")
+	assert.Nil(screen.PostError(bad))
+	assert.True(screen.HasPendingEvent())
+
+	ev, ok := screen.PollEvent().(*tcell.EventError)
+	assert.True(ok)
+	assert.Equal("bad thing", ev.Error())
+}
+
+func TestETCellLink(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	const url = "https://example.com/"
+	style := tcell.StyleDefault.Url(url)
+	assert.Equal(url, urlOf(style))
+	assert.Equal("", urlOf(tcell.StyleDefault))
+	assert.Equal("id=a", urlIdOf(style.UrlId("a")))
+	assert.Equal("", urlIdOf(style))
+
+	// The fields read by reflection still exist, as strings.
+	for _, name := range []string{"url", "urlId"} {
+		field, ok := reflect.TypeOf(tcell.Style{}).FieldByName(name)
+		if assert.True(ok, name) {
+			assert.Equal(reflect.String, field.Type.Kind(), name)
+		}
+	}
+	assert.Equal("", styleString(style, "missing"))
+	assert.Equal("", styleString(style, "fg"))
+
+	screen.SetContent(3, 2, 'x', nil, style)
+	screen.SetContent(4, 2, 'y', nil, tcell.StyleDefault)
+
+	// Not visible until shown.
+	assert.Equal("", et.linkAt(3, 2))
+
+	screen.Show()
+	assert.Equal(url, et.linkAt(3, 2))
+	assert.Equal("", et.linkAt(4, 2))
+	assert.Equal("", et.linkAt(-1, 2))
+	assert.Equal("", et.linkAt(3, 5))
+}
+
+func TestETCellHover(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	var hovers []image.Point
+	et.OnCellHover(func(x, y int) {
+		hovers = append(hovers, image.Point{X: x, Y: y})
+	})
+
+	game := et.NewGame()
+
+	game.setHover(false, image.Point{})
+	game.setHover(true, image.Point{X: 0, Y: 0})
+	game.setHover(true, image.Point{X: 0, Y: 0})
+	game.setHover(true, image.Point{X: 2, Y: 1})
+	game.setHover(false, image.Point{X: 9, Y: 9})
+	game.setHover(false, image.Point{X: 8, Y: 8})
+
+	assert.Equal([]image.Point{{0, 0}, {2, 1}, {-1, -1}}, hovers)
+}
+
+func TestETCellInvalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(1, 1, 'x', nil, tcell.StyleDefault)
+	screen.Invalidate()
+	assert.True(et.invalidated)
+	assert.False(et.grid[1*10+1].synced)
+
+	game := et.NewGame()
+	game.Draw(ebiten.NewImage(20, 15))
+	assert.False(et.invalidated)
+	assert.True(et.grid[1*10+1].synced)
+}
+
+func TestETCellInvalidateCell(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(1, 1, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(2, 1, 'y', nil, tcell.StyleDefault)
+	screen.SetContent(3, 1, 'z', nil, tcell.StyleDefault)
+	screen.Show()
+
+	// Corrupt the resolved glyphs.
+	for x := 1; x <= 3; x++ {
+		et.grid[1*10+x].glyph = nil
+	}
+
+	screen.InvalidateCell(1, 1)
+	screen.InvalidateRect(image.Rect(3, 1, 20, 2))
+	assert.False(et.grid[1*10+1].synced)
+	assert.True(et.grid[1*10+2].synced)
+	assert.False(et.grid[1*10+3].synced)
+	assert.False(et.grid[1*10+9].synced)
+	assert.True(et.grid[2*10+3].synced)
+
+	// Only the invalidated cells are redrawn.
+	screen.Show()
+	assert.NotNil(et.grid[1*10+1].glyph)
+	assert.Nil(et.grid[1*10+2].glyph)
+	assert.NotNil(et.grid[1*10+3].glyph)
+
+	assert.NotPanics(func() {
+		screen.InvalidateCell(-1, 100)
+	})
+}
+
+// benchmarkScreen returns a screen, filled with text.
+func benchmarkScreen(b *testing.B) (et *ETCell) {
+	face, err := font.NewMonoFontFromTTF(gomono.TTF, 11)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	et = &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(80, 25)
+	et.Screen().Init()
+
+	return
+}
+
+// fillScreen fills the screen with (mostly) varying content.
+func fillScreen(screen tcell.Screen, n int) {
+	width, height := screen.Size()
+	for y := range height {
+		for x := range width {
+			screen.SetContent(x, y, rune('!'+(x+y+n)%94), nil, tcell.StyleDefault)
+		}
+	}
+}
+
+func BenchmarkShow(b *testing.B) {
+	et := benchmarkScreen(b)
+	screen := et.Screen()
+	defer screen.Fini()
+
+	b.ResetTimer()
+	for n := range b.N {
+		fillScreen(screen, n)
+		screen.Show()
+	}
+}
+
+func BenchmarkInvalidate(b *testing.B) {
+	et := benchmarkScreen(b)
+	screen := et.Screen()
+	defer screen.Fini()
+
+	b.ResetTimer()
+	for n := range b.N {
+		fillScreen(screen, n)
+		screen.Invalidate()
+	}
+}
+
+func TestETCellGlyphProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	face := &font.CacheFont{Width: 2, Height: 3}
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	const bad_rune = rune(0x1234567)
+	custom := ebiten.NewImage(2, 3)
+
+	var calls int
+	et.SetGlyphProvider(func(r rune, style font.FontStyle, fg, bg color.RGBA) (*ebiten.Image, bool) {
+		calls++
+		if r != bad_rune {
+			return nil, false
+		}
+		assert.Equal(font.FontStyleBold, style)
+		assert.Equal(color.RGBA{0, 0, 255, 255}, bg)
+		return custom, true
+	})
+
+	style := tcell.StyleDefault.Bold(true).Background(tcell.ColorBlue)
+	screen.SetContent(0, 0, bad_rune, nil, style)
+	screen.SetContent(1, 0, 'x', nil, style)
+	screen.Show()
+
+	assert.Same(custom, et.grid[0].glyph)
+	assert.Same(face.Empty(), et.grid[1].glyph)
+
+	// Only changed cells are resolved again.
+	calls = 0
+	screen.Show()
+	assert.Equal(0, calls)
+}
+
+func TestETCellWheelAsKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.NewGame()
+
+	// Default is wheel as mouse buttons.
+	buttons, _ := game.wheel(0, 1, tcell.ModNone)
+	assert.Equal(tcell.WheelUp, buttons)
+	buttons, _ = game.wheel(-1, -1, tcell.ModNone)
+	assert.Equal(tcell.WheelDown|tcell.WheelLeft, buttons)
+	assert.False(screen.HasPendingEvent())
+
+	et.SetWheelAsKeys(true)
+
+	buttons, _ = game.wheel(0, 1, tcell.ModNone)
+	assert.Equal(tcell.ButtonNone, buttons)
+	buttons, _ = game.wheel(-1, -1, tcell.ModShift)
+	assert.Equal(tcell.WheelLeft, buttons)
+
+	ev, ok := screen.PollEvent().(*tcell.EventKey)
+	assert.True(ok)
+	assert.Equal(tcell.KeyUp, ev.Key())
+
+	ev, ok = screen.PollEvent().(*tcell.EventKey)
+	assert.True(ok)
+	assert.Equal(tcell.KeyDown, ev.Key())
+	assert.Equal(tcell.ModShift, ev.Modifiers())
+
+	assert.False(screen.HasPendingEvent())
+
+	et.SetWheelAsKeys(false)
+	buttons, _ = game.wheel(0, 1, tcell.ModNone)
+	assert.Equal(tcell.WheelUp, buttons)
+}
+
+func TestETCellInitFini(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	var inits, finis int
+	et.OnInit(func() { inits++ })
+	et.OnFini(func() { finis++ })
+
+	screen := et.Screen()
+
+	screen.Fini()
+	assert.Equal(0, finis)
+
+	screen.Init()
+	screen.Init()
+	assert.Equal(1, inits)
+
+	screen.Fini()
+	screen.Fini()
+	assert.Equal(1, finis)
+
+	screen.Init()
+	assert.Equal(2, inits)
+
+	et.OnInit(nil)
+	et.OnFini(nil)
+	screen.Fini()
+	screen.Init()
+	screen.Fini()
+	assert.Equal(2, inits)
+	assert.Equal(1, finis)
+}
+
+func TestETCellPixelOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+	screen.Show()
+
+	game := et.NewGame()
+	dst := ebiten.NewImage(20, 15)
+
+	// No offset; no offscreen image needed.
+	game.Draw(dst)
+	assert.Nil(game.offset_image)
+
+	// Offset; drawn via a clipping offscreen image.
+	game.SetPixelOffset(0.5, -1.5)
+	game.Draw(dst)
+	assert.NotNil(game.offset_image)
+	assert.Equal(image.Pt(20, 15), game.offset_image.Bounds().Size())
+}
+
+func TestETCellFontChecked(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+
+	err := et.SetFontChecked(&font.CacheFont{Width: 0, Height: 0})
+	assert.ErrorIs(err, ErrFontSize)
+	assert.Equal(image.Pt(1, 1), et.cell_size)
+
+	err = et.SetFontChecked(&font.CacheFont{Width: 2, Height: -1})
+	assert.ErrorIs(err, ErrFontSize)
+	assert.Equal(image.Pt(2, 1), et.cell_size)
+
+	// The screen remains usable.
+	sx, sy := et.NewGame().Layout(20, 30)
+	assert.Equal(20, sx)
+	assert.Equal(30, sy)
+
+	err = et.SetFontChecked(&font.CacheFont{Width: 2, Height: 3})
+	assert.Nil(err)
+	assert.Equal(image.Pt(2, 3), et.cell_size)
+}
+
+func TestETCellMagnifier(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+	screen.Show()
+
+	game := et.NewGame()
+	dst := ebiten.NewImage(20, 15)
+
+	// Disabled by default.
+	game.Draw(dst)
+	assert.Nil(game.magnifier_image)
+
+	// Cursor hidden; nothing to magnify.
+	et.SetMagnifier(true, 2, 3.0)
+	screen.HideCursor()
+	game.Draw(dst)
+	assert.Nil(game.magnifier_image)
+
+	screen.ShowCursor(1, 1)
+	game.Draw(dst)
+	assert.NotNil(game.magnifier_image)
+	assert.Equal(image.Pt(5*2, 5*3), game.magnifier_image.Bounds().Size())
+}
+
+func TestResolveStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	white := color.RGBA{255, 255, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+
+	table := [](struct {
+		style    tcell.Style
+		defaults tcell.Style
+		fg, bg   color.RGBA
+		attr     tcell.AttrMask
+	}){
+		{style: tcell.StyleDefault, fg: white, bg: black},
+		{
+			style:    tcell.StyleDefault,
+			defaults: tcell.StyleDefault.Foreground(tcell.ColorRed).Underline(true),
+			fg:       color.RGBA{255, 0, 0, 255}, bg: black,
+			attr: tcell.AttrUnderline,
+		},
+		{
+			style: tcell.StyleDefault.Reverse(true),
+			fg:    black, bg: white,
+			attr: tcell.AttrReverse,
+		},
+		{
+			style: tcell.StyleDefault.Foreground(tcell.NewRGBColor(0x40, 0x80, 0xc0)).Bold(true),
+			fg:    color.RGBA{0x80, 0xff, 0xff, 255}, bg: black,
+			attr: tcell.AttrBold,
+		},
+		{
+			style: tcell.StyleDefault.Foreground(tcell.NewRGBColor(0x40, 0x80, 0xc0)).Dim(true),
+			fg:    color.RGBA{0x20, 0x40, 0x60, 255}, bg: black,
+			attr: tcell.AttrDim,
+		},
+		{
+			style: tcell.StyleDefault.Background(tcell.ColorBlue).Attributes(tcell.AttrInvalid | tcell.AttrReverse),
+			fg:    white, bg: color.RGBA{0, 0, 255, 255},
+			attr: tcell.AttrNone,
+		},
+	}
+
+	for n, entry := range table {
+		fg, bg, attr := ResolveStyle(entry.style, entry.defaults)
+		assert.Equal(entry.fg, fg, "entry %v", n)
+		assert.Equal(entry.bg, bg, "entry %v", n)
+		assert.Equal(entry.attr, attr, "entry %v", n)
+	}
+}
+
+func TestETCellFrame(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.NewGame()
+	game.SetFrame(4, color.RGBA{255, 0, 0, 255})
+
+	sx, sy := game.Layout(20+8+1, 30+8+2)
+	assert.Equal(20+8, sx)
+	assert.Equal(30+8, sy)
+
+	cols, rows := screen.Size()
+	assert.Equal(10, cols)
+	assert.Equal(10, rows)
+
+	// Mouse positions are inset by the frame.
+	game.GeoM.Translate(100, 0)
+	pt, ok := inversePoint(game.gridGeoM(), 104, 4)
+	assert.True(ok)
+	assert.Equal(image.Pt(0, 0), pt)
+
+	screen.Show()
+	game.Draw(ebiten.NewImage(200, 50))
+}
+
+func TestETCellModes(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.init()
+
+	screen := et.Screen()
+
+	assert.Equal(tcell.MouseButtonEvents, screen.MouseFlags())
+	assert.False(screen.PasteEnabled())
+	assert.False(screen.FocusEnabled())
+
+	screen.EnableMouse(tcell.MouseMotionEvents)
+	screen.EnablePaste()
+	screen.EnableFocus()
+	assert.Equal(tcell.MouseButtonEvents|tcell.MouseMotionEvents, screen.MouseFlags())
+	assert.True(screen.PasteEnabled())
+	assert.True(screen.FocusEnabled())
+
+	screen.DisableMouse()
+	screen.DisablePaste()
+	screen.DisableFocus()
+	assert.Equal(tcell.MouseFlags(0), screen.MouseFlags())
+	assert.False(screen.PasteEnabled())
+	assert.False(screen.FocusEnabled())
+}
+
+func TestETCellInterrupt(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	done := make(chan struct{})
+	go func() {
+		et.Interrupt("redraw")
+		close(done)
+	}()
+
+	ev, ok := screen.PollEvent().(*tcell.EventInterrupt)
+	assert.True(ok)
+	assert.Equal("redraw", ev.Data())
+
+	<-done
+	assert.False(screen.HasPendingEvent())
+}
+
+func TestETCellCursorBlinkPause(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.UnixMilli(10_000)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetClock(func() time.Time { return now })
+	et.init()
+
+	// Cursor blink cycle is 750ms; 10_000 % 750 = 250, in the first half.
+	_, cursor := et.blinkPhases()
+	assert.True(cursor)
+
+	// No pause by default.
+	et.last_activity = now
+	_, cursor = et.blinkPhases()
+	assert.True(cursor)
+
+	et.SetCursorBlinkPause(time.Second)
+	_, cursor = et.blinkPhases()
+	assert.False(cursor)
+
+	now = now.Add(999 * time.Millisecond)
+	_, cursor = et.blinkPhases()
+	assert.False(cursor)
+
+	// 11_000 % 750 = 500, in the second half.
+	now = now.Add(time.Millisecond)
+	_, cursor = et.blinkPhases()
+	assert.False(cursor)
+
+	// 11_500 % 750 = 250, in the first half.
+	now = now.Add(500 * time.Millisecond)
+	_, cursor = et.blinkPhases()
+	assert.True(cursor)
+}
+
+func TestETCellMirror(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	mirror := tcell.NewSimulationScreen("UTF-8")
+	assert.Nil(mirror.Init())
+	defer mirror.Fini()
+	mirror.SetSize(10, 5)
+
+	et.AttachMirror(mirror)
+
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed)
+	screen.SetContent(2, 3, 'x', nil, style)
+	screen.ShowCursor(4, 1)
+	screen.Show()
+
+	primary, _, got, _ := mirror.GetContent(2, 3)
+	assert.Equal('x', primary)
+	assert.Equal(style, got)
+
+	cells, _, _ := mirror.GetContents()
+	assert.Equal([]byte("x"), cells[3*10+2].Bytes)
+
+	cx, cy, visible := mirror.GetCursor()
+	assert.Equal(4, cx)
+	assert.Equal(1, cy)
+	assert.True(visible)
+
+	// Detached mirrors are not updated.
+	et.AttachMirror(nil)
+	screen.SetContent(2, 3, 'y', nil, style)
+	screen.Show()
+
+	primary, _, _, _ = mirror.GetContent(2, 3)
+	assert.Equal('x', primary)
+}
+
+func TestETCellBlinkMode(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+
+	green := color.RGBA{0, 255, 0, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	table := [](struct {
+		mode    BlinkMode
+		alert   tcell.Color
+		phase   bool
+		fg      color.RGBA
+		visible bool
+	}){
+		{mode: BlinkModeHide, phase: false, fg: green, visible: true},
+		{mode: BlinkModeHide, phase: true, fg: green, visible: false},
+		{mode: BlinkModeColorSwap, phase: false, fg: green, visible: true},
+		{mode: BlinkModeColorSwap, phase: true, fg: red, visible: true},
+		{mode: BlinkModeColorSwap, alert: tcell.ColorBlue, phase: false, fg: green, visible: true},
+		{mode: BlinkModeColorSwap, alert: tcell.ColorBlue, phase: true, fg: blue, visible: true},
+	}
+
+	for n, entry := range table {
+		et.SetBlinkMode(entry.mode)
+		et.SetBlinkAlertColor(entry.alert)
+		fg, visible := et.blinkText(green, entry.phase)
+		assert.Equal(entry.fg, fg, "entry %v", n)
+		assert.Equal(entry.visible, visible, "entry %v", n)
+	}
+}
+
+func TestBlinkState(t *testing.T) {
+	assert := assert.New(t)
+
+	// At a high frame rate, the phase follows the clock.
+	var bs blinkState
+	for ms := int64(1000); ms < 3000; ms += 16 {
+		assert.Equal(ms%900 < 450, bs.phase(ms, 900, 0.5), "at %vms", ms)
+	}
+
+	// At a frame rate of one frame per full cycle, the clock phase
+	// never changes, but the blink is still visible.
+	bs = blinkState{}
+	on := bs.phase(100, 900, 0.5)
+	for ms := int64(1000); ms < 10000; ms += 900 {
+		on = !on
+		assert.Equal(on, bs.phase(ms, 900, 0.5), "at %vms", ms)
+	}
+}
+
+func TestETCellScrollback(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	assert.Equal(5, screen.BufferRows())
+
+	screen.SetContent(1, 1, 'a', nil, tcell.StyleDefault)
+	screen.SetBufferRows(20)
+	assert.Equal(20, screen.BufferRows())
+
+	// Size is still the visible screen.
+	cols, rows := screen.Size()
+	assert.Equal(10, cols)
+	assert.Equal(5, rows)
+
+	// Content is kept, and the whole buffer is addressable.
+	primary, _, _, _ := screen.GetContent(1, 1)
+	assert.Equal('a', primary)
+	screen.SetContent(2, 17, 'b', nil, tcell.StyleDefault.Url("b"))
+	primary, _, _, _ = screen.GetContent(2, 17)
+	assert.Equal('b', primary)
+
+	// Offsets are clamped to the buffer.
+	screen.SetScrollOffset(-3)
+	assert.Equal(0, screen.ScrollOffset())
+	screen.SetScrollOffset(100)
+	assert.Equal(15, screen.ScrollOffset())
+	screen.SetScrollOffset(13)
+	assert.Equal(13, screen.ScrollOffset())
+
+	// Visible cells are relative to the offset.
+	screen.Show()
+	assert.Equal("b", et.linkAt(2, 4))
+
+	game := et.NewGame()
+	game.Draw(ebiten.NewImage(20, 15))
+
+	// Shrinking the buffer re-clamps the offset.
+	screen.SetBufferRows(8)
+	assert.Equal(3, screen.ScrollOffset())
+	screen.SetBufferRows(0)
+	assert.Equal(5, screen.BufferRows())
+	assert.Equal(0, screen.ScrollOffset())
+	primary, _, _, _ = screen.GetContent(1, 1)
+	assert.Equal('a', primary)
+}
+
+func TestETCellClearRedraw(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	var calls int
+	et.SetGlyphProvider(func(r rune, style font.FontStyle, fg, bg color.RGBA) (*ebiten.Image, bool) {
+		calls++
+		return nil, false
+	})
+
+	style := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	fillScreen(screen, 0)
+	screen.SetContent(1, 1, 'x', []rune{'\u0301'}, style)
+	screen.Show()
+	assert.Equal(10*5+1, calls)
+
+	// Clear, then redraw the same content; nothing is resolved again.
+	calls = 0
+	screen.Clear()
+	fillScreen(screen, 0)
+	screen.SetContent(1, 1, 'x', []rune{'\u0301'}, style)
+	screen.Show()
+	assert.Equal(0, calls)
+	assert.True(et.grid[1*10+1].synced)
+
+	// Changed content is resolved again.
+	screen.SetContent(1, 1, 'x', nil, style)
+	screen.SetContent(2, 1, 'y', nil, style)
+	screen.Show()
+	assert.Equal(2, calls)
+
+	// A changed default style is resolved again.
+	calls = 0
+	screen.SetStyle(tcell.StyleDefault.Background(tcell.ColorBlue))
+	screen.Clear()
+	screen.Show()
+	assert.Equal(10*5, calls)
+	assert.Equal(color.RGBA{0, 0, 255, 255}, et.grid[0].bgColor)
+
+	// Sync resolves everything.
+	calls = 0
+	screen.Sync()
+	assert.Equal(10*5, calls)
+}
+
+func BenchmarkClearRedraw(b *testing.B) {
+	et := benchmarkScreen(b)
+	screen := et.Screen()
+	defer screen.Fini()
+
+	b.ResetTimer()
+	for range b.N {
+		screen.Clear()
+		fillScreen(screen, 0)
+		screen.Show()
+	}
+}
+
+func TestETCellCombiningBlend(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	var calls int
+	et.SetGlyphProvider(func(r rune, style font.FontStyle, fg, bg color.RGBA) (*ebiten.Image, bool) {
+		calls++
+		return nil, false
+	})
+
+	// Stacked diacritics are composited into a single glyph.
+	screen.SetContent(0, 0, 'a', []rune{'\u0301', '̈'}, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'a', []rune{'\u0301', '̈'}, tcell.StyleDefault)
+	screen.SetContent(2, 0, 'b', nil, tcell.StyleDefault)
+	screen.Show()
+
+	first := et.grid[0].glyph
+	assert.NotNil(first)
+	assert.Equal(image.Pt(2, 3), first.Bounds().Size())
+	assert.NotSame(et.face.Empty(), first)
+	assert.Same(first, et.grid[1].glyph)
+	assert.Equal(1, et.cluster_cache.len())
+
+	// Changing the blend composites the cluster again.
+	et.SetCombiningBlend(ebiten.BlendSourceOver)
+	screen.Sync()
+	assert.NotSame(first, et.grid[0].glyph)
+	assert.Same(et.grid[0].glyph, et.grid[1].glyph)
+	assert.Equal(1, et.cluster_cache.len())
+
+	// Clusters are tinted when drawn, so are shared by all colors.
+	cluster := et.grid[0].glyph
+	red := tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlue)
+	screen.SetContent(1, 0, 'a', []rune{'\u0301', '̈'}, red)
+	screen.Show()
+	assert.Same(cluster, et.grid[1].glyph)
+	assert.Equal(1, et.cluster_cache.len())
+
+	// The cache is bounded, and evicted clusters are released once no
+	// cell uses them.
+	et.grid_lock.Lock()
+	et.cluster_cache.entries = 1
+	et.grid_lock.Unlock()
+	screen.SetContent(2, 0, 'b', []rune{'\u0301'}, tcell.StyleDefault)
+	screen.Show()
+	assert.Equal(1, et.cluster_cache.len())
+	et.grid_lock.Lock()
+	et.releaseGlyphs()
+	assert.Equal([](*ebiten.Image){cluster}, et.released)
+	et.grid_lock.Unlock()
+
+	screen.SetContent(0, 0, 'b', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'b', nil, tcell.StyleDefault)
+	screen.Show()
+	et.grid_lock.Lock()
+	et.releaseGlyphs()
+	assert.Empty(et.released)
+	et.grid_lock.Unlock()
+}
+
+func TestIsRawKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, key := range []ebiten.Key{ebiten.KeyPause, ebiten.KeyPrintScreen, ebiten.KeyNumpadEnter} {
+		assert.True(isRawKey(key), key.String())
+	}
+
+	for _, key := range []ebiten.Key{ebiten.KeyA, ebiten.KeyDigit5, ebiten.KeyNumpad0, ebiten.KeySpace, ebiten.KeyF1, ebiten.KeyF13, ebiten.KeyF24, ebiten.KeyShiftLeft, ebiten.KeyControl, ebiten.KeyEnter} {
+		assert.False(isRawKey(key), key.String())
+	}
+}
+
+func TestFunctionKeysF13ToF24(t *testing.T) {
+	assert := assert.New(t)
+
+	screen := &ETCellScreen{}
+
+	for n := 0; n < 12; n++ {
+		key := tcell.KeyF13 + tcell.Key(n)
+		assert.Equal(key, ebiten_key_map[ebiten.KeyF13+ebiten.Key(n)])
+		assert.True(screen.HasKey(key), tcell.KeyNames[key])
+	}
+}
+
+func TestPressedKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	game := (&ETCell{}).NewGame()
+	assert.Empty(game.PressedKeys())
+
+	keys := pressedKeys([]ebiten.Key{ebiten.KeyArrowUp, ebiten.KeyA, ebiten.KeyShiftLeft, ebiten.KeyArrowLeft})
+	assert.Equal([]tcell.Key{tcell.KeyUp, tcell.KeyLeft}, keys)
+}
+
+func TestETCellBoxDrawing(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 8, Height: 16})
+	et.SetScreenSize(4, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Box drawing runes are displayable when drawn synthetically.
+	assert.False(screen.CanDisplay('│', false))
+	et.SetBoxDrawing(true, false)
+	assert.True(screen.CanDisplay('│', false))
+
+	screen.SetContent(1, 1, '│', nil, tcell.StyleDefault)
+	screen.SetContent(2, 1, '─', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Same(et.boxGlyph(box_arms['│']), et.grid[1*4+1].glyph)
+	assert.Same(et.boxGlyph(box_arms['─']), et.grid[1*4+2].glyph)
+
+	// Connected, the vertical line gains a junction to its right.
+	et.SetBoxDrawing(true, true)
+	screen.Sync()
+	assert.Same(et.boxGlyph(box_arms['├']), et.grid[1*4+1].glyph)
+	assert.Same(et.boxGlyph(box_arms['─']), et.grid[1*4+2].glyph)
+
+	// Changing a neighbor resolves the junction again.
+	screen.SetContent(2, 1, ' ', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, '━', nil, tcell.StyleDefault)
+	screen.SetContent(1, 2, '║', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Same(et.boxGlyph(box_arms['│']), et.grid[1*4+1].glyph)
+	assert.Same(et.boxGlyph(box_arms['┯']), et.grid[0*4+1].glyph)
+
+	// Connecting requires synthetic drawing.
+	et.SetBoxDrawing(false, true)
+	assert.False(et.box_connect)
+}
+
+func TestBoxSpan(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([][2]int{{4, 5}}, boxStrips(4, 1, box_light))
+	assert.Equal([][2]int{{3, 5}}, boxStrips(4, 1, box_heavy))
+	assert.Equal([][2]int{{3, 4}, {5, 6}}, boxStrips(4, 1, box_double))
+	assert.Empty(boxStrips(4, 1, box_none))
+
+	lo, hi := boxSpan(4, 1, box_none, box_none)
+	assert.Equal(4, lo)
+	assert.Equal(4, hi)
+
+	lo, hi = boxSpan(4, 1, box_light, box_double)
+	assert.Equal(3, lo)
+	assert.Equal(6, hi)
+}
+
+func TestETCellDefaultStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	blue := color.RGBA{R: 0x00, G: 0x00, B: 0xff, A: 0xff}
+	red := color.RGBA{R: 0xff, G: 0x00, B: 0x00, A: 0xff}
+
+	// Clearing uses the default style.
+	et.SetDefaultStyle(tcell.StyleDefault.Background(tcell.ColorBlue))
+	screen.SetContent(1, 0, 'x', nil, tcell.StyleDefault.Background(tcell.ColorGreen))
+	screen.Clear()
+	screen.Show()
+	assert.Equal(blue, et.grid[0].bgColor)
+	assert.Equal(blue, et.grid[1].bgColor)
+
+	// Changing the default style redraws cleared cells, but not others.
+	screen.SetContent(1, 0, 'x', nil, tcell.StyleDefault.Background(tcell.ColorGreen))
+	screen.Show()
+	green := et.grid[1].bgColor
+	et.SetDefaultStyle(tcell.StyleDefault.Background(tcell.ColorRed))
+	assert.False(et.grid[0].synced)
+	assert.True(et.grid[1].synced)
+	screen.Show()
+	assert.Equal(red, et.grid[0].bgColor)
+	assert.Equal(green, et.grid[1].bgColor)
+}
+
+func TestETCellWatchFont(t *testing.T) {
+	assert := assert.New(t)
+
+	interval := font_watch_interval
+	font_watch_interval = 10 * time.Millisecond
+	defer func() { font_watch_interval = interval }()
+
+	et := &ETCell{}
+
+	_, err := et.WatchFont(filepath.Join(t.TempDir(), "missing.ttf"), 0)
+	assert.ErrorIs(err, fs.ErrNotExist)
+
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	assert.Nil(os.WriteFile(path, gomono.TTF, 0o644))
+
+	stop, err := et.WatchFont(path, 0)
+	assert.Nil(err)
+	defer stop()
+
+	et.SetScreenSize(4, 2)
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	et.grid_lock.Lock()
+	face := et.face
+	et.grid_lock.Unlock()
+	assert.NotNil(face)
+
+	// Modifying the file reloads the font.
+	assert.Nil(os.WriteFile(path, gomonobold.TTF, 0o644))
+	later := time.Now().Add(time.Hour)
+	assert.Nil(os.Chtimes(path, later, later))
+
+	reloaded := false
+	for range 200 {
+		time.Sleep(font_watch_interval)
+		et.grid_lock.Lock()
+		reloaded = et.face != face
+		et.grid_lock.Unlock()
+		if reloaded {
+			break
+		}
+	}
+	assert.True(reloaded)
+
+	et.grid_lock.Lock()
+	assert.True(et.invalidated)
+	et.grid_lock.Unlock()
+
+	stop()
+	stop()
+}
+
+func BenchmarkDrawEmpty(b *testing.B) {
+	for _, clear := range []bool{false, true} {
+		name := "cells"
+		if clear {
+			name = "clear"
+		}
+		b.Run(name, func(b *testing.B) {
+			et := benchmarkScreen(b)
+			screen := et.Screen()
+			defer screen.Fini()
+
+			if clear {
+				et.SetClearColor(color.Black)
+			}
+
+			// A mostly empty screen.
+			screen.Clear()
+			screen.SetContent(0, 0, '$', nil, tcell.StyleDefault)
+			screen.Show()
+
+			game := et.NewGame()
+			dst := ebiten.NewImage(et.GetGameSize())
+
+			b.ResetTimer()
+			for range b.N {
+				game.Draw(dst)
+			}
+		})
+	}
+}
+
+func TestETCellClearColor(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetClearColor(color.Black)
+	assert.Equal(color.Black, et.clear_color)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(1, 0, 'x', nil, tcell.StyleDefault.Background(tcell.ColorBlue))
+	screen.Show()
+
+	game := et.NewGame()
+	dst := ebiten.NewImage(et.GetGameSize())
+	assert.NotPanics(func() { game.Draw(dst) })
+
+	et.SetClearColor(nil)
+	assert.Nil(et.clear_color)
+	assert.NotPanics(func() { game.Draw(dst) })
+}
+
+func TestETCellNegativeContent(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.Clear()
+	for _, pt := range []image.Point{{X: -1, Y: 0}, {X: 0, Y: -1}, {X: -1, Y: -1}, {X: -5, Y: 1}} {
+		assert.NotPanics(func() { screen.SetContent(pt.X, pt.Y, 'x', nil, tcell.StyleDefault) })
+
+		var primary rune
+		assert.NotPanics(func() { primary, _, _, _ = screen.GetContent(pt.X, pt.Y) })
+		assert.Equal(rune(0), primary)
+	}
+
+	// Negative columns do not wrap to the previous row.
+	for n := range et.grid {
+		assert.Equal(' ', et.grid[n].Rune)
+	}
+}
+
+func TestETCellFitToContent(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 6)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Content in a sub-region is moved to the top left.
+	screen.Clear()
+	screen.SetContent(3, 2, 'a', nil, tcell.StyleDefault)
+	screen.SetContent(6, 4, 'b', nil, tcell.StyleDefault)
+	screen.SetContent(4, 3, ' ', nil, tcell.StyleDefault.Background(tcell.ColorBlue))
+	et.FitToContent()
+
+	w, h := screen.Size()
+	assert.Equal(4, w)
+	assert.Equal(3, h)
+	assert.Equal(image.Rect(0, 0, 8, 9), et.layout)
+
+	primary, _, _, _ := screen.GetContent(0, 0)
+	assert.Equal('a', primary)
+	primary, _, _, _ = screen.GetContent(3, 2)
+	assert.Equal('b', primary)
+	_, _, style, _ := screen.GetContent(1, 1)
+	assert.Equal(tcell.StyleDefault.Background(tcell.ColorBlue), style)
+
+	// An empty screen is 1x1.
+	screen.Clear()
+	et.FitToContent()
+	w, h = screen.Size()
+	assert.Equal(1, w)
+	assert.Equal(1, h)
+}
+
+func TestETCellWheelMultiplier(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.NewGame()
+
+	// Unaccelerated, any movement is a single event.
+	buttons, repeat := game.wheel(0, 50, tcell.ModNone)
+	assert.Equal(tcell.WheelUp, buttons)
+	assert.Equal(0, repeat)
+
+	et.SetWheelMultiplier(3)
+
+	buttons, repeat = game.wheel(0, 1, tcell.ModNone)
+	assert.Equal(tcell.WheelUp, buttons)
+	assert.Equal(2, repeat)
+
+	// Large movement is clamped.
+	buttons, repeat = game.wheel(0, -50, tcell.ModNone)
+	assert.Equal(tcell.WheelDown, buttons)
+	assert.Equal(wheel_max_events-1, repeat)
+
+	// Small movement accumulates.
+	et.SetWheelMultiplier(1)
+	buttons, _ = game.wheel(0, 0.5, tcell.ModNone)
+	assert.Equal(tcell.ButtonNone, buttons)
+	buttons, repeat = game.wheel(0, 0.5, tcell.ModNone)
+	assert.Equal(tcell.WheelUp, buttons)
+	assert.Equal(0, repeat)
+
+	// As keys, each event is a key.
+	et.SetWheelAsKeys(true)
+	et.SetWheelMultiplier(2)
+	buttons, repeat = game.wheel(0, -1.5, tcell.ModNone)
+	assert.Equal(tcell.ButtonNone, buttons)
+	assert.Equal(0, repeat)
+
+	for range 3 {
+		ev, ok := screen.PollEvent().(*tcell.EventKey)
+		assert.True(ok)
+		assert.Equal(tcell.KeyDown, ev.Key())
+	}
+	assert.False(screen.HasPendingEvent())
+}
+
+// countingFace counts the glyphs requested from a face.
+type countingFace struct {
+	font.Face
+	calls int
+}
+
+func (cf *countingFace) Glyph(r rune, style font.FontStyle) (*ebiten.Image, bool) {
+	cf.calls++
+	return cf.Face.Glyph(r, style)
+}
+
+func TestETCellRasterizeOnDraw(t *testing.T) {
+	assert := assert.New(t)
+
+	face := &countingFace{Face: &font.CacheFont{Width: 2, Height: 3}}
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(4, 2)
+	et.SetRasterizeOnDraw(true)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Glyphs are only requested in Draw.
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.Show()
+	screen.Sync()
+	assert.Equal(0, face.calls)
+
+	game := et.NewGame()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+	assert.NotZero(face.calls)
+	assert.True(et.grid[0].synced)
+
+	// Otherwise, glyphs are requested in Show.
+	et.SetRasterizeOnDraw(false)
+	face.calls = 0
+	screen.SetContent(1, 0, 'y', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.NotZero(face.calls)
+}
+
+func TestETCellRevealAnimation(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Unix(1000, 0)
+	now := start
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetClock(func() time.Time { return now })
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Off by default.
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.True(et.grid[0].revealed.IsZero())
+
+	et.SetRevealAnimation(time.Second)
+	screen.SetContent(0, 0, 'y', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Equal(start, et.grid[0].revealed)
+
+	frame := cellFrame{now: start, reveal: time.Second}
+	assert.Equal(float32(0), frame.fade(&et.grid[0]))
+	frame.now = start.Add(500 * time.Millisecond)
+	assert.Equal(float32(0.5), frame.fade(&et.grid[0]))
+	frame.now = start.Add(2 * time.Second)
+	assert.Equal(float32(1), frame.fade(&et.grid[0]))
+
+	// Cells shown before the animation was enabled are not faded.
+	frame.now = start
+	assert.Equal(float32(1), frame.fade(&et.grid[1]))
+
+	// Disabled, nothing is faded.
+	frame.reveal = 0
+	assert.Equal(float32(1), frame.fade(&et.grid[0]))
+}
+
+// failingFace fails to generate the glyphs of some runes.
+type failingFace struct {
+	font.Face
+	fail rune
+}
+
+func (ff *failingFace) Glyph(r rune, style font.FontStyle) (*ebiten.Image, bool) {
+	if r == ff.fail {
+		panic("out of glyph memory")
+	}
+	return ff.Face.Glyph(r, style)
+}
+
+func TestETCellGlyphError(t *testing.T) {
+	assert := assert.New(t)
+
+	cf := &font.CacheFont{Width: 2, Height: 3}
+	block := ebiten.NewImage(2, 3)
+	cf.SetGlyph('x', block)
+
+	et := &ETCell{}
+	et.SetFont(&failingFace{Face: cf, fail: 'y'})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	var failed []rune
+	var errs []error
+	et.OnGlyphError(func(r rune, err error) {
+		failed = append(failed, r)
+		errs = append(errs, err)
+	})
+
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'x', []rune{'y'}, tcell.StyleDefault)
+	assert.NotPanics(screen.Show)
+
+	assert.Same(block, et.grid[0].glyph)
+	assert.NotNil(et.grid[1].glyph)
+	assert.NotEmpty(failed)
+	for n := range failed {
+		assert.Equal('y', failed[n])
+		assert.ErrorIs(errs[n], ErrGlyph)
+	}
+
+	// A failing rune can not be displayed.
+	failed = nil
+	assert.False(screen.CanDisplay('y', false))
+	assert.Equal([]rune{'y'}, failed)
+
+	game := et.NewGame()
+	assert.NotPanics(func() { game.Draw(ebiten.NewImage(et.GetGameSize())) })
+}
+
+func TestETCellSetBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(3, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.Clear()
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed)
+
+	// The buffer is clipped to the grid, and rows may be ragged.
+	screen.SetBuffer([][]BufferCell{
+		{{Rune: 'a'}, {Rune: 'b', Style: style}, {Rune: 'c'}, {Rune: 'd'}},
+		{{Rune: 'e', Combining: []rune{'\u0301'}}},
+		{{Rune: 'f'}},
+	})
+
+	primary, combining, got, _ := screen.GetContent(1, 0)
+	assert.Equal('b', primary)
+	assert.Nil(combining)
+	assert.Equal(style, got)
+
+	primary, _, _, _ = screen.GetContent(2, 0)
+	assert.Equal('c', primary)
+
+	primary, combining, _, _ = screen.GetContent(0, 1)
+	assert.Equal('e', primary)
+	assert.Equal([]rune{'\u0301'}, combining)
+
+	// Cells beyond the buffer are unchanged.
+	primary, _, _, _ = screen.GetContent(1, 1)
+	assert.Equal(' ', primary)
+	assert.False(et.grid[0].synced)
+}
+
+func BenchmarkSetContent(b *testing.B) {
+	et := benchmarkScreen(b)
+	screen := et.Screen()
+	defer screen.Fini()
+
+	b.ResetTimer()
+	for n := range b.N {
+		fillScreen(screen, n)
+	}
+}
+
+func BenchmarkSetBuffer(b *testing.B) {
+	et := benchmarkScreen(b)
+	screen := et.Screen()
+	defer screen.Fini()
+
+	width, height := screen.Size()
+	cells := make([][]BufferCell, height)
+	for y := range cells {
+		cells[y] = make([]BufferCell, width)
+	}
+
+	b.ResetTimer()
+	for n := range b.N {
+		for y := range cells {
+			for x := range cells[y] {
+				cells[y][x] = BufferCell{Rune: rune('!' + (x+y+n)%94)}
+			}
+		}
+		screen.SetBuffer(cells)
+	}
+}
+
+func TestETCellFocusIndicator(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]image.Rectangle{
+		image.Rect(0, 0, 10, 2),
+		image.Rect(0, 6, 10, 8),
+		image.Rect(0, 2, 2, 6),
+		image.Rect(8, 2, 10, 6),
+	}, ringEdges(image.Pt(10, 8), 2))
+
+	// The ring is no thicker than half the size.
+	assert.Equal(image.Rect(0, 0, 10, 1), ringEdges(image.Pt(10, 3), 5)[0])
+	assert.Empty(ringEdges(image.Pt(10, 8), 0))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.NewGame()
+	game.SetFocusIndicator(color.White, 2)
+	game.SetFrame(1, color.Black)
+	game.has_focus = true
+
+	dst := ebiten.NewImage(et.GetGameSize())
+	assert.NotPanics(func() { game.Draw(dst) })
+
+	game.SetFocusIndicator(nil, 0)
+	assert.NotPanics(func() { game.Draw(dst) })
+}
+
+func TestETCellInactiveDim(t *testing.T) {
+	assert := assert.New(t)
+
+	newGame := func() *ETCellGame {
+		et := &ETCell{}
+		et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+		et.SetScreenSize(4, 2)
+		return et.NewGame()
+	}
+
+	active, inactive := newGame(), newGame()
+	active.has_focus = true
+
+	// Off by default.
+	assert.Equal(0.0, inactive.inactiveDim())
+
+	for _, game := range []*ETCellGame{active, inactive} {
+		game.SetInactiveDim(0.5)
+	}
+	assert.Equal(0.0, active.inactiveDim())
+	assert.Equal(0.5, inactive.inactiveDim())
+
+	dst := ebiten.NewImage(8, 6)
+	assert.Equal(0.0, active.drawState(dst, false, false).inactive_dim)
+	assert.Equal(0.5, inactive.drawState(dst, false, false).inactive_dim)
+	assert.NotPanics(func() { active.Draw(dst) })
+	assert.NotPanics(func() { inactive.Draw(dst) })
+
+	// Losing focus dims.
+	active.has_focus = false
+	assert.Equal(0.5, active.inactiveDim())
+
+	inactive.SetInactiveDim(2)
+	assert.Equal(1.0, inactive.inactiveDim())
+}
+
+// offsetFace records the baseline offset that each glyph of a MonoFont is
+// generated with.
+type offsetFace struct {
+	*font.MonoFont
+	offsets map[*ebiten.Image]float64
+}
+
+func (of *offsetFace) Glyph(character rune, style font.FontStyle) (glyph *ebiten.Image, is_empty bool) {
+	glyph, is_empty = of.MonoFont.Glyph(character, style)
+	if _, ok := of.offsets[glyph]; !ok {
+		of.offsets[glyph] = of.BaselineOffset
+	}
+
+	return
+}
+
+func TestETCellInvalidateGlyphs(t *testing.T) {
+	assert := assert.New(t)
+
+	mono, err := font.NewMonoFont(nil)
+	assert.Nil(err)
+	face := &offsetFace{MonoFont: mono, offsets: map[*ebiten.Image]float64{}}
+
+	// Glyphs set by the application can not be generated again.
+	user := ebiten.NewImage(mono.Size())
+	mono.SetGlyph('u', user)
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'u', nil, tcell.StyleDefault)
+	screen.Show()
+	glyph := et.grid[0].glyph
+	assert.Equal(mono.BaselineOffset, face.offsets[glyph])
+
+	// Glyphs are kept until the next Draw.
+	mono.BaselineOffset += 3
+	et.InvalidateGlyphs()
+	assert.Same(glyph, et.grid[0].glyph)
+	assert.True(face.HasGlyph('x', font.FontStyleNormal))
+
+	game := et.NewGame()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+
+	// The glyph is generated again with the new setting.
+	assert.NotSame(glyph, et.grid[0].glyph)
+	assert.Equal(mono.BaselineOffset, face.offsets[et.grid[0].glyph])
+	assert.True(et.grid[0].synced)
+	assert.False(et.invalidate_glyphs)
+
+	// Set glyphs are kept.
+	assert.Same(user, et.grid[1].glyph)
+}
+
+func TestETCellKeyboardMouse(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	et.SetKeyboardMouse(true)
+	assert.Equal(DefaultKeyboardMouseBindings, et.kbd_mouse_bindings)
+
+	game := et.NewGame()
+
+	expect := func(x, y int, buttons tcell.ButtonMask) {
+		ev, ok := screen.PollEvent().(*tcell.EventMouse)
+		if assert.True(ok) {
+			ex, ey := ev.Position()
+			assert.Equal(x, ex)
+			assert.Equal(y, ey)
+			assert.Equal(buttons, ev.Buttons())
+		}
+	}
+
+	// Movement is clamped to the screen.
+	right := KeyboardMouseBinding{Key: ebiten.KeyArrowRight, Move: image.Pt(1, 0)}
+	down := KeyboardMouseBinding{Key: ebiten.KeyArrowDown, Move: image.Pt(0, 2)}
+	game.keyboardMouseAction(right)
+	game.keyboardMouseAction(down)
+	game.keyboardMouseAction(down)
+	expect(1, 0, tcell.ButtonNone)
+	expect(1, 2, tcell.ButtonNone)
+	expect(1, 2, tcell.ButtonNone)
+
+	// Clicks press and release.
+	game.keyboardMouseAction(KeyboardMouseBinding{Key: ebiten.KeyEnter, Button: tcell.ButtonPrimary})
+	expect(1, 2, tcell.ButtonPrimary)
+	expect(1, 2, tcell.ButtonNone)
+	assert.False(screen.HasPendingEvent())
+
+	dst := ebiten.NewImage(et.GetGameSize())
+	assert.NotPanics(func() { game.Draw(dst) })
+}
+
+func TestETCellPaste(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	drain := func() (events []tcell.Event) {
+		for screen.HasPendingEvent() {
+			events = append(events, screen.PollEvent())
+		}
+		return
+	}
+
+	expectKeys := func(events []tcell.Event) {
+		if !assert.Len(events, 5) {
+			return
+		}
+		for n, r := range "hi" {
+			ev, ok := events[n].(*tcell.EventKey)
+			if assert.True(ok) {
+				assert.Equal(tcell.KeyRune, ev.Key())
+				assert.Equal(r, ev.Rune())
+			}
+		}
+		for _, n := range []int{2, 4} {
+			ev, ok := events[n].(*tcell.EventKey)
+			if assert.True(ok) {
+				assert.Equal(tcell.KeyEnter, ev.Key())
+			}
+		}
+		ev, ok := events[3].(*tcell.EventKey)
+		if assert.True(ok) {
+			assert.Equal(tcell.KeyTab, ev.Key())
+		}
+	}
+
+	// Without paste enabled, only the keys are posted.
+	et.Paste("hi\r\n\t\n")
+	expectKeys(drain())
+
+	// With paste enabled, each pasted key is posted once, bracketed.
+	screen.EnablePaste()
+	et.Paste("hi\r\n\t\n")
+	events := drain()
+	if assert.Len(events, 7) {
+		start, ok := events[0].(*tcell.EventPaste)
+		if assert.True(ok) {
+			assert.True(start.Start())
+		}
+		end, ok := events[6].(*tcell.EventPaste)
+		if assert.True(ok) {
+			assert.True(end.End())
+		}
+		expectKeys(events[1:6])
+	}
+}
+
+func TestETCellWriteANSI(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(6, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	row := func(y int) (text string) {
+		for x := 0; x < 6; x++ {
+			r, _, _, _ := screen.GetContent(x, y)
+			text += string(r)
+		}
+		return
+	}
+
+	// Text, with an escape sequence split between writes.
+	et.WriteANSI([]byte("ab\x1b[1;3"))
+	et.WriteANSI([]byte("1mcd\x1b[0m"))
+	assert.Equal("abcd  ", row(0))
+	_, _, style, _ := screen.GetContent(2, 0)
+	assert.Equal(tcell.StyleDefault.Bold(true).Foreground(tcell.ColorMaroon), style)
+	_, _, style, _ = screen.GetContent(1, 0)
+	assert.Equal(tcell.StyleDefault, style)
+
+	// Extended colors.
+	assert.Equal(tcell.StyleDefault.Foreground(tcell.PaletteColor(200)).Background(tcell.NewRGBColor(1, 2, 3)),
+		ansiSGR(tcell.StyleDefault, []int{38, 5, 200, 48, 2, 1, 2, 3}))
+
+	// Cursor positioning, and UTF-8 split between writes.
+	et.WriteANSI([]byte("\x1b[2;5H\xc2"))
+	et.WriteANSI([]byte("\xa9"))
+	assert.Equal("    © ", row(1))
+	x, y := screen.ANSICursor()
+	assert.Equal(5, x)
+	assert.Equal(1, y)
+
+	// Erase in line, and relative movement.
+	et.WriteANSI([]byte("\x1b[H\x1b[2C\x1b[K"))
+	assert.Equal("ab    ", row(0))
+
+	// Wrapping, and scrolling at the bottom.
+	et.WriteANSI([]byte("\x1b[3;1H123456789"))
+	assert.Equal("    © ", row(0))
+	assert.Equal("123456", row(1))
+	assert.Equal("789   ", row(2))
+
+	// Line endings, and combining marks.
+	et.WriteANSI([]byte("\r\né"))
+	r, combining, _, _ := screen.GetContent(0, 2)
+	assert.Equal('e', r)
+	assert.Equal([]rune{'\u0301'}, combining)
+
+	// Erase the screen, ignoring OSC strings.
+	et.WriteANSI([]byte("\x1b]0;title\x07\x1b[2J"))
+	for y := 0; y < 3; y++ {
+		assert.Equal("      ", row(y))
+	}
+}
+
+func TestETCellCellCache(t *testing.T) {
+	assert := assert.New(t)
+
+	cc := newCellCache(2, image.Pt(2, 3))
+
+	var renders int
+	render := func(img *ebiten.Image) { renders++ }
+
+	a := cellKey{fg: color.RGBA{R: 1}}
+	b := cellKey{fg: color.RGBA{R: 2}}
+	c := cellKey{fg: color.RGBA{R: 3}}
+
+	img_a := cc.get(a, render)
+	assert.Equal(image.Pt(2, 3), img_a.Bounds().Size())
+	cc.get(b, render)
+	assert.Equal(img_a, cc.get(a, render))
+	assert.Equal(2, renders)
+
+	// The least recently used image is evicted.
+	cc.get(c, render)
+	assert.Equal(3, renders)
+	assert.Len(cc.cells, 2)
+	assert.NotContains(cc.cells, b)
+	assert.Equal(img_a, cc.get(a, render))
+
+	cc.dispose()
+	assert.Empty(cc.cells)
+	assert.Equal(0, cc.lru.Len())
+}
+
+func TestETCellSetCellCache(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.NewGame()
+	dst := ebiten.NewImage(et.GetGameSize())
+
+	// Caching is off by default.
+	game.Draw(dst)
+	assert.Nil(game.cell_cache)
+
+	// Cells that look alike share an image.
+	et.SetCellCache(16)
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(2, 0, 'x', nil, tcell.StyleDefault.Underline(true))
+	screen.SetContent(3, 0, 'x', nil, tcell.StyleDefault.Blink(true))
+	screen.Show()
+	game.Draw(dst)
+	if assert.NotNil(game.cell_cache) {
+		// Plain 'x', and underlined 'x'. Blinking cells are not cached.
+		assert.Len(game.cell_cache.cells, 2)
+	}
+
+	// Disabling caching releases the cache.
+	et.SetCellCache(0)
+	game.Draw(dst)
+	assert.Nil(game.cell_cache)
+}
+
+func BenchmarkDrawCellCache(b *testing.B) {
+	for _, content := range []string{"demo", "noise"} {
+		for _, entries := range []int{0, 1024} {
+			name := content + "/uncached"
+			if entries > 0 {
+				name = content + "/cached"
+			}
+			b.Run(name, func(b *testing.B) {
+				et := benchmarkScreen(b)
+				screen := et.Screen()
+				defer screen.Fini()
+
+				et.SetCellCache(entries)
+
+				game := et.NewGame()
+				dst := ebiten.NewImage(et.GetGameSize())
+
+				width, height := screen.Size()
+				fillScreen(screen, 0)
+				screen.Show()
+
+				b.ResetTimer()
+				for n := range b.N {
+					// Noise changes every cell, every frame.
+					if content == "noise" {
+						for y := range height {
+							for x := range width {
+								color := tcell.PaletteColor((x*7 + y*13 + n) % 256)
+								style := tcell.StyleDefault.Foreground(color).Background(color ^ 0xff)
+								screen.SetContent(x, y, rune('!'+(x*y+n)%94), nil, style)
+							}
+						}
+						screen.Show()
+					}
+					game.Draw(dst)
+				}
+			})
+		}
+	}
+}
+
+func TestETCellGame(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+
+	// Game is a singleton, NewGame is not.
+	game := et.Game()
+	assert.NotNil(game)
+	assert.Same(game, et.Game())
+	assert.Same(et, game.ETCell)
+	assert.NotSame(game, et.NewGame())
+	assert.NotSame(et.NewGame(), et.NewGame())
+}
+
+func TestETCellEnterKey(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	tests := []struct {
+		mode EnterKeyMode
+		key  tcell.Key
+		r    rune
+	}{
+		{EnterKeyEnter, tcell.KeyEnter, 0},
+		{EnterKeyCR, tcell.KeyCR, '\r'},
+		{EnterKeyLF, tcell.KeyLF, '\n'},
+	}
+
+	for _, test := range tests {
+		et.SetEnterKey(test.mode)
+
+		ev := et.enterEvent(tcell.ModShift)
+		assert.Equal(test.key, ev.Key())
+		assert.Equal(test.r, ev.Rune())
+		assert.Equal(tcell.ModShift, ev.Modifiers())
+
+		// Pasted line endings are posted as a single Enter key.
+		et.Paste("\r\n")
+		ev, ok := screen.PollEvent().(*tcell.EventKey)
+		if assert.True(ok) {
+			assert.Equal(test.key, ev.Key())
+			assert.Equal(test.r, ev.Rune())
+			assert.Equal(tcell.ModNone, ev.Modifiers())
+		}
+		assert.False(screen.HasPendingEvent())
+	}
+}
+
+func TestETCellLogicalSize(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	game := et.Game()
+
+	// Without a logical size, the grid fills the window.
+	w, h := game.Layout(100, 60)
+	assert.Equal(100, w)
+	assert.Equal(60, h)
+
+	// With a logical size, the grid is laid out in it, and the game
+	// draws it scaled to the outside size.
+	et.SetLogicalSize(40, 30)
+	for _, size := range []image.Point{{100, 60}, {1000, 800}, {10, 10}} {
+		w, h = game.Layout(size.X, size.Y)
+		assert.Equal(size.X, w)
+		assert.Equal(size.Y, h)
+		cols, rows := screen.Size()
+		assert.Equal(20, cols)
+		assert.Equal(10, rows)
+	}
+
+	// The render target is scaled by an integer factor, and centered.
+	game.Layout(100, 100)
+	geom := game.final_geom
+	assert.Equal(2.0, geom.Element(0, 0))
+	assert.Equal(2.0, geom.Element(1, 1))
+	x, y := geom.Apply(0, 0)
+	assert.Equal(10.0, x)
+	assert.Equal(20.0, y)
+
+	// Scaling down fits the outside size.
+	game.Layout(20, 30)
+	geom = game.final_geom
+	assert.Equal(0.5, geom.Element(0, 0))
+	x, y = geom.Apply(0, 0)
+	assert.Equal(0.0, x)
+	assert.Equal(7.5, y)
+
+	// The default final screen fits the layout to the window.
+	geom = fitGeoM(image.Pt(40, 30), image.Pt(100, 100))
+	assert.Equal(2.5, geom.Element(0, 0))
+	x, y = geom.Apply(0, 0)
+	assert.Equal(0.0, x)
+	assert.Equal(12.5, y)
+
+	et.SetLogicalSize(0, 0)
+	w, h = game.Layout(100, 60)
+	assert.Equal(100, w)
+	assert.Equal(60, h)
+}
+
+func TestETCellRedrawOnEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetRedrawOnEvent(true)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.Show()
+
+	game := et.Game()
+	dst := ebiten.NewImage(et.GetGameSize())
+
+	// redrawn draws a frame, returning true if it was redrawn, rather
+	// than re-blitted.
+	redrawn := func() bool {
+		game.grid_draw = nil
+		game.Draw(dst)
+		return game.grid_draw != nil
+	}
+
+	assert.True(redrawn())
+	assert.NotNil(game.frame_image)
+	assert.False(redrawn())
+
+	// Content changes are redrawn.
+	screen.SetContent(1, 0, 'y', nil, tcell.StyleDefault)
+	assert.False(redrawn())
+	screen.Show()
+	assert.True(redrawn())
+	assert.False(redrawn())
+
+	// Cursor changes are redrawn.
+	screen.ShowCursor(1, 1)
+	assert.True(redrawn())
+	assert.False(redrawn())
+
+	// Without redraw on event, every frame is redrawn.
+	et.SetRedrawOnEvent(false)
+	assert.True(redrawn())
+	assert.True(redrawn())
+	assert.Nil(game.frame_image)
+}
+
+func BenchmarkDrawIdle(b *testing.B) {
+	for _, on_event := range []bool{false, true} {
+		name := "continuous"
+		if on_event {
+			name = "on_event"
+		}
+		b.Run(name, func(b *testing.B) {
+			et := benchmarkScreen(b)
+			screen := et.Screen()
+			defer screen.Fini()
+
+			et.SetRedrawOnEvent(on_event)
+
+			// A static screen.
+			fillScreen(screen, 0)
+			screen.Show()
+
+			game := et.Game()
+			dst := ebiten.NewImage(et.GetGameSize())
+
+			b.ResetTimer()
+			for range b.N {
+				game.Draw(dst)
+			}
+		})
+	}
+}
+
+func TestETCellCursorAdvance(t *testing.T) {
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 4)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	tests := []struct {
+		name   string
+		x, y   int
+		text   string
+		nx, ny int
+	}{
+		{"ascii", 0, 0, "abc", 3, 0},
+		{"empty", 4, 2, "", 4, 2},
+		{"cjk", 0, 0, "日本", 4, 0},
+		{"cjk wrapped", 8, 0, "日本語", 4, 1},
+		{"cjk moved to next row", 8, 0, "a日", 2, 1},
+		{"right edge", 7, 1, "abc", 0, 2},
+		{"emoji", 1, 0, "👍", 3, 0},
+		{"emoji zwj", 0, 0, "👨‍👩‍👧x", 3, 0},
+		{"emoji variation", 0, 0, "❤️", 2, 0},
+		{"combining", 0, 0, "ée", 2, 0},
+		{"newline", 5, 0, "a\nbc\r\nd", 1, 2},
+		{"past bottom", 0, 3, "0123456789ab", 2, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			nx, ny := screen.CursorAdvance(test.x, test.y, test.text)
+			assert.Equal(test.nx, nx)
+			assert.Equal(test.ny, ny)
+		})
+	}
+}
+
+func TestETCellPrint(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	style := tcell.StyleDefault.Bold(true)
+	nx, ny := screen.Print(1, 0, "é日本", style)
+	assert.Equal(2, nx)
+	assert.Equal(1, ny)
+
+	r, combining, cell_style, _ := screen.GetContent(1, 0)
+	assert.Equal('e', r)
+	assert.Equal([]rune{'\u0301'}, combining)
+	assert.Equal(style, cell_style)
+
+	r, _, _, _ = screen.GetContent(2, 0)
+	assert.Equal('日', r)
+	r, _, _, _ = screen.GetContent(3, 0)
+	assert.Equal(' ', r)
+	r, _, _, _ = screen.GetContent(0, 1)
+	assert.Equal('本', r)
+}
+
+func TestETCellFocusModel(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	left, right := et.NewGame(), et.NewGame()
+
+	// Hover: focus follows the mouse.
+	assert.True(left.updateFocus(true, false))
+	assert.False(left.updateFocus(false, false))
+	assert.False(left.updateFocus(false, true))
+
+	// Click: focus moves on taps, and is kept until another game is tapped.
+	et.SetFocusModel(FocusModelClick)
+	assert.False(left.updateFocus(true, false))
+	assert.True(left.updateFocus(true, true))
+	assert.True(left.updateFocus(false, false))
+	assert.False(right.updateFocus(true, false))
+	assert.True(right.updateFocus(true, true))
+	assert.False(left.updateFocus(false, false))
+	assert.True(right.updateFocus(false, false))
+
+	// Always: the game with focus keeps it, regardless of the mouse.
+	et.SetFocusModel(FocusModelAlways)
+	assert.True(right.updateFocus(false, false))
+	assert.False(left.updateFocus(true, true))
+	assert.True(right.updateFocus(false, false))
+
+	// Always: with no game focused, the first game updated takes focus,
+	// and only it posts key events and draws its focus ring.
+	et = &ETCell{}
+	et.SetFocusModel(FocusModelAlways)
+	left, right = et.NewGame(), et.NewGame()
+	for range 3 {
+		assert.True(left.updateFocus(false, false))
+		assert.False(right.updateFocus(true, false))
+	}
+}
+
+func TestETCellScrollbar(t *testing.T) {
+	assert := assert.New(t)
+
+	size := image.Pt(40, 30)
+
+	// No thumb when all rows are visible.
+	assert.True(scrollbarThumb(size, 4, 10, 10, 0).Empty())
+	assert.True(scrollbarThumb(size, 0, 10, 20, 0).Empty())
+
+	// The thumb spans the visible rows.
+	assert.Equal(image.Rect(36, 0, 40, 15), scrollbarThumb(size, 4, 10, 20, 0))
+	assert.Equal(image.Rect(36, 6, 40, 21), scrollbarThumb(size, 4, 10, 20, 4))
+	assert.Equal(image.Rect(36, 15, 40, 30), scrollbarThumb(size, 4, 10, 20, 10))
+
+	// The thumb is at least as tall as it is wide.
+	assert.Equal(image.Rect(36, 26, 40, 30), scrollbarThumb(size, 4, 10, 1000, 990))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(20, 10)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Off by default, and with a nil color.
+	assert.False(et.scrollbar.enabled)
+	et.SetScrollbar(true, 4, nil)
+	assert.False(et.scrollbar.enabled)
+
+	et.SetScrollbar(true, 4, color.White)
+	assert.True(et.scrollbar.enabled)
+	screen.SetBufferRows(20)
+
+	dst := ebiten.NewImage(et.GetGameSize())
+	assert.NotPanics(func() { et.Game().Draw(dst) })
+}
+
+func TestETCellCursorFallbackRender(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &cell{fgColor: color.RGBA{R: 1, A: 255}, bgColor: color.RGBA{B: 2, A: 255}}
+	inverted := invertCell(c)
+	assert.Equal(c.bgColor, inverted.fgColor)
+	assert.Equal(c.fgColor, inverted.bgColor)
+	assert.Equal(color.RGBA{R: 1, A: 255}, c.fgColor)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetCursorFallbackRender(true)
+	assert.True(et.cursor_fallback)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(1, 1, 'x', nil, tcell.StyleDefault)
+	screen.SetCursorStyle(tcell.CursorStyleSteadyBlock)
+	screen.Show()
+
+	dst := ebiten.NewImage(et.GetGameSize())
+	for _, pt := range []image.Point{{1, 1}, {0, 0}, {-1, -1}, {9, 9}} {
+		screen.ShowCursor(pt.X, pt.Y)
+		assert.NotPanics(func() { et.Game().Draw(dst) })
+	}
+}
+
+func TestETCellMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	// The default font is set lazily, before Init.
+	et := &ETCell{}
+	width, height, metrics := et.Metrics()
+	assert.Greater(width, 0)
+	assert.Greater(height, 0)
+	assert.Greater(metrics.HAscent, 0.0)
+	assert.NotNil(et.face)
+
+	// A later font replaces it.
+	face := &font.CacheFont{Width: 2, Height: 3}
+	et.SetFont(face)
+	width, height, metrics = et.Metrics()
+	assert.Equal(2, width)
+	assert.Equal(3, height)
+	assert.Equal(face.Metrics(), metrics)
+}
+
+func TestETCellEventDropped(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	var dropped []tcell.Event
+	et.OnEventDropped(func(ev tcell.Event) {
+		dropped = append(dropped, ev)
+	})
+
+	// Fill the queue, without blocking.
+	for screen.PostEvent(tcell.NewEventInterrupt(nil)) == nil {
+	}
+	assert.Len(dropped, 1)
+
+	ev := tcell.NewEventInterrupt(42)
+	assert.ErrorIs(screen.PostEvent(ev), tcell.ErrEventQFull)
+	if assert.Len(dropped, 2) {
+		assert.Same(ev, dropped[1])
+	}
+
+	// Draining the queue makes room.
+	screen.PollEvent()
+	assert.NoError(screen.PostEvent(ev))
+	assert.Len(dropped, 2)
+}
+
+func TestBlinkDutyCycle(t *testing.T) {
+	assert := assert.New(t)
+
+	// The 'off' segment starts the cycle, and is the rest of the duty cycle.
+	for duty, off_ms := range map[float64]int64{0.25: 750, 0.5: 500, 0.75: 250, 0.9: 100} {
+		var bs blinkState
+		for ms := int64(0); ms < 3000; ms += 10 {
+			assert.Equal(ms%1000 < off_ms, bs.phase(ms, 1000, duty), "duty %v at %vms", duty, ms)
+		}
+	}
+
+	// A duty cycle of 1 is never 'off'.
+	var bs blinkState
+	for ms := int64(0); ms < 3000; ms += 10 {
+		assert.False(bs.phase(ms, 1000, 1))
+	}
+
+	// Fades ramp down and back up in the 'off' segment.
+	assert.Equal(float32(1), blinkFade(0, 1000, 0.5))
+	assert.Equal(float32(0.5), blinkFade(125, 1000, 0.5))
+	assert.Equal(float32(0), blinkFade(250, 1000, 0.5))
+	assert.Equal(float32(0.5), blinkFade(375, 1000, 0.5))
+	assert.Equal(float32(1), blinkFade(500, 1000, 0.5))
+	assert.Equal(float32(1), blinkFade(900, 1000, 0.5))
+	assert.Equal(float32(0), blinkFade(1100, 1000, 0.8))
+	assert.Equal(float32(1), blinkFade(1500, 1000, 1))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	now := time.UnixMilli(0)
+	et.SetClock(func() time.Time { return now })
+	et.init()
+	assert.Equal(0.5, et.blink_duty)
+
+	et.SetBlinkDutyCycle(2, false)
+	assert.Equal(1.0, et.blink_duty)
+	et.SetBlinkDutyCycle(0, false)
+	assert.Equal(0.5, et.blink_duty)
+
+	// Fading blinks are never 'off', but fade.
+	et.SetBlinkDutyCycle(0.5, true)
+	now = time.UnixMilli(225)
+	text, cursor := et.blinkPhases()
+	assert.False(text)
+	assert.False(cursor)
+	text_fade, cursor_fade := et.blinkFades()
+	assert.Equal(float32(0), text_fade)
+	assert.Equal(float32(0.2), cursor_fade)
+
+	green := color.RGBA{G: 200, A: 200}
+	assert.Equal(green, et.blinkTextFade(green, 1))
+	assert.Equal(color.RGBA{G: 100, A: 100}, et.blinkTextFade(green, 0.5))
+	et.SetBlinkMode(BlinkModeColorSwap)
+	assert.Equal(color.RGBA{R: 255, A: 255}, et.blinkTextFade(green, 0))
+}
+
+func TestETCellPanes(t *testing.T) {
+	assert := assert.New(t)
+
+	// Separate ETCells have independent fonts and grids.
+	var small, large ETCell
+	small.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	large.SetFont(&font.CacheFont{Width: 4, Height: 6})
+
+	small.Game().Layout(40, 60)
+	large.Game().Layout(80, 60)
+	large.Game().GeoM.Translate(40, 0)
+
+	cols, rows := small.Screen().Size()
+	assert.Equal(20, cols)
+	assert.Equal(20, rows)
+	cols, rows = large.Screen().Size()
+	assert.Equal(20, cols)
+	assert.Equal(10, rows)
+
+	dst := ebiten.NewImage(120, 60)
+	assert.NotPanics(func() {
+		small.Game().Draw(dst)
+		large.Game().Draw(dst)
+	})
+}
+
+func TestETCellCellBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(8, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Drive a tcell view onto the screen.
+	style := tcell.StyleDefault.Bold(true)
+	text := views.NewText()
+	text.SetView(views.NewViewPort(screen, 0, 0, 8, 2))
+	text.SetStyle(style)
+	text.SetText("ab\ncd")
+	text.Draw()
+
+	cb := screen.CellBuffer()
+	w, h := cb.Size()
+	assert.Equal(8, w)
+	assert.Equal(2, h)
+
+	r, combining, cell_style, width := cb.GetContent(0, 0)
+	assert.Equal('a', r)
+	assert.Empty(combining)
+	assert.Equal(style, cell_style)
+	assert.Equal(1, width)
+	r, _, _, _ = cb.GetContent(1, 1)
+	assert.Equal('d', r)
+	assert.True(cb.Dirty(0, 0))
+
+	screen.Show()
+	screen.SetContent(2, 1, 'e', []rune{'\u0301'}, tcell.StyleDefault)
+
+	cb = screen.CellBuffer()
+	assert.False(cb.Dirty(0, 0))
+	assert.False(cb.Dirty(1, 1))
+	assert.True(cb.Dirty(2, 1))
+	r, combining, _, _ = cb.GetContent(2, 1)
+	assert.Equal('e', r)
+	assert.Equal([]rune{'\u0301'}, combining)
+
+	// The snapshot is independent of the screen.
+	cb.SetContent(0, 0, 'z', nil, style)
+	r, _, _, _ = screen.GetContent(0, 0)
+	assert.Equal('a', r)
+
+	// The snapshot is of the rows scrolled into view.
+	screen.SetBufferRows(6)
+	screen.SetContent(1, 4, 'f', nil, style)
+	screen.SetContent(3, 5, 'g', nil, style)
+	screen.Show()
+	screen.SetContent(4, 5, 'h', nil, style)
+	screen.SetScrollOffset(4)
+
+	cb = screen.CellBuffer()
+	w, h = cb.Size()
+	assert.Equal(8, w)
+	assert.Equal(2, h)
+	r, _, _, _ = cb.GetContent(1, 0)
+	assert.Equal('f', r)
+	r, _, _, _ = cb.GetContent(3, 1)
+	assert.Equal('g', r)
+	r, _, _, _ = cb.GetContent(0, 0)
+	assert.NotEqual('a', r)
+	assert.False(cb.Dirty(1, 0))
+	assert.False(cb.Dirty(3, 1))
+	assert.True(cb.Dirty(4, 1))
+}
+
+func TestETCellWindowToCell(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	game := et.NewGame()
+	game.Layout(8, 9)
+
+	translate := ebiten.GeoM{}
+	translate.Translate(10, 20)
+
+	scale := ebiten.GeoM{}
+	scale.Scale(2, 2)
+	scale.Translate(6, 4)
+
+	rotate := ebiten.GeoM{}
+	rotate.Rotate(math.Pi / 2)
+	rotate.Translate(100, 0)
+
+	for _, entry := range []struct {
+		geom         ebiten.GeoM
+		device_scale float64
+		corner       image.Point // Window position of cell 1, 2.
+	}{
+		{ebiten.GeoM{}, 0, image.Point{2, 6}},
+		{translate, 0, image.Point{12, 26}},
+		{scale, 2, image.Point{5, 8}},
+		{rotate, 0, image.Point{94, 2}},
+	} {
+		game.GeoM = entry.geom
+		game.device_scale = entry.device_scale
+
+		assert.Equal(entry.corner, game.CellToWindow(1, 2))
+
+		// The middle of each cell maps back to the cell.
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 4; x++ {
+				mid := game.CellToWindow(x, y).Add(game.CellToWindow(x+1, y+1)).Div(2)
+				cx, cy, inside := game.WindowToCell(mid.X, mid.Y)
+				assert.True(inside)
+				assert.Equal(x, cx)
+				assert.Equal(y, cy)
+			}
+		}
+
+		// Positions past the grid are outside.
+		past := game.CellToWindow(-1, 1).Add(game.CellToWindow(0, 2)).Div(2)
+		cx, cy, inside := game.WindowToCell(past.X, past.Y)
+		assert.False(inside)
+		assert.Equal(-1, cx)
+		assert.Equal(1, cy)
+	}
+
+	// The final screen transform is accounted for.
+	game.GeoM = ebiten.GeoM{}
+	game.device_scale = 0
+	game.final_geom.Translate(3, 0)
+	assert.Equal(image.Point{5, 6}, game.CellToWindow(1, 2))
+
+	// Non-invertible transforms have no cells.
+	game.GeoM.Scale(0, 0)
+	_, _, inside := game.WindowToCell(0, 0)
+	assert.False(inside)
+}
+
+func TestEnclosureMask(t *testing.T) {
+	assert := assert.New(t)
+
+	// Golden renderings of the synthetic enclosing marks, in a 10x14 cell.
+	golden := map[rune]string{
+		'⃝': `
+..........
+..........
+...####...
+.##....##.
+.#......#.
+#........#
+#........#
+#........#
+#........#
+.#......#.
+.##....##.
+...####...
+..........
+..........
+`,
+		'⃞': `
+##########
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+##########
+`,
+		'⃟': `
+..........
+....##....
+...#..#...
+..##..##..
+..#....#..
+.#......#.
+#........#
+#........#
+.#......#.
+..#....#..
+..##..##..
+...#..#...
+....##....
+..........
+`,
+		'⃠': `
+..........
+..........
+...####...
+.##....##.
+.##.....#.
+#..#.....#
+#...#....#
+#....#...#
+#.....#..#
+.#.....##.
+.##....##.
+...####...
+..........
+..........
+`,
+		'⃣': `
+.########.
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+#........#
+.########.
+`,
+	}
+
+	for r, expect := range golden {
+		mask := enclosureMask(r, 10, 14)
+		assert.NotNil(mask)
+
+		art := "\n"
+		for y := 0; y < 14; y++ {
+			for x := 0; x < 10; x++ {
+				if mask.AlphaAt(x, y).A == 0xff {
+					art += "#"
+				} else {
+					art += "."
+				}
+			}
+			art += "\n"
+		}
+		assert.Equal(expect, art, "%U", r)
+	}
+
+	// Other marks are left to the font.
+	assert.Nil(enclosureMask('\u0301', 10, 14))
+}
+
+func TestETCellEnclosingMarks(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 10, Height: 14})
+	et.SetScreenSize(4, 1)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// A keycap, with an emoji presentation selector.
+	keycap := []rune{'\ufe0f', '⃣'}
+	screen.SetContent(0, 0, '1', keycap, tcell.StyleDefault)
+	screen.SetContent(1, 0, '1', keycap, tcell.StyleDefault)
+	screen.SetContent(2, 0, '2', []rune{'⃝'}, tcell.StyleDefault)
+	screen.Show()
+
+	screen.grid_lock.Lock()
+	defer screen.grid_lock.Unlock()
+
+	// The composed clusters are cached.
+	assert.Equal(2, screen.cluster_cache.len())
+	assert.Len(screen.enclosure_cache, 2)
+	assert.Same(screen.grid[0].glyph, screen.grid[1].glyph)
+	assert.NotSame(screen.grid[0].glyph, screen.grid[2].glyph)
+	assert.Equal(image.Point{10, 14}, screen.grid[0].glyph.Bounds().Size())
+
+	// Changing the font drops them.
+	screen.forget()
+	assert.Equal(0, screen.cluster_cache.len())
+	assert.Nil(screen.enclosure_cache)
+}
+
+func TestETCellHideCursorWhenUnfocused(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	focused := false
+	et.window_focused = func() bool { return focused }
+
+	game := et.Game()
+	game.Layout(8, 9)
+	et.Screen().ShowCursor(1, 1)
+
+	dst := ebiten.NewImage(8, 9)
+
+	// By default, the cursor is drawn when unfocused.
+	assert.False(game.cursorHidden())
+	assert.NotPanics(func() { game.Draw(dst) })
+
+	et.SetHideCursorWhenUnfocused(true)
+	assert.True(game.cursorHidden())
+	assert.NotPanics(func() { game.Draw(dst) })
+	assert.True(game.drawState(dst, false, false).cursor_hidden)
+
+	focused = true
+	assert.False(game.cursorHidden())
+	assert.False(game.drawState(dst, false, false).cursor_hidden)
+
+	et.SetHideCursorWhenUnfocused(false)
+	focused = false
+	assert.False(game.cursorHidden())
+}
+
+func TestETCellCursorVisible(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	game := et.Game()
+	game.Layout(8, 9)
+	screen := et.Screen()
+	screen.ShowCursor(1, 1)
+
+	dst := ebiten.NewImage(8, 9)
+
+	assert.True(screen.CursorVisible())
+	assert.False(game.cursorHidden())
+
+	// Hiding keeps the position.
+	screen.SetCursorVisible(false)
+	assert.False(screen.CursorVisible())
+	assert.True(game.cursorHidden())
+	assert.Equal(image.Point{X: 1, Y: 1}, et.cursor)
+	assert.True(game.drawState(dst, false, false).cursor_hidden)
+	assert.NotPanics(func() { game.Draw(dst) })
+
+	// Moving does not make it visible.
+	screen.ShowCursor(2, 1)
+	assert.True(game.cursorHidden())
+	assert.Equal(image.Point{X: 2, Y: 1}, et.cursor)
+
+	screen.SetCursorVisible(true)
+	assert.False(game.cursorHidden())
+	assert.Equal(image.Point{X: 2, Y: 1}, et.cursor)
+
+	// Making it visible does not move it back on the screen.
+	screen.HideCursor()
+	screen.SetCursorVisible(true)
+	assert.True(screen.CursorVisible())
+	assert.Equal(image.Point{X: -1, Y: -1}, et.cursor)
+}
+
+func TestETCellSupportsBlend(t *testing.T) {
+	assert := assert.New(t)
+
+	game := (&ETCell{}).NewGame()
+
+	for _, blend := range []ebiten.Blend{ebiten.BlendSourceOver, ebiten.BlendClear, cursor_blend, BlendMax, {}} {
+		assert.True(game.SupportsBlend(blend))
+	}
+
+	assert.False(game.SupportsBlend(ebiten.Blend{BlendOperationRGB: ebiten.BlendOperationMax + 1}))
+	assert.False(game.SupportsBlend(ebiten.Blend{BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusDestinationAlpha + 1}))
+}
+
+func TestETCellGridOverlay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]image.Rectangle{
+		image.Rect(0, 0, 1, 6),
+		image.Rect(2, 0, 3, 6),
+		image.Rect(3, 0, 4, 6),
+		image.Rect(0, 0, 4, 1),
+		image.Rect(0, 3, 4, 4),
+		image.Rect(0, 5, 4, 6),
+	}, gridLines(image.Point{4, 6}, image.Point{2, 3}))
+	assert.Empty(gridLines(image.Point{4, 6}, image.Point{}))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	game := et.NewGame()
+	game.Layout(8, 9)
+	dst := ebiten.NewImage(8, 9)
+
+	// Off by default.
+	assert.Nil(game.grid_overlay)
+	assert.Equal(color.RGBA{}, game.drawState(dst, false, false).grid_overlay)
+
+	game.SetGridOverlay(true, color.RGBA{0xff, 0, 0, 0xff})
+	assert.Equal(color.RGBA{0xff, 0, 0, 0xff}, game.drawState(dst, false, false).grid_overlay)
+	game.GeoM.Rotate(math.Pi / 4)
+	assert.NotPanics(func() { game.Draw(dst) })
+
+	game.SetGridOverlay(true, nil)
+	assert.NotNil(game.grid_overlay)
+
+	game.SetGridOverlay(false, color.White)
+	assert.Nil(game.grid_overlay)
+}
+
+func TestETCellSetFontNil(t *testing.T) {
+	assert := assert.New(t)
+
+	def, err := font.NewMonoFontFromTTF(nil, 0)
+	assert.Nil(err)
+	def_width, def_height := def.Size()
+
+	// A nil face is the default font.
+	et := &ETCell{}
+	assert.NotPanics(func() { et.SetFont(nil) })
+	width, height, _ := et.Metrics()
+	assert.Equal(def_width, width)
+	assert.Equal(def_height, height)
+
+	// A nil face resets a custom font to the default.
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	assert.Nil(et.SetFontChecked(nil))
+	width, height, _ = et.Metrics()
+	assert.Equal(def_width, width)
+	assert.Equal(def_height, height)
+
+	// A game without a font set draws with the default.
+	et = &ETCell{}
+	game := et.Game()
+	assert.NotPanics(func() {
+		game.Layout(80, 60)
+		game.Draw(ebiten.NewImage(80, 60))
+	})
+	width, height, _ = et.Metrics()
+	assert.Equal(def_width, width)
+	assert.Equal(def_height, height)
+}
+
+func TestETCellAttrEnabled(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 1)
+
+	// Record the font style glyphs are requested in.
+	styles := map[rune]font.FontStyle{}
+	et.SetGlyphProvider(func(r rune, style font.FontStyle, fg, bg color.RGBA) (*ebiten.Image, bool) {
+		styles[r] = style
+		return nil, false
+	})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	italic := tcell.StyleDefault.Italic(true)
+	bold_italic := italic.Bold(true).Underline(true)
+
+	screen.SetContent(0, 0, 'a', nil, italic)
+	screen.SetContent(1, 0, 'b', nil, bold_italic)
+	screen.Show()
+	assert.Equal(font.FontStyleItalic, styles['a'])
+	assert.Equal(font.FontStyleBoldItalic, styles['b'])
+
+	// Disabling italic yields normal style glyphs.
+	et.SetAttrEnabled(tcell.AttrItalic, false)
+	screen.Sync()
+	assert.Equal(font.FontStyleNormal, styles['a'])
+	assert.Equal(font.FontStyleBold, styles['b'])
+	assert.Equal(tcell.AttrBold|tcell.AttrUnderline, screen.grid[1].attr)
+
+	// Attributes of the default style are also disabled.
+	et.SetAttrEnabled(tcell.AttrUnderline, false)
+	screen.SetStyle(tcell.StyleDefault.Italic(true).Underline(true))
+	screen.SetContent(2, 0, 'c', nil, tcell.StyleDefault)
+	screen.Sync()
+	assert.Equal(font.FontStyleNormal, styles['c'])
+	assert.Equal(tcell.AttrNone, screen.grid[2].attr)
+	assert.Equal(tcell.AttrBold, screen.grid[1].attr)
+
+	// Enabled again.
+	et.SetAttrEnabled(tcell.AttrItalic|tcell.AttrUnderline, true)
+	screen.Sync()
+	assert.Equal(font.FontStyleItalic, styles['a'])
+	assert.Equal(font.FontStyleBoldItalic, styles['b'])
+	assert.Equal(font.FontStyleItalic, styles['c'])
+}
+
+func TestETCellDumpHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(6, 3)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	red := tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true)
+	screen.Print(0, 0, "ab", red)
+	screen.Print(2, 0, "c", red)
+	screen.Print(3, 0, "<&>", tcell.StyleDefault)
+	screen.Print(0, 1, "日é", tcell.StyleDefault.Italic(true).Underline(true))
+	screen.Print(3, 1, "x", tcell.StyleDefault.Url("https://example.com/?a&b"))
+	screen.Print(4, 1, "y", tcell.StyleDefault.Reverse(true))
+	screen.SetContent(0, 2, 0, nil, tcell.StyleDefault)
+
+	var sb strings.Builder
+	assert.Nil(screen.DumpHTML(&sb))
+	assert.Equal(`<pre style="color:#ffffff;background-color:#000000">`+
+		`<span style="color:#ff0000;font-weight:bold">abc</span>&lt;&amp;&gt;`+"\n"+
+		`<span style="font-style:italic;text-decoration:underline">日é</span>`+
+		`<a href="https://example.com/?a&amp;b" style="text-decoration:underline">x</a>`+
+		`<span style="color:#000000;background-color:#ffffff">y</span> `+"\n"+
+		`      </pre>`+"\n", sb.String())
+
+	// Default colors follow the default style.
+	screen.SetStyle(tcell.StyleDefault.Background(tcell.ColorBlue))
+	screen.Clear()
+	screen.Print(0, 0, "z", tcell.StyleDefault.Background(tcell.ColorBlack))
+	sb.Reset()
+	assert.Nil(screen.DumpHTML(&sb))
+	assert.Equal(`<pre style="color:#ffffff;background-color:#0000ff">`+
+		`<span style="background-color:#000000">z</span>     `+"\n"+
+		`      `+"\n"+
+		`      </pre>`+"\n", sb.String())
+
+	// Only hyperlinks of safe schemes are links.
+	screen.SetStyle(tcell.StyleDefault)
+	screen.Clear()
+	screen.Print(0, 0, "a", tcell.StyleDefault.Url("javascript:alert(1)"))
+	screen.Print(1, 0, "b", tcell.StyleDefault.Url("DATA:text/html,x"))
+	screen.Print(2, 0, "c", tcell.StyleDefault.Url("MailTo:me@example.com"))
+	screen.Print(3, 0, "d", tcell.StyleDefault.Url("file:///tmp/x"))
+	screen.Print(4, 0, "e", tcell.StyleDefault.Url("/relative"))
+	sb.Reset()
+	assert.Nil(screen.DumpHTML(&sb))
+	assert.Equal(`<pre style="color:#ffffff;background-color:#000000">`+
+		`<span style="text-decoration:underline">a</span>`+
+		`<span style="text-decoration:underline">b</span>`+
+		`<a href="MailTo:me@example.com" style="text-decoration:underline">c</a>`+
+		`<a href="file:///tmp/x" style="text-decoration:underline">d</a>`+
+		`<span style="text-decoration:underline">e</span> `+"\n"+
+		`      `+"\n"+
+		`      </pre>`+"\n", sb.String())
+
+	assert.True(isSafeURL("https://example.com"))
+	assert.False(isSafeURL("java\x00script:x"))
+	assert.False(isSafeURL(""))
+}
+
+func TestETCellRuneWidthFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	// U+25CB WHITE CIRCLE and U+00B7 MIDDLE DOT are of ambiguous width.
+	assert.Equal(1, DefaultRuneWidth('○'))
+	assert.Equal(1, DefaultRuneWidth('·'))
+	assert.Equal(2, DefaultRuneWidth('日'))
+	assert.Equal(0, DefaultRuneWidth('\u0301'))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(6, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// By default, ambiguous width runes are narrow.
+	nx, ny := screen.Print(0, 0, "○·日", tcell.StyleDefault)
+	assert.Equal(4, nx)
+	assert.Equal(0, ny)
+	_, _, _, width := screen.GetContent(0, 0)
+	assert.Equal(1, width)
+	_, _, _, width = screen.GetContent(2, 0)
+	assert.Equal(2, width)
+
+	// Treat ambiguous width runes as wide.
+	ambiguous := map[rune]bool{'○': true, '·': true}
+	et.SetRuneWidthFunc(func(r rune) int {
+		if ambiguous[r] {
+			return 2
+		}
+		return DefaultRuneWidth(r)
+	})
+
+	nx, ny = screen.CursorAdvance(0, 0, "○·日")
+	assert.Equal(0, nx)
+	assert.Equal(1, ny)
+
+	screen.Clear()
+	nx, ny = screen.Print(0, 0, "○·e\u0301", tcell.StyleDefault)
+	assert.Equal(5, nx)
+	assert.Equal(0, ny)
+	_, _, _, width = screen.GetContent(0, 0)
+	assert.Equal(2, width)
+	r, _, _, _ := screen.GetContent(2, 0)
+	assert.Equal('·', r)
+	r, combining, _, width := screen.GetContent(4, 0)
+	assert.Equal('e', r)
+	assert.Equal([]rune{'\u0301'}, combining)
+	assert.Equal(1, width)
+
+	// Widths are clamped.
+	et.SetRuneWidthFunc(func(r rune) int { return 5 })
+	_, _, _, width = screen.GetContent(0, 0)
+	assert.Equal(2, width)
+
+	// Restore the default.
+	et.SetRuneWidthFunc(nil)
+	_, _, _, width = screen.GetContent(0, 0)
+	assert.Equal(1, width)
+}
+
+func TestETCellFreeze(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	game := et.Game()
+	game.SetFrame(1, color.White)
+	game.Layout(10, 11)
+	game.GeoM.Translate(5, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	screen.Show()
+
+	dst := ebiten.NewImage(20, 20)
+
+	// The frozen image is of the layout and frame.
+	frozen := game.Freeze()
+	assert.Equal(image.Point{10, 11}, frozen.Bounds().Size())
+	assert.Equal('a', game.grid_draw[0].Rune)
+
+	// The grid is updated, but not rendered, while frozen.
+	screen.SetContent(0, 0, 'b', nil, tcell.StyleDefault)
+	screen.Show()
+	game.Draw(dst)
+	assert.Equal('a', game.grid_draw[0].Rune)
+	r, _, _, _ := screen.GetContent(0, 0)
+	assert.Equal('b', r)
+
+	// Freezing again renders the grid again.
+	again := game.Freeze()
+	assert.NotSame(frozen, again)
+	assert.Equal('b', game.grid_draw[0].Rune)
+
+	screen.SetContent(0, 0, 'c', nil, tcell.StyleDefault)
+	screen.Show()
+	game.Unfreeze()
+	assert.Nil(game.frozen)
+	game.Draw(dst)
+	assert.Equal('c', game.grid_draw[0].Rune)
+
+	assert.NotPanics(game.Unfreeze)
+}
+
+func TestETCellOnCloseRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	closing := false
+	et.window_closing = func() bool { return closing }
+
+	game := et.Game()
+	game.Layout(8, 9)
+
+	// No close request.
+	requests := 0
+	confirm := false
+	et.OnCloseRequest(func() bool {
+		requests++
+		return confirm
+	})
+	assert.False(game.closeRequested())
+	assert.Equal(0, requests)
+
+	// The close is cancelled.
+	closing = true
+	assert.False(game.closeRequested())
+	assert.Nil(game.Update())
+	assert.Equal(2, requests)
+
+	// The close is confirmed.
+	confirm = true
+	assert.Equal(ebiten.Termination, game.Update())
+	assert.Equal(3, requests)
+
+	// Without a callback, the window closes.
+	et.OnCloseRequest(nil)
+	assert.True(game.closeRequested())
+}
+
+func TestETCellLineShaping(t *testing.T) {
+	assert := assert.New(t)
+
+	face, err := font.NewMonoFont(nil)
+	assert.Nil(err)
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(5, 2)
+	et.SetBoxDrawing(true, false)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Glyph by glyph, by default.
+	screen.SetContent(0, 0, 'f', nil, tcell.StyleDefault)
+	screen.Show()
+	glyph, _ := face.Glyph('f', font.FontStyleNormal)
+	assert.Same(glyph, et.grid[0].glyph)
+
+	et.SetLineShaping(true)
+	screen.SetContent(1, 0, 'i', nil, tcell.StyleDefault)
+	screen.SetContent(2, 0, '─', nil, tcell.StyleDefault)
+	screen.SetContent(3, 0, 'e', []rune{'\u0301'}, tcell.StyleDefault.Bold(true))
+	screen.Show()
+	assert.NotSame(glyph, et.grid[0].glyph)
+	assert.True(et.grid[0].face_glyph)
+	assert.True(et.grid[3].face_glyph)
+	assert.True(et.grid[4].face_glyph)
+
+	// Synthetic glyphs are not shaped.
+	assert.False(et.grid[2].face_glyph)
+	assert.Same(et.boxGlyph(box_arms['─']), et.grid[2].glyph)
+
+	// The row is shaped again when any of its cells changes.
+	shaped := et.grid[1].glyph
+	other := et.grid[5].glyph
+	screen.SetContent(0, 0, 'g', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.NotSame(shaped, et.grid[1].glyph)
+	assert.Same(other, et.grid[5].glyph)
+
+	// Shaped runs are cached, so runs that are shaped again, or repeated
+	// on other rows, keep their glyphs, and so their cell cache entries.
+	screen.SetContent(0, 0, 'f', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Same(shaped, et.grid[1].glyph)
+	screen.SetContent(0, 1, 'f', nil, tcell.StyleDefault)
+	screen.SetContent(1, 1, 'i', nil, tcell.StyleDefault)
+	screen.SetContent(2, 1, '─', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Same(shaped, et.grid[6].glyph)
+
+	// Until the glyphs are forgotten.
+	et.SetLineShaping(true)
+	screen.Sync()
+	assert.NotSame(shaped, et.grid[1].glyph)
+
+	// The least recently used runs are evicted one at a time, and their
+	// glyphs are released once no cell uses them.
+	et.grid_lock.Lock()
+	et.releaseGlyphs()
+	runs := et.shaped_cache.len()
+	et.shaped_cache.entries = runs
+	et.grid_lock.Unlock()
+
+	screen.SetContent(0, 1, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(1, 1, 'y', nil, tcell.StyleDefault)
+	screen.Show()
+
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+	assert.Equal(runs, et.shaped_cache.len())
+	assert.NotEmpty(et.released)
+	et.releaseGlyphs()
+	used := map[*ebiten.Image]bool{}
+	for n := range et.grid {
+		used[et.grid[n].glyph] = true
+	}
+	for _, glyph := range et.released {
+		assert.True(used[glyph])
+	}
+}
+
+// deuteranopia simulates deuteranopia, with the matrix of Machado et al.
+func deuteranopia(c color.RGBA) color.RGBA {
+	channel := func(r, g, b float64) uint8 {
+		return uint8(math.Round(max(0, min(255, r*float64(c.R)+g*float64(c.G)+b*float64(c.B)))))
+	}
+
+	return color.RGBA{
+		R: channel(0.367, 0.861, -0.228),
+		G: channel(0.280, 0.673, 0.047),
+		B: channel(-0.012, 0.043, 0.969),
+		A: c.A,
+	}
+}
+
+func TestETCellColorFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	red := tcell.NewRGBColor(0xff, 0, 0)
+	dark_red := tcell.NewRGBColor(0x40, 0, 0)
+
+	// Colors are unchanged by default.
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault.Foreground(red))
+	screen.Show()
+	assert.Equal(color.RGBA{0xff, 0, 0, 0xff}, et.grid[0].fgColor)
+
+	et.SetColorFilter(deuteranopia)
+	screen.Sync()
+	assert.Equal(color.RGBA{94, 71, 0, 0xff}, et.grid[0].fgColor)
+	assert.Equal(color.RGBA{0, 0, 0, 0xff}, et.grid[0].bgColor)
+
+	// The filter is applied after bold intensifies the color.
+	screen.SetContent(1, 0, 'x', nil, tcell.StyleDefault.Foreground(dark_red).Background(red).Bold(true))
+	screen.Show()
+	assert.Equal(deuteranopia(color.RGBA{0x80, 0, 0, 0xff}), et.grid[1].fgColor)
+	assert.Equal(color.RGBA{94, 71, 0, 0xff}, et.grid[1].bgColor)
+
+	var html strings.Builder
+	assert.NoError(screen.DumpHTML(&html))
+	assert.Contains(html.String(), "color:#5e4700")
+
+	et.SetColorFilter(nil)
+	screen.Sync()
+	assert.Equal(color.RGBA{0xff, 0, 0, 0xff}, et.grid[0].fgColor)
+}
+
+// TestETCellHasPendingEventShutdown is meaningful with -race.
+func TestETCellHasPendingEventShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	screen := et.Screen()
+	assert.False(screen.HasPendingEvent())
+
+	for range 10 {
+		screen.Init()
+
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				screen.HasPendingEvent()
+				screen.PostEvent(tcell.NewEventInterrupt(nil))
+				runtime.Gosched()
+			}
+		}()
+
+		time.Sleep(time.Millisecond)
+		screen.Fini()
+		close(done)
+		<-stopped
+
+		assert.False(screen.HasPendingEvent())
+	}
+}
+
+func TestETCellPollEventTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	screen := et.Screen()
+	screen.Init()
+
+	// Times out with no events.
+	start := time.Now()
+	assert.Nil(screen.PollEventTimeout(20 * time.Millisecond))
+	assert.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+
+	// Pending events are returned at once.
+	interrupt := tcell.NewEventInterrupt(nil)
+	screen.PostEvent(interrupt)
+	assert.Same(interrupt, screen.PollEventTimeout(time.Hour))
+
+	// Events arriving while waiting are returned.
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		screen.PostEvent(interrupt)
+	}()
+	assert.Same(interrupt, screen.PollEventTimeout(time.Hour))
+
+	// A finalized screen returns nil.
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		screen.Fini()
+	}()
+	assert.Nil(screen.PollEventTimeout(time.Hour))
+}
+
+func TestETCellOnBeep(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	screen := et.Screen()
+
+	// Silent by default.
+	assert.NoError(screen.Beep())
+
+	var beeps int
+	failure := errors.New("no audio")
+	et.OnBeep(func() error {
+		beeps++
+		return failure
+	})
+	assert.Same(failure, screen.Beep())
+	assert.Equal(1, beeps)
+
+	et.OnBeep(nil)
+	assert.NoError(screen.Beep())
+	assert.Equal(1, beeps)
+}
+
+func TestETCellSaveState(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	link := tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.NewRGBColor(1, 2, 3)).Bold(true).Url("https://example.com").UrlId("ex")
+	screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'e', []rune{'\u0301', '\u20dd'}, link)
+	screen.SetContent(3, 1, '世', nil, tcell.StyleDefault.Italic(true))
+	screen.Show()
+
+	state := screen.SaveState()
+	assert.Equal("ETCS\x01", string(state[:5]))
+	var want []BufferCell
+	for _, c := range et.grid {
+		want = append(want, BufferCell{Rune: c.Rune, Combining: c.Combining, Style: c.Style})
+	}
+
+	screen.Clear()
+	screen.SetContent(2, 1, 'z', nil, tcell.StyleDefault)
+	assert.NoError(screen.RestoreState(state))
+
+	for n, c := range et.grid {
+		assert.Equal(want[n], BufferCell{Rune: c.Rune, Combining: c.Combining, Style: c.Style})
+	}
+	assert.Equal(link, et.grid[1].Style)
+
+	// Round trips.
+	assert.Equal(state, screen.SaveState())
+
+	// Restoring to a smaller grid clips.
+	et.SetScreenSize(2, 1)
+	assert.NoError(screen.RestoreState(state))
+	assert.Equal('e', et.grid[1].Rune)
+
+	// Invalid states are rejected, without changing the screen.
+	screen.SetContent(0, 0, 'q', nil, tcell.StyleDefault)
+	for _, bad := range [][]byte{
+		nil,
+		[]byte("ETCS"),
+		[]byte("ETCS\x02"),
+		[]byte("XXXX\x01"),
+		state[:len(state)-1],
+		append(append([]byte{}, state...), 0),
+		[]byte("ETCS\x01\xff\xff\xff\xff\x0f\xff\xff\xff\xff\x0f\x00"),
+		[]byte("ETCS\x01\x01\x01\x00\x41\x00\x00"),
+	} {
+		err := screen.RestoreState(bad)
+		assert.ErrorIs(err, ErrState, "%q", bad)
+	}
+	assert.Equal('q', et.grid[0].Rune)
+}
+
+func TestETCellResizeCoalesced(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	var resizes int
+	et.OnResize(func(cols, rows int) { resizes++ })
+
+	// Identical sizes keep the content, and post nothing.
+	screen.SetContent(1, 1, 'x', nil, tcell.StyleDefault)
+	for range 1000 {
+		et.SetScreenSize(4, 2)
+	}
+	assert.Equal('x', et.grid[1*4+1].Rune)
+	assert.False(screen.HasPendingEvent())
+	assert.Equal(0, resizes)
+
+	// Changing sizes post a single event, of the latest size.
+	for n := range 1000 {
+		et.SetScreenSize(5+n%7, 3+n%5)
+	}
+	et.SetScreenSize(20, 10)
+	assert.Equal(1000+1, resizes)
+
+	ev, ok := screen.PollEvent().(*tcell.EventResize)
+	assert.True(ok)
+	cols, rows := ev.Size()
+	assert.Equal(20, cols)
+	assert.Equal(10, rows)
+	assert.False(screen.HasPendingEvent())
+
+	// Once received, the next change posts again.
+	et.SetScreenSize(8, 4)
+	ev, ok = screen.PollEventTimeout(time.Second).(*tcell.EventResize)
+	assert.True(ok)
+	cols, rows = ev.Size()
+	assert.Equal(8, cols)
+	assert.Equal(4, rows)
+
+	// Resize events posted by the application are not coalesced.
+	screen.PostEvent(tcell.NewEventResize(1, 1))
+	et.SetScreenSize(9, 4)
+	ev, _ = screen.PollEvent().(*tcell.EventResize)
+	cols, _ = ev.Size()
+	assert.Equal(1, cols)
+	ev, _ = screen.PollEvent().(*tcell.EventResize)
+	cols, _ = ev.Size()
+	assert.Equal(9, cols)
+}
+
+func TestETCellDeviceScale(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	var scales []float64
+	et.OnScaleChange(func(scale float64) {
+		// Called unlocked, so the screen can be used.
+		et.SetFont(&font.CacheFont{Width: 4, Height: 6})
+		scales = append(scales, scale)
+	})
+
+	game := et.NewGame()
+	game.SetFrame(1, nil)
+
+	// Window sizes are in device independent pixels, rounded up.
+	assert.Equal(image.Point{X: 22, Y: 32}, game.windowSizeAt(1, 10, 10))
+	assert.Equal(image.Point{X: 11, Y: 16}, game.windowSizeAt(2, 10, 10))
+	assert.Equal(image.Point{X: 15, Y: 22}, game.windowSizeAt(1.5, 10, 10))
+	assert.Equal(image.Point{X: 2, Y: 2}, game.windowSizeAt(1, -1, 0))
+
+	// The first layout, and unchanged scales, do not notify.
+	game.setDeviceScale(1)
+	game.setDeviceScale(1)
+	assert.Empty(scales)
+
+	game.setDeviceScale(2)
+	assert.Equal([]float64{2}, scales)
+	assert.Equal(2.0, game.device_scale)
+	cell_width, cell_height, _ := et.Metrics()
+	assert.Equal(4, cell_width)
+	assert.Equal(6, cell_height)
+
+	game.setDeviceScale(2)
+	game.setDeviceScale(1.5)
+	assert.Equal([]float64{2, 1.5}, scales)
+
+	assert.Equal(1.0, deviceScale(nil))
+}
+
+func TestETCellBlinkingDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.UnixMilli(0)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetClock(func() time.Time { return now })
+	et.SetRedrawOnEvent(true)
+	et.SetBlinkingEnabled(false)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault.Blink(true))
+	screen.SetCursorStyle(tcell.CursorStyleBlinkingBlock)
+	screen.ShowCursor(1, 0)
+	screen.Show()
+
+	game := et.Game()
+	dst := ebiten.NewImage(et.GetGameSize())
+	redrawn := func() bool {
+		game.grid_draw = nil
+		game.Draw(dst)
+		return game.grid_draw != nil
+	}
+	assert.True(redrawn())
+
+	// Nothing blinks, so frames over the blink cycles are not redrawn.
+	for _, fade := range []bool{false, true} {
+		et.SetBlinkDutyCycle(0.5, fade)
+		for ms := int64(0); ms < 2000; ms += 25 {
+			now = time.UnixMilli(ms)
+			text, cursor := et.blinkPhases()
+			assert.False(text, "text at %vms", ms)
+			assert.False(cursor, "cursor at %vms", ms)
+			text_fade, cursor_fade := et.blinkFades()
+			assert.Equal(float32(1), text_fade)
+			assert.Equal(float32(1), cursor_fade)
+			if ms > 0 {
+				assert.False(redrawn(), "at %vms", ms)
+			}
+		}
+	}
+
+	// Enabled again, blinks resume.
+	et.SetBlinkDutyCycle(0.5, false)
+	et.SetBlinkingEnabled(true)
+	var phases int
+	for ms := int64(0); ms < 2000; ms += 25 {
+		now = time.UnixMilli(ms)
+		text, cursor := et.blinkPhases()
+		if text || cursor {
+			phases++
+		}
+	}
+	assert.NotZero(phases)
+}
+
+func TestETCellTraceFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	counts := map[string]int{}
+	et.SetTraceFunc(func(event string, d time.Duration) {
+		assert.GreaterOrEqual(d, time.Duration(0))
+		counts[event]++
+	})
+
+	screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'y', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Equal(1, counts[TraceShow])
+	assert.NotZero(counts[TraceGlyph])
+
+	game := et.Game()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+	assert.Equal(1, counts[TraceGridCopy])
+	assert.Equal(1, counts[TraceDraw])
+
+	// Unset, nothing is traced.
+	traced := maps.Clone(counts)
+	et.SetTraceFunc(nil)
+	screen.SetContent(2, 0, 'z', nil, tcell.StyleDefault)
+	screen.Show()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+	assert.Equal(traced, counts)
+}
+
+func TestETCellBackgroundPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	et.SetCellCache(16)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	gradient := ebiten.NewImage(1, 8)
+	for y := range 8 {
+		gradient.Set(0, y, color.Gray{Y: uint8(y * 16)})
+	}
+
+	// The bottom row is a status bar, with a gradient.
+	var calls []image.Point
+	et.SetBackgroundPattern(func(x, y int) *ebiten.Image {
+		calls = append(calls, image.Point{X: x, Y: y})
+		if y == 1 {
+			return gradient
+		}
+		return nil
+	})
+
+	screen.SetContent(0, 1, 'x', nil, tcell.StyleDefault)
+	screen.Show()
+
+	game := et.Game()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+
+	// Every cell is looked up, bypassing the cell cache.
+	assert.Len(calls, 8)
+	assert.Contains(calls, image.Point{X: 0, Y: 1})
+	assert.Contains(calls, image.Point{X: 3, Y: 0})
+	assert.Zero(game.cell_cache.lru.Len())
+
+	// Without a pattern, nothing is looked up.
+	calls = nil
+	et.SetBackgroundPattern(nil)
+	screen.Sync()
+	game.Draw(ebiten.NewImage(et.GetGameSize()))
+	assert.Empty(calls)
+	assert.NotZero(game.cell_cache.lru.Len())
+}
+
+func TestETCellCanDisplayString(t *testing.T) {
+	assert := assert.New(t)
+
+	face := &font.CacheFont{Width: 2, Height: 3}
+	for _, r := range "abé\u0301" {
+		face.SetGlyph(r, ebiten.NewImage(2, 3))
+	}
+
+	et := &ETCell{}
+	et.SetFont(face)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	ok, missing := screen.CanDisplayString("")
+	assert.True(ok)
+	assert.Empty(missing)
+
+	// Spaces, controls and format runes draw nothing.
+	ok, missing = screen.CanDisplayString("ab é\u0301\t\n\u200d")
+	assert.True(ok)
+	assert.Empty(missing)
+
+	// Missing runes, and combining marks, are listed once each.
+	ok, missing = screen.CanDisplayString("abxcba\u0302x\u0301x")
+	assert.False(ok)
+	assert.Equal([]rune{'x', 'c', '\u0302'}, missing)
+
+	// Fallbacks are considered.
+	screen.RegisterRuneFallback('c', "a")
+	ok, missing = screen.CanDisplayString("abxcba\u0302x\u0301x")
+	assert.False(ok)
+	assert.Equal([]rune{'x', '\u0302'}, missing)
+	screen.RegisterRuneFallback('x', "b")
+	screen.RegisterRuneFallback('\u0302', "\u0301")
+	ok, missing = screen.CanDisplayString("abxcba\u0302x\u0301x")
+	assert.True(ok)
+	assert.Empty(missing)
+}
+
+func TestETCellEscapeKey(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Escape is posted immediately, by default; the delay is ignored.
+	et.SetEscapeDelay(time.Second)
+	ev := et.keyEvent(tcell.KeyEscape, tcell.ModAlt)
+	if assert.NotNil(ev) {
+		assert.Equal(tcell.KeyEscape, ev.Key())
+		assert.Equal(tcell.ModAlt, ev.Modifiers())
+	}
+
+	// Suppressed, only Escape is not posted.
+	et.SetEscapeKey(false)
+	assert.Nil(et.keyEvent(tcell.KeyEscape, tcell.ModNone))
+	ev = et.keyEvent(tcell.KeyF1, tcell.ModNone)
+	if assert.NotNil(ev) {
+		assert.Equal(tcell.KeyF1, ev.Key())
+	}
+	ev = et.keyEvent(tcell.KeyEnter, tcell.ModNone)
+	if assert.NotNil(ev) {
+		assert.Equal(tcell.KeyEnter, ev.Key())
+	}
+
+	// Pasted Escape is still posted.
+	screen.EnablePaste()
+	et.Paste("\x1b")
+	assert.IsType(&tcell.EventPaste{}, screen.PollEvent())
+	key, ok := screen.PollEvent().(*tcell.EventKey)
+	if assert.True(ok) {
+		assert.Equal(tcell.KeyEscape, key.Key())
+	}
+
+	et.SetEscapeKey(true)
+	assert.NotNil(et.keyEvent(tcell.KeyEscape, tcell.ModNone))
+}
+
+func TestETCellRenderTarget(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	game := et.Game()
+	game.SetFrame(1, color.White)
+	game.Layout(10, 11)
+	game.GeoM.Translate(5, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	screen.Show()
+
+	// The target is of the layout and frame, rendered without Draw.
+	target := game.RenderTarget()
+	assert.Equal(image.Point{10, 11}, target.Bounds().Size())
+	assert.Equal('a', game.grid_draw[0].Rune)
+
+	// The target is reused, and rendered again on each call.
+	screen.SetContent(0, 0, 'b', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.Same(target, game.RenderTarget())
+	assert.Equal('b', game.grid_draw[0].Rune)
+
+	// A new layout size makes a new target.
+	game.Layout(14, 11)
+	resized := game.RenderTarget()
+	assert.NotSame(target, resized)
+	assert.Equal(image.Point{14, 11}, resized.Bounds().Size())
+
+	// Rendering the target does not freeze Draw.
+	screen.SetContent(0, 0, 'c', nil, tcell.StyleDefault)
+	screen.Show()
+	game.Draw(ebiten.NewImage(20, 20))
+	assert.Equal('c', game.grid_draw[0].Rune)
+}
+
+func TestETCellSuspendInputHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.window_closing = func() bool { return false }
+
+	game := et.Game()
+	game.Layout(8, 9)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Resumed, events are posted, and the handler is not called.
+	calls := 0
+	et.SetSuspendInputHandler(func() {
+		calls++
+		if calls == 3 {
+			assert.NoError(screen.Resume())
+		}
+	})
+	assert.NoError(game.Update())
+	assert.True(screen.HasPendingEvent())
+	assert.Equal(0, calls)
+	for screen.HasPendingEvent() {
+		screen.PollEvent()
+	}
+
+	// Suspended, the handler is called instead, and may resume.
+	assert.NoError(screen.Suspend())
+	for range 3 {
+		assert.NoError(game.Update())
+		assert.False(screen.HasPendingEvent())
+	}
+	assert.Equal(3, calls)
+
+	assert.NoError(game.Update())
+	assert.True(screen.HasPendingEvent())
+	assert.Equal(3, calls)
+
+	// Without a handler, nothing happens while suspended.
+	for screen.HasPendingEvent() {
+		screen.PollEvent()
+	}
+	et.SetSuspendInputHandler(nil)
+	assert.NoError(screen.Suspend())
+	assert.NoError(game.Update())
+	assert.False(screen.HasPendingEvent())
+	assert.Equal(3, calls)
+}
+
+func TestETCellCursorWidth(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, '世', nil, tcell.StyleDefault)
+	screen.SetContent(2, 0, 'x', nil, tcell.StyleDefault)
+	screen.SetContent(3, 1, '界', nil, tcell.StyleDefault)
+	screen.Show()
+
+	// The block spans both cells of a wide rune.
+	screen.ShowCursor(0, 0)
+	assert.Equal(2, et.cursorWidth())
+	screen.ShowCursor(2, 0)
+	assert.Equal(1, et.cursorWidth())
+
+	// Wide runes are clipped to the grid.
+	screen.ShowCursor(3, 1)
+	assert.Equal(1, et.cursorWidth())
+
+	screen.HideCursor()
+	assert.Equal(1, et.cursorWidth())
+
+	// The cursor draws over a wide rune, with and without the fallback.
+	screen.ShowCursor(0, 0)
+	for _, fallback := range []bool{false, true} {
+		et.SetCursorFallbackRender(fallback)
+		game := et.Game()
+		assert.NotPanics(func() { game.Draw(ebiten.NewImage(et.GetGameSize())) })
+	}
+}
+
+func TestETCellCursorBaseline(t *testing.T) {
+	assert := assert.New(t)
+
+	face, err := font.NewMonoFont(nil)
+	assert.NoError(err)
+	_, height := face.Size()
+
+	auto := face.BaselineOffset
+	for _, offset := range []float64{auto, auto - 3, auto + 1, 0} {
+		face.BaselineOffset = offset
+		baseline := font.Baseline(face)
+
+		// The underline is under the ink of glyphs sitting on the
+		// baseline, inside the cell.
+		top, bottom, ok := cursorSpan(tcell.CursorStyleSteadyUnderline, baseline, height)
+		assert.True(ok)
+		assert.GreaterOrEqual(top, baseline, offset)
+		assert.LessOrEqual(bottom, float64(height), offset)
+		assert.Greater(bottom, top, offset)
+
+		// The bar rises from the baseline.
+		top, bottom, ok = cursorSpan(tcell.CursorStyleBlinkingBar, baseline, height)
+		assert.True(ok)
+		assert.LessOrEqual(bottom, math.Max(baseline, float64(height)/4), offset)
+		assert.GreaterOrEqual(top, 0.0, offset)
+	}
+
+	_, _, ok := cursorSpan(tcell.CursorStyleSteadyBlock, 0, height)
+	assert.False(ok)
+
+	// Moving the glyphs moves the cursors with them.
+	face.BaselineOffset = auto - 1
+	top, _, _ := cursorSpan(tcell.CursorStyleSteadyUnderline, font.Baseline(face), height)
+	face.BaselineOffset = auto - 3
+	moved, _, _ := cursorSpan(tcell.CursorStyleSteadyUnderline, font.Baseline(face), height)
+	assert.InDelta(top-2, moved, 1e-9)
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(4, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.ShowCursor(1, 1)
+	game := et.Game()
+	for _, style := range []tcell.CursorStyle{tcell.CursorStyleSteadyUnderline, tcell.CursorStyleSteadyBar} {
+		screen.SetCursorStyle(style)
+		assert.NotPanics(func() { game.Draw(ebiten.NewImage(et.GetGameSize())) })
+	}
+}
+
+func TestETCellColorGlyph(t *testing.T) {
+	assert := assert.New(t)
+
+	ttf, err := os.ReadFile("font/testdata/color_emoji.ttf")
+	assert.NoError(err)
+
+	const tiger = rune(0x1F42F)
+
+	mono, err := font.NewMonoFont(nil)
+	assert.NoError(err)
+	emoji, err := font.NewMonoFontFromTTF(ttf, 11)
+	assert.NoError(err)
+	emoji.Width, emoji.Height = mono.Width, mono.Height
+
+	et := &ETCell{}
+	et.SetFont(&font.FaceWithBackup{Face: mono, Backup: emoji})
+	et.SetScreenSize(4, 1)
+	et.SetLineShaping(true)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault.Foreground(tcell.ColorRed))
+	screen.SetContent(1, 0, tiger, nil, tcell.StyleDefault.Foreground(tcell.ColorRed))
+	screen.SetContent(2, 0, 'b', nil, tcell.StyleDefault.Foreground(tcell.ColorRed))
+	screen.Show()
+
+	// Color glyphs are drawn as they are, and not shaped.
+	assert.False(et.grid[0].color_glyph)
+	assert.True(et.grid[1].color_glyph)
+	assert.False(et.grid[1].face_glyph)
+	glyph, _ := emoji.Glyph(tiger, font.FontStyleNormal)
+	assert.Same(glyph, et.grid[1].glyph)
+	assert.False(et.grid[2].color_glyph)
+
+	game := et.Game()
+	assert.NotPanics(func() { game.Draw(ebiten.NewImage(et.GetGameSize())) })
+
+	// Replaced, the cell is tinted again.
+	screen.SetContent(1, 0, 'c', nil, tcell.StyleDefault)
+	screen.Show()
+	assert.False(et.grid[1].color_glyph)
+}
+
+func TestETCellColorGlyphTint(t *testing.T) {
+	assert := assert.New(t)
+
+	face := &font.CacheFont{Width: 2, Height: 3}
+	face.SetGlyph('a', ebiten.NewImage(2, 3))
+	face.SetColorGlyph('b', ebiten.NewImage(2, 3))
+	icon := ebiten.NewImage(2, 3)
+
+	et := &ETCell{}
+	et.SetFont(face)
+	et.SetScreenSize(4, 1)
+	et.SetGlyphProvider(func(r rune, style font.FontStyle, fg, bg color.RGBA) (*ebiten.Image, bool) {
+		return icon, r == 'i'
+	})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed)
+	for x, r := range "abi" {
+		screen.SetContent(x, 0, r, nil, style)
+	}
+	screen.Show()
+
+	// Face glyphs are colored as the face marks them.
+	assert.False(et.grid[0].color_glyph)
+	assert.True(et.grid[1].color_glyph)
+
+	// Provider glyphs are tinted, unless set as colored.
+	assert.False(et.grid[2].color_glyph)
+	et.SetGlyphProviderColored(true)
+	screen.Sync()
+	assert.True(et.grid[2].color_glyph)
+	assert.Same(icon, et.grid[2].glyph)
+
+	// Mono glyphs are tinted with the foreground color, and color glyphs
+	// are only faded.
+	red := color.RGBA{R: 0x80, A: 0x80}
+	tinted := glyphColorScale(&et.grid[0], red)
+	assert.Equal(float32(0x80)/0xff, tinted.R())
+	assert.Equal(float32(0), tinted.G())
+	assert.Equal(float32(0x80)/0xff, tinted.A())
+
+	faded := glyphColorScale(&et.grid[1], red)
+	assert.Equal(float32(0x80)/0xff, faded.R())
+	assert.Equal(float32(0x80)/0xff, faded.G())
+	assert.Equal(float32(0x80)/0xff, faded.A())
+
+	game := et.Game()
+	assert.NotPanics(func() { game.Draw(ebiten.NewImage(et.GetGameSize())) })
+}
+
+func TestETCellVisibleRunes(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(10, 5)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.Clear()
+	screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	screen.SetContent(1, 0, 'a', nil, tcell.StyleDefault)
+	screen.SetContent(2, 1, 'e', []rune{'\u0301'}, tcell.StyleDefault)
+	screen.SetContent(3, 4, 'z', nil, tcell.StyleDefault)
+
+	runes := screen.VisibleRunes()
+	assert.Equal(2, runes['a'])
+	assert.Equal(1, runes['e'])
+	assert.Equal(1, runes['\u0301'])
+	assert.Equal(1, runes['z'])
+	assert.Equal(10*5-4, runes[' '])
+
+	// Rows scrolled out of view are not counted.
+	screen.SetBufferRows(10)
+	screen.SetContent(0, 9, 'q', nil, tcell.StyleDefault)
+	assert.NotContains(screen.VisibleRunes(), 'q')
+	screen.SetScrollOffset(5)
+	runes = screen.VisibleRunes()
+	assert.Equal(1, runes['q'])
+	assert.NotContains(runes, 'a')
+}
+
+func TestETCellPixelSnap(t *testing.T) {
+	assert := assert.New(t)
+
+	// A zoom animation, from 1x to 2x.
+	for frame := range 10 {
+		scale := 1 + float64(frame)/9
+		var geom ebiten.GeoM
+		geom.Translate(0.3, 0.6)
+		geom.Scale(scale, scale)
+
+		for x := range 5 {
+			unsnapped := cellGeoM(float64(x*7), 11, geom, false)
+			tx, ty := geom.Apply(float64(x*7), 11)
+			assert.Equal(tx, unsnapped.Element(0, 2))
+			assert.Equal(ty, unsnapped.Element(1, 2))
+
+			// Origins are whole pixels, with the same scale.
+			snapped := cellGeoM(float64(x*7), 11, geom, true)
+			assert.Equal(math.Round(tx), snapped.Element(0, 2))
+			assert.Equal(math.Round(ty), snapped.Element(1, 2))
+			assert.Equal(scale, snapped.Element(0, 0))
+			assert.Equal(scale, snapped.Element(1, 1))
+		}
+	}
+
+	// Rotated transforms are not rounded.
+	var rotated ebiten.GeoM
+	rotated.Translate(0.3, 0.6)
+	rotated.Rotate(math.Pi / 6)
+	assert.Equal(cellGeoM(7, 11, rotated, false), cellGeoM(7, 11, rotated, true))
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(4, 2)
+	assert.Same(et, et.SetPixelSnap(true))
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetContent(1, 1, 'x', nil, tcell.StyleDefault.Underline(true))
+	screen.Show()
+
+	game := et.Game()
+	dst := ebiten.NewImage(40, 40)
+	for frame := range 10 {
+		game.GeoM.Reset()
+		game.GeoM.Scale(1+float64(frame)/9, 1+float64(frame)/9)
+		game.GeoM.Translate(0.5, 0.25)
+		assert.NotPanics(func() { game.Draw(dst) })
+	}
+}
+
+func TestETCellWriteANSIResize(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	// Before the first layout, the screen is empty.
+	assert.NotPanics(func() { et.WriteANSI([]byte("a\x1b[1K\x1b[1J\x1b[2J\r\nb")) })
+	x, y := screen.ANSICursor()
+	assert.Equal(0, x)
+	assert.Equal(0, y)
+
+	et.SetScreenSize(6, 3)
+	et.WriteANSI([]byte("\x1b[1;6H"))
+	x, _ = screen.ANSICursor()
+	assert.Equal(5, x)
+
+	// After shrinking, the cursor is clamped, and erasing to it stays
+	// within its row.
+	et.SetScreenSize(3, 3)
+	screen.SetContent(0, 1, 'x', nil, tcell.StyleDefault)
+	et.WriteANSI([]byte("\x1b[1K"))
+	x, y = screen.ANSICursor()
+	assert.Equal(2, x)
+	assert.Equal(0, y)
+	r, _, _, _ := screen.GetContent(0, 1)
+	assert.Equal('x', r)
+}
+
+func TestETCellWriteANSIScrollback(t *testing.T) {
+	assert := assert.New(t)
+
+	et := &ETCell{}
+	et.SetFont(&font.CacheFont{Width: 2, Height: 3})
+	et.SetScreenSize(3, 2)
+
+	screen := et.Screen()
+	screen.Init()
+	defer screen.Fini()
+
+	screen.SetBufferRows(5)
+	screen.SetScrollOffset(3)
+	screen.SetContent(0, 0, 'z', nil, tcell.StyleDefault)
+
+	row := func(y int) string {
+		var text []rune
+		for x := range 3 {
+			r, _, _, _ := screen.GetContent(x, y)
+			if r == 0 {
+				r = '.'
+			}
+			text = append(text, r)
+		}
+		return string(text)
+	}
+
+	// The terminal is the visible rows.
+	et.WriteANSI([]byte("ab"))
+	assert.Equal("ab.", row(3))
+	assert.Equal("z  ", row(0))
+
+	// Lines scrolled off the screen move up into the buffer.
+	et.WriteANSI([]byte("\r\ncd\r\nef\r\ngh"))
+	assert.Equal([]string{"...", "ab.", "cd.", "ef ", "gh "},
+		[]string{row(0), row(1), row(2), row(3), row(4)})
+	x, y := screen.ANSICursor()
+	assert.Equal(2, x)
+	assert.Equal(1, y)
+
+	// Erasing only erases the screen.
+	et.WriteANSI([]byte("\x1b[2J"))
+	assert.Equal("cd.", row(2))
+	assert.Equal("   ", row(3))
+	assert.Equal("   ", row(4))
+
+	// Rows below the screen are not scrolled.
+	screen.SetScrollOffset(1)
+	screen.SetContent(0, 4, 'q', nil, tcell.StyleDefault)
+	et.WriteANSI([]byte("\x1b[2;1Hij\r\nkl"))
+	assert.Equal([]string{"ab.", "ij.", "kl ", "   ", "q  "},
+		[]string{row(0), row(1), row(2), row(3), row(4)})
+}
@@ -0,0 +1,93 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+package tcell_ebiten
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ezrec/tcell_ebiten/font"
+)
+
+// font_watch_interval is how often a watched font file is polled for changes.
+var font_watch_interval = 500 * time.Millisecond
+
+// WatchFont loads a TTF font file as the font, and reloads it whenever the
+// file is modified, for iterating on fonts or themes without restarting.
+// A size of 0 is the default size of [font.NewMonoFontFromTTF].
+//
+// The file is polled, so no file system watcher is required. Reloads are
+// serialized with drawing, and redraw the whole screen. Errors reloading
+// the font are posted as tcell.EventError events. Call stop to stop watching.
+func (et *ETCell) WatchFont(path string, size float64) (stop func(), err error) {
+	modified, err := et.loadFont(path, size)
+	if err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+	}
+
+	go func() {
+		ticker := time.NewTicker(font_watch_interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			// The file may be missing while it is being replaced.
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modified) {
+				continue
+			}
+
+			modified, err = et.loadFont(path, size)
+			if err != nil {
+				et.PostError(err)
+			}
+		}
+	}()
+
+	return
+}
+
+// loadFont loads a TTF font file as the font, returning the modification
+// time of the file loaded, even if it could not be loaded.
+func (et *ETCell) loadFont(path string, size float64) (modified time.Time, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	modified = info.ModTime()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	face, err := font.NewMonoFontFromTTF(data, size)
+	if err != nil {
+		return
+	}
+
+	et.grid_lock.Lock()
+	defer et.grid_lock.Unlock()
+
+	err = et.setFont(face)
+
+	// Redraw all the cells in the new font.
+	for n := 0; n < len(et.grid); n++ {
+		et.grid[n].synced = false
+	}
+	et.invalidated = true
+
+	return
+}
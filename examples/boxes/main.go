@@ -0,0 +1,125 @@
+package main
+
+import (
+	"log"
+
+	etcell "github.com/ezrec/tcell_ebiten"
+	"github.com/ezrec/tcell_ebiten/font"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// Box drawing grids. The last is drawn with only plain lines, which
+// form junctions when connected.
+var grids = [][]string{
+	{
+		"┌──┬──┐ ┏━━┳━━┓ ╔══╦══╗",
+		"│  │  │ ┃  ┃  ┃ ║  ║  ║",
+		"├──┼──┤ ┣━━╋━━┫ ╠══╬══╣",
+		"│  │  │ ┃  ┃  ┃ ║  ║  ║",
+		"└──┴──┘ ┗━━┻━━┛ ╚══╩══╝",
+	},
+	{
+		"────────",
+		"  │  │  ",
+		"────────",
+		"  │  │  ",
+		"────────",
+	},
+}
+
+// Drawing modes, cycled through by any key.
+var modes = []struct {
+	name      string
+	synthetic bool
+	connect   bool
+}{
+	{"font", false, false},
+	{"synthetic", true, false},
+	{"synthetic, connected", true, true},
+}
+
+type Boxes struct {
+	etcell.ETCell
+
+	mode int
+}
+
+func (b *Boxes) Run() (err error) {
+	err = b.ETCell.Run(b.runner)
+	return
+}
+
+func (b *Boxes) draw(screen tcell.Screen) {
+	style := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+
+	mode := modes[b.mode]
+	b.SetBoxDrawing(mode.synthetic, mode.connect)
+
+	screen.Clear()
+	for n, r := range "Any key changes mode. Mode: " + mode.name {
+		screen.SetContent(n, 0, r, nil, style)
+	}
+
+	x := 0
+	for _, grid := range grids {
+		width := 0
+		for y, line := range grid {
+			runes := []rune(line)
+			for n, r := range runes {
+				screen.SetContent(x+n, y+2, r, nil, style)
+			}
+			width = max(width, len(runes))
+		}
+		x += width + 2
+	}
+
+	screen.Sync()
+}
+
+func (b *Boxes) runner(screen tcell.Screen) (err error) {
+	screen.Init()
+	defer screen.Fini()
+
+	b.draw(screen)
+
+	for {
+		event := screen.PollEvent()
+		if event == nil {
+			return
+		}
+		switch ev := event.(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEnd, tcell.KeyEscape:
+				return nil
+			default:
+				b.mode = (b.mode + 1) % len(modes)
+				b.draw(screen)
+			}
+		case *tcell.EventResize:
+			b.draw(screen)
+		}
+	}
+}
+
+func main() {
+	ebiten.SetWindowSize(800, 300)
+	ebiten.SetWindowTitle("etcell boxes")
+
+	boxes := &Boxes{}
+
+	font_face, err := font.NewMonoFontFromTTF(gomono.TTF, 32)
+	if err != nil {
+		panic(err)
+	}
+
+	boxes.SetFont(font_face)
+
+	err = boxes.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
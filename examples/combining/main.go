@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+
+	etcell "github.com/ezrec/tcell_ebiten"
+	"github.com/ezrec/tcell_ebiten/font"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// Stacked diacritics, to compare combining glyph blend modes.
+var clusters = [][]rune{
+	{'a', '́', '̈'},
+	{'e', '̀', '̂', '̃'},
+	{'o', '̄', '̆', '̇'},
+	{'u', '̣', '̤', '̰'},
+	{'n', '̃', '̃', '̃'},
+}
+
+type Combining struct {
+	etcell.ETCell
+
+	blend_max bool
+}
+
+func (c *Combining) Run() (err error) {
+	err = c.ETCell.Run(c.runner)
+	return
+}
+
+func (c *Combining) draw(screen tcell.Screen) {
+	style := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+
+	title := "Space toggles blend. Blend: source-over"
+	if c.blend_max {
+		title = "Space toggles blend. Blend: max        "
+	}
+
+	screen.Clear()
+	for n, r := range title {
+		screen.SetContent(n, 0, r, nil, style)
+	}
+
+	for n, cluster := range clusters {
+		screen.SetContent(n*2, 2, cluster[0], cluster[1:], style)
+	}
+
+	screen.Show()
+}
+
+func (c *Combining) runner(screen tcell.Screen) (err error) {
+	screen.Init()
+	defer screen.Fini()
+
+	c.blend_max = true
+	c.draw(screen)
+
+	for {
+		event := screen.PollEvent()
+		if event == nil {
+			return
+		}
+		switch ev := event.(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEnd, tcell.KeyEscape:
+				return nil
+			case tcell.KeyRune:
+				c.blend_max = !c.blend_max
+				if c.blend_max {
+					c.SetCombiningBlend(etcell.BlendMax)
+				} else {
+					c.SetCombiningBlend(ebiten.BlendSourceOver)
+				}
+				c.draw(screen)
+			}
+		case *tcell.EventResize:
+			c.draw(screen)
+		}
+	}
+}
+
+func main() {
+	ebiten.SetWindowSize(800, 200)
+	ebiten.SetWindowTitle("etcell combining")
+
+	combining := &Combining{}
+
+	font_face, err := font.NewMonoFontFromTTF(gomono.TTF, 48)
+	if err != nil {
+		panic(err)
+	}
+
+	combining.SetFont(font_face)
+
+	err = combining.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
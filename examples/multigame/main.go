@@ -1,6 +1,7 @@
 package main
 
 import (
+	"image/color"
 	"log"
 	"math"
 	"math/rand"
@@ -205,12 +206,19 @@ func main() {
 	ms.spin.ETCellGame = ms.NewGame()
 	ms.zoom.ETCellGame = ms.NewGame()
 
+	// Highlight the game under the mouse.
+	ms.spin.SetFocusIndicator(color.White, 4)
+	ms.zoom.SetFocusIndicator(color.White, 4)
+
 	font, err := font.NewMonoFontFromTTF(gomono.TTF, 16)
 	if err != nil {
 		panic(err)
 	}
 	ms.SetFont(font)
 
+	// Steady the zooming game; the spinning game is not snapped.
+	ms.SetPixelSnap(true)
+
 	err = ms.Run()
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	etcell "github.com/ezrec/tcell_ebiten"
+	"github.com/ezrec/tcell_ebiten/font"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// Panes lays out two independent ETCells side by side, each with its own
+// font, cell size, and grid: a small font log pane on the left, and a
+// large font editor pane on the right.
+type Panes struct {
+	log    etcell.ETCell
+	editor etcell.ETCell
+}
+
+// Layout splits the window between the panes. Each game lays out its own
+// grid in its pane size, and is moved into place by its GeoM.
+func (p *Panes) Layout(width, height int) (int, int) {
+	log_width := width / 3
+
+	p.log.Game().Layout(log_width, height)
+	p.editor.Game().Layout(width-log_width, height)
+
+	p.editor.Game().GeoM.Reset()
+	p.editor.Game().GeoM.Translate(float64(log_width), 0)
+
+	return width, height
+}
+
+func (p *Panes) Update() (err error) {
+	// Keys are posted to the pane under the mouse.
+	err = p.log.Game().Update()
+	if err != nil {
+		return
+	}
+
+	err = p.editor.Game().Update()
+
+	return
+}
+
+func (p *Panes) Draw(screen *ebiten.Image) {
+	p.log.Game().Draw(screen)
+	p.editor.Game().Draw(screen)
+}
+
+// start runs a tcell application on an ETCell's screen.
+func start(et *etcell.ETCell, runner func(screen tcell.Screen) error) {
+	go func() {
+		screen := et.Screen()
+		screen.Init()
+		defer screen.Fini()
+
+		et.Exit(runner(screen))
+	}()
+}
+
+// logger prints a line to the log pane every second.
+func logger(screen tcell.Screen) (err error) {
+	style := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	go func() {
+		for range ticker.C {
+			screen.PostEvent(tcell.NewEventInterrupt(nil))
+		}
+	}()
+
+	lines := []string{}
+	for {
+		event := screen.PollEvent()
+		switch ev := event.(type) {
+		case nil:
+			return
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape {
+				return
+			}
+		case *tcell.EventInterrupt:
+			lines = append(lines, fmt.Sprintf("%v tick", ev.When().Format(time.TimeOnly)))
+		}
+
+		_, height := screen.Size()
+		lines = lines[max(0, len(lines)-height):]
+
+		screen.Clear()
+		for y, line := range lines {
+			screen.(*etcell.ETCellScreen).Print(0, y, line, style)
+		}
+		screen.Show()
+	}
+}
+
+// editor echoes typed text in the editor pane.
+func editor(screen tcell.Screen) (err error) {
+	style := tcell.StyleDefault
+	text := "Type here. Escape quits.\n"
+
+	for {
+		event := screen.PollEvent()
+		switch ev := event.(type) {
+		case nil:
+			return
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return
+			case tcell.KeyEnter:
+				text += "\n"
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				runes := []rune(text)
+				text = string(runes[:max(0, len(runes)-1)])
+			case tcell.KeyRune:
+				text += string(ev.Rune())
+			}
+		}
+
+		etcell_screen := screen.(*etcell.ETCellScreen)
+		screen.Clear()
+		x, y := etcell_screen.Print(0, 0, text, style)
+		screen.ShowCursor(x, y)
+		screen.Show()
+	}
+}
+
+func main() {
+	ebiten.SetWindowSize(900, 400)
+	ebiten.SetWindowTitle("etcell panes")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+
+	panes := &Panes{}
+
+	small, err := font.NewMonoFontFromTTF(gomono.TTF, 12)
+	if err != nil {
+		panic(err)
+	}
+	panes.log.SetFont(small)
+
+	large, err := font.NewMonoFontFromTTF(gomono.TTF, 28)
+	if err != nil {
+		panic(err)
+	}
+	panes.editor.SetFont(large)
+
+	start(&panes.log, logger)
+	start(&panes.editor, editor)
+
+	err = ebiten.RunGame(panes)
+	if err != nil && err != ebiten.Termination {
+		log.Fatal(err)
+	}
+}
@@ -6,17 +6,26 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"math"
+	"strings"
 
 	typesetting_font "github.com/go-text/typesetting/font"
 	"github.com/hajimehoshi/ebiten/v2"
 	ebiten_text "github.com/hajimehoshi/ebiten/v2/text/v2"
 	image_font "golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/math/fixed"
 )
 
 var ErrFontType = errors.New("unknown font source type")
 
+// ErrNoNormalStyle is returned when a style map has no FontStyleNormal face.
+var ErrNoNormalStyle = errors.New("no face for FontStyleNormal")
+
 // FontStyle selects which font style with which to render a rune.
 type FontStyle int
 
@@ -28,6 +37,17 @@ const (
 )
 
 // Face provides an interace to the font properties.
+//
+// Glyphs are tinted with the foreground color of their cells when drawn,
+// so are normally white on transparent. Faces with full color glyphs,
+// such as emoji or icons, mark them by implementing an
+// IsColorGlyph(rune, FontStyle) bool method; see [IsColorGlyph].
+//
+// A screen calls Glyph with its grid locked, so calls are serialized,
+// but they may come from the goroutine calling the screen's Show(), Sync()
+// or CanDisplay(), or from the ebiten goroutine in Draw(). Faces that must
+// rasterize on the ebiten goroutine should be used with
+// ETCell.SetRasterizeOnDraw.
 type Face interface {
 	Metrics() (metrics ebiten_text.Metrics)
 	Size() (width, height int) // Character cell size, in pixels.
@@ -35,6 +55,107 @@ type Face interface {
 	Empty() (empty_glyph *ebiten.Image)
 }
 
+// GlyphPixels reads the glyph of a rune, in the specified style, into CPU memory.
+// The returned pixels are in pre-multiplied alpha, as stored in the glyph image.
+//
+// As with [ebiten.Image.ReadPixels], this can only be called once the ebiten
+// game loop has started, and is slow; it is intended for tooling and tests.
+// A nil glyph, from a custom Face, reads as transparent pixels of the cell
+// size.
+func GlyphPixels(face Face, character rune, style FontStyle) image.Image {
+	glyph, _ := face.Glyph(character, style)
+	if glyph == nil {
+		width, height := face.Size()
+		return image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	pixels := image.NewRGBA(glyph.Bounds())
+	glyph.ReadPixels(pixels.Pix)
+
+	return pixels
+}
+
+// SupportsStyle returns true if a face renders a style distinctly, rather
+// than rendering it as another style. Faces with a single font, such as
+// MonoFont and CacheFont, ignore the style, so only support
+// FontStyleNormal. Wrapping faces support the styles of the faces they
+// wrap, and FaceWithStyle supports the styles it maps.
+//
+// Applications can use this to show unsupported styles some other way,
+// such as with color.
+func SupportsStyle(face Face, style FontStyle) bool {
+	supporter, ok := face.(interface{ SupportsStyle(FontStyle) bool })
+	if ok {
+		return supporter.SupportsStyle(style)
+	}
+
+	return style == FontStyleNormal
+}
+
+// Invalidate drops the cached glyphs of a face, and of the faces it wraps,
+// so that they are generated again with the current rendering settings.
+// Faces that do not cache glyphs are unchanged, as are glyphs set with
+// CacheFont.SetGlyph.
+//
+// The dropped glyphs are deallocated, so must no longer be drawn.
+func Invalidate(face Face) {
+	invalidator, ok := face.(interface{ Invalidate() })
+	if ok {
+		invalidator.Invalidate()
+	}
+}
+
+// IsColorGlyph returns true if the glyph of a rune, in a style, is a full
+// color image, such as of a color emoji font, to be drawn as it is rather
+// than tinted with the foreground color, as other glyphs are. Only
+// CacheFont and MonoFont, and faces wrapping them, have color glyphs: those
+// set by CacheFont.SetColorGlyph, and the PNG and JPG bitmaps of the CBDT
+// and sbix tables of TTF fonts. COLR glyphs are drawn from their outlines,
+// as other glyphs are.
+func IsColorGlyph(face Face, character rune, style FontStyle) bool {
+	colorer, ok := face.(interface {
+		IsColorGlyph(rune, FontStyle) bool
+	})
+	if ok {
+		return colorer.IsColorGlyph(character, style)
+	}
+
+	return false
+}
+
+// Baseline returns the distance, in pixels, from the top of the cells of
+// a face to the baseline of its glyphs. Faces that place glyphs in their
+// cells other than with the top of the line at the top of the cell, such
+// as MonoFont, implement a Baseline() float64 method; for other faces it
+// is the ascent of their metrics.
+func Baseline(face Face) float64 {
+	baseliner, ok := face.(interface{ Baseline() float64 })
+	if ok {
+		return baseliner.Baseline()
+	}
+
+	return face.Metrics().HAscent
+}
+
+// ShapeCells shapes the text of a run of cells as a single line, so that
+// the kerning, ligatures and mark positioning of the font apply across the
+// cells, and returns a cell sized glyph for each cell. Each cluster is
+// snapped to the start of its first cell, so that the advances of the
+// font do not accumulate and drift from the grid; a ligature of several
+// cells is split across them. ok is false if the face cannot shape text,
+// as only MonoFont, and FaceWithStyle of MonoFont faces, can. The glyphs
+// are new images, owned by the caller.
+func ShapeCells(face Face, cells []string, style FontStyle) (glyphs [](*ebiten.Image), ok bool) {
+	shaper, is_shaper := face.(interface {
+		ShapeCells([]string, FontStyle) ([](*ebiten.Image), bool)
+	})
+	if !is_shaper {
+		return
+	}
+
+	return shaper.ShapeCells(cells, style)
+}
+
 // Implements Face
 type CacheFont struct {
 	FontMetrics ebiten_text.Metrics
@@ -42,7 +163,8 @@ type CacheFont struct {
 	Width       int // Nominal cell width.
 	Height      int // Nominal cell height.
 
-	empty *ebiten.Image
+	empty   *ebiten.Image
+	colored map[rune]struct{} // Runes with color glyphs.
 }
 
 // Assert interface compliance.
@@ -62,6 +184,36 @@ func (mf *CacheFont) SetGlyph(character rune, glyph *ebiten.Image) {
 	}
 
 	mf.Cache[character] = glyph
+	delete(mf.colored, character)
+}
+
+// SetColorGlyph sets a full color glyph into the cache, such as an icon,
+// which is drawn as it is, rather than tinted with the foreground color.
+func (mf *CacheFont) SetColorGlyph(character rune, glyph *ebiten.Image) {
+	mf.SetGlyph(character, glyph)
+
+	if mf.colored == nil {
+		mf.colored = map[rune]struct{}{}
+	}
+	mf.colored[character] = struct{}{}
+}
+
+// IsColorGlyph returns true if the cached glyph of a rune was set as a
+// color glyph. See [IsColorGlyph].
+func (mf *CacheFont) IsColorGlyph(character rune, style FontStyle) bool {
+	if mf.Cache[character] == nil {
+		return false
+	}
+
+	_, colored := mf.colored[character]
+
+	return colored
+}
+
+// Invalidate keeps the glyphs set by SetGlyph, which are owned by the
+// caller and could not be generated again. Faces that generate their
+// glyphs, such as MonoFont, drop those they generated.
+func (mf *CacheFont) Invalidate() {
 }
 
 // Empty() returns the empty image.
@@ -122,12 +274,32 @@ func (mf *CacheFont) Glyph(character rune, style FontStyle) (glyph *ebiten.Image
 	return
 }
 
+// MonoFont is a face of a single font. Styles are ignored, and render as
+// FontStyleNormal; use FaceWithStyle for bold and italic fonts.
 // Implements Face
 type MonoFont struct {
 	CacheFont
 	Face ebiten_text.Face
 
+	// BaselineOffset moves glyphs down in their cells, in pixels, or up
+	// if negative. NewMonoFont sets it to vertically center the ink of
+	// FULL BLOCK in the cell, which fixes fonts that sit too high or low.
+	// Set it to override that; glyphs already generated are only moved
+	// once dropped with Invalidate. The offset is of glyphs filling the
+	// cell, and is scaled with them by PreserveAspect.
+	BaselineOffset float64
+
+	// PreserveAspect scales glyphs by the same factor in both directions,
+	// the smaller of those that fill the cell, and centers them in it,
+	// rather than stretching them to fill the cell, for fonts whose
+	// natural aspect differs from the cell's. The default is to fill.
+	// BaselineOffset is scaled with the glyphs, so the ink of FULL BLOCK
+	// stays centered. Glyphs already generated are only changed once
+	// dropped with Invalidate.
+	PreserveAspect bool
+
 	drawOptions ebiten_text.DrawOptions
+	generated   map[rune](*ebiten.Image) // Glyphs generated by the face.
 }
 
 // Assert interface compliance.
@@ -178,6 +350,11 @@ func NewMonoFont(source any) (mf *MonoFont, err error) {
 
 	mf.drawOptions.GeoM.Scale(scale_w, scale_h)
 
+	ink, ok := mf.inkBounds(reference_rune)
+	if ok {
+		mf.BaselineOffset = math.Round(float64(height-ink.Min.Y-ink.Max.Y) / 2)
+	}
+
 	return
 }
 
@@ -217,6 +394,21 @@ func NewMonoFontFromTTF(source any, size float64) (mf *MonoFont, err error) {
 	return NewMonoFont(face)
 }
 
+// NewMonoFontFromTTFPoints creates a new monospaced font face from a TTF font,
+// sized in points for a display of the given dots per inch, so that the
+// text has the same physical size on any display. A point is 1/72 of an
+// inch, so the size in pixels is points * dpi / 72. A dpi of 0 assumes 72,
+// where points and pixels are the same. For HiDPI displays, the dpi is the
+// nominal dpi scaled by ebiten.Monitor().DeviceScaleFactor().
+// Takes the same sources as [NewMonoFontFromTTF].
+func NewMonoFontFromTTFPoints(source any, points float64, dpi float64) (mf *MonoFont, err error) {
+	if dpi == 0 {
+		dpi = 72
+	}
+
+	return NewMonoFontFromTTF(source, points*dpi/72)
+}
+
 // HasGlyph returns true if a rune is in the font, in the specified style.
 func (mf *MonoFont) HasGlyph(character rune, style FontStyle) (has bool) {
 	// Short-circuit for cached glyphs.
@@ -241,20 +433,105 @@ func (mf *MonoFont) HasGlyph(character rune, style FontStyle) (has bool) {
 	return
 }
 
+// inkBounds returns the bounds of the ink of a rune's glyph in its cell,
+// including the baseline offset, from the glyph metrics of the font.
+func (mf *MonoFont) inkBounds(character rune) (bounds image.Rectangle, ok bool) {
+	// Ink relative to the baseline, in unscaled pixels, y down.
+	var x0, y0, x1, y1 float64
+
+	// As with HasGlyph, the glyph metrics are only available internally.
+	switch ebiten_face := mf.Face.(type) {
+	case (*ebiten_text.GoXFace):
+		face := ebiten_face.UnsafeInternal()
+		var ink fixed.Rectangle26_6
+		ink, _, ok = face.GlyphBounds(character)
+		x0, y0 = float64(ink.Min.X)/64, float64(ink.Min.Y)/64
+		x1, y1 = float64(ink.Max.X)/64, float64(ink.Max.Y)/64
+	case (*ebiten_text.GoTextFace):
+		face := ebiten_face.Source.UnsafeInternal().(*typesetting_font.Face)
+		var gid typesetting_font.GID
+		gid, ok = face.NominalGlyph(character)
+		if !ok {
+			break
+		}
+		var extents typesetting_font.GlyphExtents
+		extents, ok = face.GlyphExtents(gid)
+		scale := ebiten_face.Size / float64(face.Upem())
+		x0, y0 = float64(extents.XBearing)*scale, -float64(extents.YBearing)*scale
+		x1, y1 = x0+float64(extents.Width)*scale, y0-float64(extents.Height)*scale
+	}
+
+	if !ok {
+		return
+	}
+
+	// Glyphs are drawn with the top of the line at the top of the cell.
+	ascent := mf.FontMetrics.HAscent
+	geom := mf.glyphGeoM()
+	min_x, min_y := geom.Apply(x0, ascent+y0)
+	max_x, max_y := geom.Apply(x1, ascent+y1)
+
+	bounds = image.Rect(
+		int(math.Floor(min_x)), int(math.Floor(min_y)),
+		int(math.Ceil(max_x)), int(math.Ceil(max_y)),
+	)
+
+	return
+}
+
+// glyphGeoM returns the transform of glyphs into their cells, with the
+// baseline offset: filling the cell, or preserving their aspect ratio.
+func (mf *MonoFont) glyphGeoM() (geom ebiten.GeoM) {
+	geom = mf.drawOptions.GeoM
+	if !mf.PreserveAspect {
+		geom.Translate(0, mf.BaselineOffset)
+		return
+	}
+
+	scale_w, scale_h := geom.Element(0, 0), geom.Element(1, 1)
+	scale := min(scale_w, scale_h)
+	width, height := float64(mf.Width), float64(mf.Height)
+
+	// The nominal box is centered, and the offset, which centers the
+	// ink of filled glyphs, is scaled with it.
+	geom.Reset()
+	geom.Scale(scale, scale)
+	geom.Translate((width-width*scale/scale_w)/2, (height-height*scale/scale_h)/2)
+	geom.Translate(0, mf.BaselineOffset*scale/scale_h)
+
+	return
+}
+
 // Glyph returns a glyph for a rune. Rune glyphs are cached on their first access.
 func (mf *MonoFont) Glyph(character rune, style FontStyle) (glyph *ebiten.Image, is_empty bool) {
 	glyph, ok := mf.CacheFont.Cache[character]
 	if !ok {
+		colored := false
 		if !mf.HasGlyph(character, style) {
 			// Empty glyph.
 			glyph = nil
+		} else if bitmap := mf.bitmapOf(character); bitmap != nil {
+			// Color bitmaps are drawn as they are, fit to the cell.
+			glyph = mf.bitmapGlyph(bitmap)
+			colored = true
 		} else {
 			// Generate new glyph for this rune.
 			glyph = ebiten.NewImage(mf.Width, mf.Height)
-			ebiten_text.Draw(glyph, string([]rune{character}), mf.Face, &mf.drawOptions)
+			opts := mf.drawOptions
+			opts.GeoM = mf.glyphGeoM()
+			ebiten_text.Draw(glyph, string([]rune{character}), mf.Face, &opts)
+		}
+
+		if colored {
+			mf.CacheFont.SetColorGlyph(character, glyph)
+		} else {
+			mf.CacheFont.SetGlyph(character, glyph)
 		}
 
-		mf.CacheFont.SetGlyph(character, glyph)
+		if mf.generated == nil {
+			mf.generated = map[rune](*ebiten.Image){}
+		}
+		mf.generated[character] = glyph
 	}
 
 	if glyph == nil {
@@ -265,6 +542,174 @@ func (mf *MonoFont) Glyph(character rune, style FontStyle) (glyph *ebiten.Image,
 	return
 }
 
+// Invalidate drops and deallocates the glyphs generated by the face, so
+// that they are generated again with the current settings on their next
+// access. Glyphs set by SetGlyph are kept.
+func (mf *MonoFont) Invalidate() {
+	for character, glyph := range mf.generated {
+		cached, ok := mf.CacheFont.Cache[character]
+		if !ok || cached != glyph {
+			continue
+		}
+		if glyph != nil {
+			glyph.Deallocate()
+		}
+		delete(mf.CacheFont.Cache, character)
+		delete(mf.CacheFont.colored, character)
+	}
+
+	mf.generated = nil
+}
+
+// Baseline returns the baseline of glyphs in their cells, as placed by
+// the fill or PreserveAspect scaling and BaselineOffset. See [Baseline].
+func (mf *MonoFont) Baseline() float64 {
+	geom := mf.glyphGeoM()
+	_, baseline := geom.Apply(0, mf.FontMetrics.HAscent)
+
+	return baseline
+}
+
+// IsColorGlyph returns true if the glyph of a rune is a color bitmap.
+// See [IsColorGlyph].
+func (mf *MonoFont) IsColorGlyph(character rune, style FontStyle) bool {
+	mf.Glyph(character, style)
+
+	return mf.CacheFont.IsColorGlyph(character, style)
+}
+
+// bitmapOf returns the decoded color bitmap of the glyph of a rune, or nil
+// if its glyph is not a PNG or JPG bitmap. Only go-text faces have them.
+func (mf *MonoFont) bitmapOf(character rune) (bitmap image.Image) {
+	ebiten_face, ok := mf.Face.(*ebiten_text.GoTextFace)
+	if !ok {
+		return
+	}
+
+	face := ebiten_face.Source.UnsafeInternal().(*typesetting_font.Face)
+	gid, ok := face.NominalGlyph(character)
+	if !ok {
+		return
+	}
+
+	data, ok := face.GlyphData(gid).(typesetting_font.GlyphBitmap)
+	if !ok {
+		return
+	}
+
+	var err error
+	switch data.Format {
+	case typesetting_font.PNG:
+		bitmap, err = png.Decode(bytes.NewReader(data.Data))
+	case typesetting_font.JPG:
+		bitmap, err = jpeg.Decode(bytes.NewReader(data.Data))
+	}
+	if err != nil {
+		bitmap = nil
+	}
+
+	return
+}
+
+// bitmapGlyph returns a glyph of a color bitmap, scaled to fit the cell,
+// keeping its aspect ratio, and centered in it.
+func (mf *MonoFont) bitmapGlyph(bitmap image.Image) (glyph *ebiten.Image) {
+	glyph = ebiten.NewImage(mf.Width, mf.Height)
+
+	size := bitmap.Bounds().Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return
+	}
+
+	scale := min(float64(mf.Width)/float64(size.X), float64(mf.Height)/float64(size.Y))
+
+	var opts ebiten.DrawImageOptions
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate((float64(mf.Width)-float64(size.X)*scale)/2, (float64(mf.Height)-float64(size.Y)*scale)/2)
+	opts.Filter = ebiten.FilterLinear
+	glyph.DrawImage(ebiten.NewImageFromImage(bitmap), &opts)
+
+	return
+}
+
+// ShapeCells shapes the text of a run of cells as a single line, and
+// returns a cell sized glyph for each cell. See [ShapeCells].
+func (mf *MonoFont) ShapeCells(cells []string, style FontStyle) (glyphs [](*ebiten.Image), ok bool) {
+	glyphs = make([](*ebiten.Image), len(cells))
+	for n := range glyphs {
+		glyphs[n] = ebiten.NewImage(mf.Width, mf.Height)
+	}
+
+	for _, placed := range mf.shapeLine(cells) {
+		var opts ebiten.DrawImageOptions
+		opts.GeoM = placed.geom
+		glyphs[placed.cell].DrawImage(placed.image, &opts)
+	}
+
+	ok = true
+
+	return
+}
+
+// cellGlyph is a glyph of a shaped line, placed in a cell.
+type cellGlyph struct {
+	cell  int
+	image *ebiten.Image
+	geom  ebiten.GeoM // Transform of the image into the cell.
+
+	origin_x, origin_y float64 // Pen origin of the glyph, in its image.
+}
+
+// shapeLine shapes the text of a run of cells as a single line, and
+// places its glyphs in the cells, with the first cluster of each cell at
+// the cell's origin.
+func (mf *MonoFont) shapeLine(cells []string) (placed []cellGlyph) {
+	// The cell of each byte of the line.
+	var line strings.Builder
+	var owner []int
+	for n, text := range cells {
+		line.WriteString(text)
+		for range len(text) {
+			owner = append(owner, n)
+		}
+	}
+
+	shaped := ebiten_text.AppendGlyphs(nil, line.String(), mf.Face, nil)
+
+	// The origin of the first cluster of each cell.
+	origin := map[int]float64{}
+	for _, g := range shaped {
+		first := owner[g.StartIndexInBytes]
+		if _, ok := origin[first]; !ok {
+			origin[first] = g.OriginX
+		}
+	}
+
+	geom := mf.glyphGeoM()
+	for _, g := range shaped {
+		if g.Image == nil || g.EndIndexInBytes <= g.StartIndexInBytes {
+			continue
+		}
+
+		first, last := owner[g.StartIndexInBytes], owner[g.EndIndexInBytes-1]
+		for n := first; n <= last; n++ {
+			var glyph_geom ebiten.GeoM
+			glyph_geom.Translate(g.X-origin[first], g.Y)
+			glyph_geom.Concat(geom)
+			glyph_geom.Translate(-float64((n-first)*mf.Width), 0)
+			placed = append(placed, cellGlyph{
+				cell:     n,
+				image:    g.Image,
+				geom:     glyph_geom,
+				origin_x: g.OriginX - g.X,
+				origin_y: g.OriginY - g.Y,
+			})
+		}
+	}
+
+	return
+}
+
 // FaceWithOnlyRunes limits the font to only the specified runes.
 type FaceWithOnlyRunes struct {
 	Face
@@ -296,6 +741,29 @@ func (fm *FaceWithOnlyRunes) Glyph(character rune, style FontStyle) (glyph *ebit
 	return
 }
 
+// IsColorGlyph returns true if the glyph of the rune is a color bitmap,
+// so long as it is in the mapping.
+func (fm *FaceWithOnlyRunes) IsColorGlyph(character rune, style FontStyle) bool {
+	_, is_empty := fm.Glyph(character, style)
+
+	return !is_empty && IsColorGlyph(fm.Face, character, style)
+}
+
+// Baseline returns the baseline of the font. See [Baseline].
+func (fm *FaceWithOnlyRunes) Baseline() float64 {
+	return Baseline(fm.Face)
+}
+
+// SupportsStyle returns true if the font supports the style.
+func (fm *FaceWithOnlyRunes) SupportsStyle(style FontStyle) bool {
+	return SupportsStyle(fm.Face, style)
+}
+
+// Invalidate drops the cached glyphs of the font.
+func (fm *FaceWithOnlyRunes) Invalidate() {
+	Invalidate(fm.Face)
+}
+
 // FaceWithRuneMapping applies a rune mapping to a font.
 // Implements [Face]
 type FaceWithRuneMapping struct {
@@ -316,6 +784,32 @@ func (fm *FaceWithRuneMapping) Glyph(character rune, style FontStyle) (glyph *eb
 	return fm.Face.Glyph(character, style)
 }
 
+// IsColorGlyph returns true if the glyph of the mapped rune is a color
+// bitmap.
+func (fm *FaceWithRuneMapping) IsColorGlyph(character rune, style FontStyle) bool {
+	replacement, ok := fm.RuneMapping[character]
+	if ok {
+		character = replacement
+	}
+
+	return IsColorGlyph(fm.Face, character, style)
+}
+
+// Baseline returns the baseline of the font. See [Baseline].
+func (fm *FaceWithRuneMapping) Baseline() float64 {
+	return Baseline(fm.Face)
+}
+
+// SupportsStyle returns true if the font supports the style.
+func (fm *FaceWithRuneMapping) SupportsStyle(style FontStyle) bool {
+	return SupportsStyle(fm.Face, style)
+}
+
+// Invalidate drops the cached glyphs of the font.
+func (fm *FaceWithRuneMapping) Invalidate() {
+	Invalidate(fm.Face)
+}
+
 // FaceWithBackup allows a font be the 'backup' for another font, if the primary font doesn't have the right runes.
 // Implements [Face]
 type FaceWithBackup struct {
@@ -334,17 +828,140 @@ func (fm *FaceWithBackup) Glyph(character rune, style FontStyle) (glyph *ebiten.
 	return
 }
 
+// IsColorGlyph returns true if the glyph of the rune is a color bitmap,
+// in the font, or in the backup font if the font does not have it.
+func (fm *FaceWithBackup) IsColorGlyph(character rune, style FontStyle) bool {
+	_, is_empty := fm.Face.Glyph(character, style)
+	if !is_empty {
+		return IsColorGlyph(fm.Face, character, style)
+	}
+
+	return IsColorGlyph(fm.Backup, character, style)
+}
+
+// Baseline returns the baseline of the font, which sets the metrics of
+// the cells. See [Baseline].
+func (fm *FaceWithBackup) Baseline() float64 {
+	return Baseline(fm.Face)
+}
+
+// SupportsStyle returns true if the font, or its backup, supports the style.
+func (fm *FaceWithBackup) SupportsStyle(style FontStyle) bool {
+	return SupportsStyle(fm.Face, style) || SupportsStyle(fm.Backup, style)
+}
+
+// Invalidate drops the cached glyphs of the font, and its backup.
+func (fm *FaceWithBackup) Invalidate() {
+	Invalidate(fm.Face)
+	Invalidate(fm.Backup)
+}
+
 // FaceWithStyle has alternate fonts for bold or italic styles.
 //
-// FontStyleNormal must be mapped to a valid face.
+// FontStyleNormal must be mapped to a valid face, which supplies the
+// metrics and cell size. Styles that are not mapped fall back to other
+// styles, see Glyph. NewFaceWithStyle checks the style map; a
+// FaceWithStyle without FontStyleNormal panics when used.
+// Scale optionally scales the glyphs of a style within their cells, such
+// as to draw italic slightly smaller, or bold slightly larger, for visual
+// balance. Glyphs are scaled about the baseline, at the horizontal center
+// of the cell, and clipped to the cell, so cells keep the size of the
+// FontStyleNormal face and stay aligned. Styles that are not in Scale, or
+// have a scale of 0, are drawn at a scale of 1.0. The scale of the
+// requested style applies, even if it falls back to the face of another
+// style. Call Invalidate after changing Scale.
 // Implements [Face]
 type FaceWithStyle struct {
 	StyleMap map[FontStyle]Face
+	Scale    map[FontStyle]float64
+
+	scaled map[scaledGlyph](*ebiten.Image)
+}
+
+// scaledGlyph is the key of a scaled glyph.
+type scaledGlyph struct {
+	character rune
+	style     FontStyle
 }
 
 // Assert interface compliance.
 var _ Face = (*FaceWithStyle)(nil)
 
+// NewFaceWithStyle returns a face using the faces of a style map, or
+// ErrNoNormalStyle if FontStyleNormal is not mapped to a face.
+func NewFaceWithStyle(style_map map[FontStyle]Face) (fm *FaceWithStyle, err error) {
+	if style_map[FontStyleNormal] == nil {
+		err = ErrNoNormalStyle
+		return
+	}
+
+	fm = &FaceWithStyle{StyleMap: style_map}
+	return
+}
+
+// SupportsStyle returns true if the style is mapped to a face.
+func (fm *FaceWithStyle) SupportsStyle(style FontStyle) bool {
+	return fm.StyleMap[style] != nil
+}
+
+// IsColorGlyph returns true if the glyph of the rune, in the face of a
+// style, is a color bitmap.
+func (fm *FaceWithStyle) IsColorGlyph(character rune, style FontStyle) bool {
+	return IsColorGlyph(fm.forStyle(style), character, style)
+}
+
+// ShapeCells shapes the text of a run of cells with the face of a style.
+// See [ShapeCells].
+func (fm *FaceWithStyle) ShapeCells(cells []string, style FontStyle) (glyphs [](*ebiten.Image), ok bool) {
+	glyphs, ok = ShapeCells(fm.forStyle(style), cells, style)
+
+	// The unscaled glyphs are new, and only returned scaled.
+	scale, is_scaled := fm.scaleOf(style)
+	if is_scaled {
+		for n, glyph := range glyphs {
+			glyphs[n] = fm.scaleGlyph(glyph, scale, Baseline(fm.forStyle(style)))
+			glyph.Deallocate()
+		}
+	}
+
+	return
+}
+
+// scaleOf returns the scale of a style, and true if it is not 1.0.
+func (fm *FaceWithStyle) scaleOf(style FontStyle) (scale float64, is_scaled bool) {
+	scale = fm.Scale[style]
+	is_scaled = scale > 0 && scale != 1
+
+	return
+}
+
+// scaleGlyph returns a copy of a glyph, scaled about a baseline at the
+// horizontal center of the cell, and clipped to the cell.
+func (fm *FaceWithStyle) scaleGlyph(glyph *ebiten.Image, scale float64, baseline float64) (scaled *ebiten.Image) {
+	size := glyph.Bounds().Size()
+
+	scaled = ebiten.NewImage(size.X, size.Y)
+
+	var opts ebiten.DrawImageOptions
+	opts.GeoM = scaleGeoM(size, scale, baseline)
+	opts.Filter = ebiten.FilterLinear
+	scaled.DrawImage(glyph, &opts)
+
+	return
+}
+
+// scaleGeoM returns the transform of a glyph of a cell size, scaled about
+// a baseline at the horizontal center of the cell.
+func scaleGeoM(size image.Point, scale float64, baseline float64) (geom ebiten.GeoM) {
+	center_x := float64(size.X) / 2
+
+	geom.Translate(-center_x, -baseline)
+	geom.Scale(scale, scale)
+	geom.Translate(center_x, baseline)
+
+	return
+}
+
 func (fm *FaceWithStyle) forStyle(style FontStyle) (face Face) {
 	var ok bool
 	switch style {
@@ -386,6 +1003,11 @@ func (fm *FaceWithStyle) Metrics() ebiten_text.Metrics {
 	return fm.forStyle(FontStyleNormal).Metrics()
 }
 
+// Baseline returns the baseline of the FontStyleNormal font. See [Baseline].
+func (fm *FaceWithStyle) Baseline() float64 {
+	return Baseline(fm.forStyle(FontStyleNormal))
+}
+
 // Size returns the font size.
 func (fm *FaceWithStyle) Size() (width, height int) {
 	return fm.forStyle(FontStyleNormal).Size()
@@ -396,6 +1018,19 @@ func (fm *FaceWithStyle) Empty() (glyph *ebiten.Image) {
 	return fm.forStyle(FontStyleNormal).Empty()
 }
 
+// Invalidate drops the cached glyphs of the fonts of all styles, and the
+// scaled glyphs.
+func (fm *FaceWithStyle) Invalidate() {
+	for _, face := range fm.StyleMap {
+		Invalidate(face)
+	}
+
+	for _, glyph := range fm.scaled {
+		glyph.Deallocate()
+	}
+	fm.scaled = nil
+}
+
 // Glyph returns the image for the rune, using the appropriate style font.
 // FontStyleBoldItalic falls back to FontStyleBold
 // FontStyleItalic falls back to FontStyleNormal
@@ -404,6 +1039,27 @@ func (fm *FaceWithStyle) Empty() (glyph *ebiten.Image) {
 // FontStyleNormal must be mapped.
 //
 // Style hints are passed unchanged to the underlying font.
+//
+// Glyphs of styles with a Scale are scaled copies, cached on their first
+// access.
 func (fm *FaceWithStyle) Glyph(character rune, style FontStyle) (glyph *ebiten.Image, is_empty bool) {
-	return fm.forStyle(style).Glyph(character, style)
+	glyph, is_empty = fm.forStyle(style).Glyph(character, style)
+
+	scale, is_scaled := fm.scaleOf(style)
+	if !is_scaled || is_empty {
+		return
+	}
+
+	key := scaledGlyph{character: character, style: style}
+	scaled, ok := fm.scaled[key]
+	if !ok {
+		if fm.scaled == nil {
+			fm.scaled = map[scaledGlyph](*ebiten.Image){}
+		}
+		scaled = fm.scaleGlyph(glyph, scale, Baseline(fm.forStyle(style)))
+		fm.scaled[key] = scaled
+	}
+	glyph = scaled
+
+	return
 }
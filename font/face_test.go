@@ -1,12 +1,19 @@
 package font
 
 import (
+	"fmt"
+	"image"
 	"image/color"
+	"math"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
 )
 
 const full_block = rune('█')
@@ -281,3 +288,454 @@ func TestFaceStyle(t *testing.T) {
 		}
 	}
 }
+
+func TestMonoFontPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, entry := range []struct {
+		dpi    float64
+		pixels float64
+	}{
+		{0, 11},
+		{72, 11},
+		{144, 22},
+	} {
+		pf, err := NewMonoFontFromTTFPoints(nil, 11, entry.dpi)
+		assert.Nil(err)
+
+		mf, err := NewMonoFontFromTTF(nil, entry.pixels)
+		assert.Nil(err)
+
+		w, h := pf.Size()
+		mw, mh := mf.Size()
+		assert.Equal(mw, w)
+		assert.Equal(mh, h)
+	}
+
+	// Doubling the dpi doubles the cell size, give or take rounding.
+	lo, _ := NewMonoFontFromTTFPoints(nil, 11, 72)
+	hi, _ := NewMonoFontFromTTFPoints(nil, 11, 144)
+	lw, lh := lo.Size()
+	hw, hh := hi.Size()
+	assert.InDelta(2*lw, hw, 1)
+	assert.InDelta(2*lh, hh, 1)
+
+	_, err := NewMonoFontFromTTFPoints("a string", 11, 72)
+	assert.Equal(ErrFontType, err)
+}
+
+func TestFaceInvalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	// Set glyphs are kept, as they can not be generated again.
+	cf := &CacheFont{Width: 7, Height: 13}
+	block := ebiten.NewImage(7, 13)
+	cf.SetGlyph(full_block, block)
+	assert.True(cf.HasGlyph(full_block, FontStyleNormal))
+
+	Invalidate(&FaceWithRuneMapping{Face: cf})
+	glyph, _ := cf.Glyph(full_block, FontStyleNormal)
+	assert.Same(block, glyph)
+
+	// Generated glyphs are generated again.
+	mf, err := NewMonoFont(nil)
+	assert.Nil(err)
+	bf, err := NewMonoFont(nil)
+	assert.Nil(err)
+
+	sf := &FaceWithStyle{StyleMap: map[FontStyle]Face{
+		FontStyleNormal: mf,
+		FontStyleBold:   &FaceWithBackup{Face: &FaceWithOnlyRunes{Face: bf, Runes: []rune{'x'}}, Backup: mf},
+	}}
+
+	normal, _ := sf.Glyph(full_block, FontStyleNormal)
+	bold, _ := sf.Glyph('x', FontStyleBold)
+
+	Invalidate(sf)
+
+	again, is_empty := sf.Glyph(full_block, FontStyleNormal)
+	assert.False(is_empty)
+	assert.NotSame(normal, again)
+
+	again, is_empty = sf.Glyph('x', FontStyleBold)
+	assert.False(is_empty)
+	assert.NotSame(bold, again)
+
+	// Glyphs set into a generating face are kept.
+	user := ebiten.NewImage(mf.Size())
+	mf.SetGlyph('u', user)
+	Invalidate(sf)
+	again, _ = sf.Glyph('u', FontStyleNormal)
+	assert.Same(user, again)
+
+	// Faces without caches are unchanged.
+	assert.NotPanics(func() { Invalidate(nil) })
+}
+
+func TestFaceWithStyleMissingNormal(t *testing.T) {
+	assert := assert.New(t)
+
+	mf, err := NewMonoFont(nil)
+	assert.Nil(err)
+
+	// A style map without a normal face is an error.
+	sf, err := NewFaceWithStyle(map[FontStyle]Face{FontStyleBold: mf})
+	assert.Equal(ErrNoNormalStyle, err)
+	assert.Nil(sf)
+
+	sf, err = NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: nil})
+	assert.Equal(ErrNoNormalStyle, err)
+	assert.Nil(sf)
+
+	sf, err = NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: mf, FontStyleBold: mf})
+	assert.Nil(err)
+	assert.Same(mf, sf.StyleMap[FontStyleNormal])
+
+	// Literals without a normal face panic on use.
+	assert.Panics(func() {
+		(&FaceWithStyle{StyleMap: map[FontStyle]Face{FontStyleBold: mf}}).Size()
+	})
+}
+
+func TestSupportsStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	mf, err := NewMonoFont(nil)
+	assert.Nil(err)
+
+	sf, err := NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: mf, FontStyleBold: mf})
+	assert.Nil(err)
+
+	for _, entry := range []struct {
+		face   Face
+		style  FontStyle
+		expect bool
+	}{
+		{mf, FontStyleNormal, true},
+		{mf, FontStyleBold, false},
+		{&CacheFont{}, FontStyleItalic, false},
+		{sf, FontStyleNormal, true},
+		{sf, FontStyleBold, true},
+		{sf, FontStyleItalic, false},
+		{sf, FontStyleBoldItalic, false},
+		{&FaceWithOnlyRunes{Face: sf}, FontStyleBold, true},
+		{&FaceWithRuneMapping{Face: mf}, FontStyleBold, false},
+		{&FaceWithBackup{Face: mf, Backup: sf}, FontStyleBold, true},
+		{&FaceWithBackup{Face: mf, Backup: mf}, FontStyleItalic, false},
+	} {
+		assert.Equal(entry.expect, SupportsStyle(entry.face, entry.style), "%T %v", entry.face, entry.style)
+	}
+}
+
+func TestMonoFontBaselineOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	otf, err := opentype.Parse(gomono.TTF)
+	assert.Nil(err)
+
+	for _, size := range []float64{11, 16, 24} {
+		tf, err := NewMonoFontFromTTF(nil, size)
+		assert.Nil(err)
+
+		xface, err := opentype.NewFace(otf, &opentype.FaceOptions{Size: size, DPI: 72})
+		assert.Nil(err)
+		xf, err := NewMonoFont(xface)
+		assert.Nil(err)
+
+		for _, mf := range []*MonoFont{tf, xf} {
+			// The ink of FULL BLOCK is centered in the cell.
+			ink, ok := mf.inkBounds(full_block)
+			assert.True(ok)
+			top, bottom := ink.Min.Y, mf.Height-ink.Max.Y
+			assert.InDelta(top, bottom, 1, "%T %v: %v in %v", mf.Face, size, ink, mf.Height)
+
+			// Glyphs move with a manual offset.
+			x, ok := mf.inkBounds('x')
+			assert.True(ok)
+			mf.BaselineOffset += 3
+			moved, ok := mf.inkBounds('x')
+			assert.True(ok)
+			assert.Equal(x.Add(image.Point{0, 3}), moved)
+		}
+	}
+}
+
+func TestShapeCells(t *testing.T) {
+	assert := assert.New(t)
+
+	mf, err := NewMonoFont(nil)
+	assert.NoError(err)
+
+	cells := []string{"f", "i", "é", "", "x"}
+	glyphs, ok := ShapeCells(mf, cells, FontStyleNormal)
+	assert.True(ok)
+	assert.Len(glyphs, len(cells))
+	for _, glyph := range glyphs {
+		assert.Equal(image.Rect(0, 0, mf.Width, mf.Height), glyph.Bounds())
+	}
+
+	glyphs, ok = ShapeCells(mf, nil, FontStyleNormal)
+	assert.True(ok)
+	assert.Empty(glyphs)
+
+	fm, err := NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: mf})
+	assert.NoError(err)
+	glyphs, ok = ShapeCells(fm, cells, FontStyleBold)
+	assert.True(ok)
+	assert.Len(glyphs, len(cells))
+
+	_, ok = ShapeCells(&CacheFont{Width: 2, Height: 3}, cells, FontStyleNormal)
+	assert.False(ok)
+
+	// The advances of a proportional font do not accumulate: each cluster
+	// lands at the origin of its cell, on the baseline, to within the
+	// sub-pixel positioning of the glyph images.
+	proportional, err := NewMonoFontFromTTF(goregular.TTF, 16)
+	assert.NoError(err)
+	cells = []string{"W", "i", "W", "i", "m", "l"}
+	placed := proportional.shapeLine(cells)
+	assert.Len(placed, len(cells))
+	for n, glyph := range placed {
+		assert.Equal(n, glyph.cell)
+		x, y := glyph.geom.Apply(glyph.origin_x, glyph.origin_y)
+		assert.InDelta(0, x, 0.5)
+		assert.InDelta(proportional.Baseline(), y, 0.5)
+	}
+}
+
+func TestFaceWithStyleScale(t *testing.T) {
+	assert := assert.New(t)
+
+	mf, err := NewMonoFont(nil)
+	assert.NoError(err)
+	cell := image.Rect(0, 0, mf.Width, mf.Height)
+
+	fm, err := NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: mf})
+	assert.NoError(err)
+	fm.Scale = map[FontStyle]float64{
+		FontStyleItalic: 0.9,
+		FontStyleBold:   1.2,
+	}
+
+	// Unscaled styles use the glyphs of the face.
+	normal, _ := mf.Glyph('M', FontStyleNormal)
+	glyph, is_empty := fm.Glyph('M', FontStyleNormal)
+	assert.False(is_empty)
+	assert.Same(normal, glyph)
+	glyph, _ = fm.Glyph('M', FontStyleBoldItalic)
+	assert.Same(normal, glyph)
+
+	// Scaled glyphs are cached copies, that still fit the cell.
+	for _, style := range []FontStyle{FontStyleItalic, FontStyleBold} {
+		glyph, is_empty := fm.Glyph('M', style)
+		assert.False(is_empty)
+		assert.NotSame(normal, glyph)
+		assert.Equal(cell, glyph.Bounds())
+
+		again, _ := fm.Glyph('M', style)
+		assert.Same(glyph, again)
+	}
+
+	// Empty glyphs are not scaled.
+	glyph, is_empty = fm.Glyph(bad_rune, FontStyleBold)
+	assert.True(is_empty)
+	assert.Same(mf.Empty(), glyph)
+
+	// Shaped cells are scaled, and still fit the cell.
+	glyphs, ok := ShapeCells(fm, []string{"a", "b"}, FontStyleBold)
+	assert.True(ok)
+	for _, glyph := range glyphs {
+		assert.Equal(cell, glyph.Bounds())
+	}
+
+	// Glyphs are scaled about the baseline of their cells, so the ink
+	// baseline row does not move, even with a baseline offset.
+	mf.BaselineOffset += 3
+	ink, ok := mf.inkBounds('H')
+	assert.True(ok)
+	assert.InDelta(float64(ink.Max.Y), Baseline(fm), 1)
+	assert.Equal(Baseline(mf), Baseline(fm))
+	for _, scale := range fm.Scale {
+		geom := scaleGeoM(cell.Size(), scale, Baseline(fm))
+		_, y := geom.Apply(float64(ink.Min.X), float64(ink.Max.Y))
+		assert.InDelta(float64(ink.Max.Y), y, 1)
+		_, y = geom.Apply(0, Baseline(fm))
+		assert.InDelta(Baseline(fm), y, 1e-9)
+	}
+	mf.BaselineOffset -= 3
+
+	// Invalidate drops the scaled glyphs.
+	bold, _ := fm.Glyph('M', FontStyleBold)
+	fm.Invalidate()
+	glyph, _ = fm.Glyph('M', FontStyleBold)
+	assert.NotSame(bold, glyph)
+	assert.Equal(cell, glyph.Bounds())
+}
+
+func TestColorGlyph(t *testing.T) {
+	assert := assert.New(t)
+
+	// A CBDT color bitmap font of the tiger face emoji, from the
+	// HarfBuzz test suite.
+	ttf, err := os.ReadFile("testdata/color_emoji.ttf")
+	assert.NoError(err)
+
+	const tiger = rune(0x1F42F)
+
+	mono, err := NewMonoFont(nil)
+	assert.NoError(err)
+	emoji, err := NewMonoFontFromTTF(ttf, 11)
+	assert.NoError(err)
+	emoji.Width, emoji.Height = mono.Width, mono.Height
+
+	// Color glyphs are cell sized.
+	glyph, is_empty := emoji.Glyph(tiger, FontStyleNormal)
+	assert.False(is_empty)
+	assert.Equal(image.Rect(0, 0, mono.Width, mono.Height), glyph.Bounds())
+
+	assert.True(IsColorGlyph(emoji, tiger, FontStyleNormal))
+	assert.False(IsColorGlyph(emoji, 'a', FontStyleNormal))
+	assert.False(IsColorGlyph(mono, 'a', FontStyleNormal))
+	assert.False(IsColorGlyph(mono, tiger, FontStyleNormal))
+	assert.False(IsColorGlyph(&CacheFont{Width: 2, Height: 3}, tiger, FontStyleNormal))
+
+	// Wrapping faces report the color glyphs of the faces they wrap.
+	backup := &FaceWithBackup{Face: mono, Backup: emoji}
+	assert.True(IsColorGlyph(backup, tiger, FontStyleNormal))
+	assert.False(IsColorGlyph(backup, 'a', FontStyleNormal))
+
+	mapping := &FaceWithRuneMapping{Face: backup, RuneMapping: map[rune]rune{'T': tiger}}
+	assert.True(IsColorGlyph(mapping, 'T', FontStyleNormal))
+
+	only := &FaceWithOnlyRunes{Face: backup, Runes: []rune{'a'}}
+	assert.False(IsColorGlyph(only, tiger, FontStyleNormal))
+	only.Runes = append(only.Runes, tiger)
+	assert.True(IsColorGlyph(only, tiger, FontStyleNormal))
+
+	styled, err := NewFaceWithStyle(map[FontStyle]Face{FontStyleNormal: backup})
+	assert.NoError(err)
+	assert.True(IsColorGlyph(styled, tiger, FontStyleBold))
+}
+
+func TestCacheFontColorGlyph(t *testing.T) {
+	assert := assert.New(t)
+
+	cf := &CacheFont{Width: 2, Height: 3}
+	cf.SetGlyph('a', ebiten.NewImage(2, 3))
+	cf.SetColorGlyph('b', ebiten.NewImage(2, 3))
+	cf.SetColorGlyph('c', nil)
+
+	assert.False(IsColorGlyph(cf, 'a', FontStyleNormal))
+	assert.True(IsColorGlyph(cf, 'b', FontStyleNormal))
+	assert.False(IsColorGlyph(cf, 'c', FontStyleNormal))
+	assert.False(IsColorGlyph(cf, 'd', FontStyleNormal))
+
+	// Mono glyphs replace color glyphs.
+	cf.SetGlyph('b', ebiten.NewImage(2, 3))
+	assert.False(IsColorGlyph(cf, 'b', FontStyleNormal))
+
+	cf.SetColorGlyph('b', ebiten.NewImage(2, 3))
+	cf.Invalidate()
+	assert.True(IsColorGlyph(cf, 'b', FontStyleNormal))
+}
+
+func TestMonoFontPreserveAspect(t *testing.T) {
+	assert := assert.New(t)
+
+	mf, err := NewMonoFontFromTTF(nil, 48)
+	assert.NoError(err)
+	mf.BaselineOffset = 0
+
+	aspect := func(r image.Rectangle) float64 {
+		return float64(r.Dx()) / float64(r.Dy())
+	}
+
+	// The natural aspect of the glyphs, unscaled.
+	mf.drawOptions.GeoM.Reset()
+	natural, ok := mf.inkBounds(full_block)
+	assert.True(ok)
+
+	// A font whose natural aspect differs from the cell's.
+	mf.drawOptions.GeoM.Scale(0.8, 1.3)
+
+	// Filled, glyphs are stretched.
+	fill, ok := mf.inkBounds(full_block)
+	assert.True(ok)
+	assert.InDelta(aspect(natural)*0.8/1.3, aspect(fill), 0.05)
+
+	// Preserved, glyphs keep their aspect, centered in the cell.
+	mf.PreserveAspect = true
+	geom := mf.glyphGeoM()
+	assert.Equal(geom.Element(0, 0), geom.Element(1, 1))
+	preserved, ok := mf.inkBounds(full_block)
+	assert.True(ok)
+	assert.InDelta(aspect(natural), aspect(preserved), 0.05)
+	assert.Less(preserved.Dy(), fill.Dy())
+
+	// The narrower direction is still filled.
+	assert.Equal(fill.Min.X, preserved.Min.X)
+	assert.Equal(fill.Max.X, preserved.Max.X)
+
+	// The automatic baseline offset, of the filled glyphs, still centers
+	// the ink of FULL BLOCK.
+	mf.PreserveAspect = false
+	fill, ok = mf.inkBounds(full_block)
+	assert.True(ok)
+	mf.BaselineOffset = math.Round(float64(mf.Height-fill.Min.Y-fill.Max.Y) / 2)
+	mf.PreserveAspect = true
+	preserved, ok = mf.inkBounds(full_block)
+	assert.True(ok)
+	assert.InDelta(0, mf.Height-preserved.Min.Y-preserved.Max.Y, 2)
+
+	// Glyphs are generated as preserved once invalidated.
+	mf.Invalidate()
+	glyph, is_empty := mf.Glyph('x', FontStyleNormal)
+	assert.False(is_empty)
+	assert.Equal(image.Rect(0, 0, mf.Width, mf.Height), glyph.Bounds())
+}
+
+// nilFace is a custom face that returns nil glyphs.
+type nilFace struct {
+	CacheFont
+}
+
+func (nf *nilFace) Glyph(character rune, style FontStyle) (*ebiten.Image, bool) {
+	return nil, true
+}
+
+func TestGlyphPixels(t *testing.T) {
+	assert := assert.New(t)
+
+	// Nil glyphs read as transparent cells. Reading the pixels of actual
+	// glyphs needs the ebiten game loop, see ExampleGlyphPixels.
+	pixels := GlyphPixels(&nilFace{CacheFont{Width: 4, Height: 6}}, 'x', FontStyleNormal)
+	assert.Equal(image.Rect(0, 0, 4, 6), pixels.Bounds())
+	for y := range 6 {
+		for x := range 4 {
+			_, _, _, a := pixels.At(x, y).RGBA()
+			assert.Zero(a)
+		}
+	}
+}
+
+// ExampleGlyphPixels checks, from within a running ebiten game, that the
+// glyph of FULL BLOCK fills its cell. It has no output to check, as the
+// pixels can only be read once the game loop has started.
+func ExampleGlyphPixels() {
+	face, err := NewMonoFont(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// In Update() or Draw():
+	pixels := GlyphPixels(face, '█', FontStyleNormal)
+	bounds := pixels.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := pixels.At(x, y).RGBA()
+			if a != 0xffff {
+				fmt.Printf("pixel %v, %v is not opaque\n", x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+// Package lineedit supplies a single line text editor, for use with
+// [github.com/ezrec/tcell_ebiten] or any other tcell.Screen.
+package lineedit
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+)
+
+// LineEditor edits a single line of text, drawn into a row of a screen
+// with the cursor, from key events.
+//
+// Left, Right, Home and End (or Ctrl-A and Ctrl-E) move the cursor,
+// Backspace and Delete remove the rune before or at the cursor, Insert
+// toggles between inserting and overwriting typed runes, and Enter ends
+// editing. Combining marks are kept with the rune before them, so the
+// cursor moves, and runes are removed, a whole cluster at a time.
+type LineEditor struct {
+	Style tcell.Style // Style of the text, and of the rest of the row.
+
+	buffer    []rune
+	cursor    int  // Index of the rune at the cursor.
+	scroll    int  // Index of the first rune drawn.
+	overwrite bool // Typed runes replace the rune at the cursor.
+}
+
+// NewLineEditor returns an editor of a text, with the cursor at its end,
+// inserting typed runes.
+func NewLineEditor(text string) (le *LineEditor) {
+	le = &LineEditor{}
+	le.SetText(text)
+
+	return
+}
+
+// SetText replaces the text, and moves the cursor to its end.
+func (le *LineEditor) SetText(text string) {
+	le.buffer = []rune(text)
+	le.cursor = len(le.buffer)
+	le.scroll = 0
+}
+
+// Text returns the text.
+func (le *LineEditor) Text() string {
+	return string(le.buffer)
+}
+
+// Cursor returns the index of the rune at the cursor, which is the
+// number of runes before it.
+func (le *LineEditor) Cursor() int {
+	return le.cursor
+}
+
+// SetCursor moves the cursor to a rune index, clamped to the text.
+func (le *LineEditor) SetCursor(n int) {
+	le.cursor = max(0, min(n, len(le.buffer)))
+}
+
+// Overwrite returns true if typed runes replace the rune at the cursor,
+// rather than being inserted before it.
+func (le *LineEditor) Overwrite() bool {
+	return le.overwrite
+}
+
+// SetOverwrite sets whether typed runes replace the rune at the cursor,
+// rather than being inserted before it. The default is to insert.
+func (le *LineEditor) SetOverwrite(overwrite bool) {
+	le.overwrite = overwrite
+}
+
+// HandleEvent edits the text for a key event, and returns the text and
+// true when Enter is pressed. Events other than key events, and keys
+// that do not edit, are ignored.
+func (le *LineEditor) HandleEvent(ev tcell.Event) (text string, done bool) {
+	ev_key, ok := ev.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	switch ev_key.Key() {
+	case tcell.KeyEnter, tcell.KeyLF:
+		return le.Text(), true
+	case tcell.KeyLeft:
+		le.cursor = le.prevStop(le.cursor)
+	case tcell.KeyRight:
+		le.cursor = le.nextStop(le.cursor)
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		le.cursor = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		le.cursor = len(le.buffer)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		start := le.prevStop(le.cursor)
+		le.buffer = append(le.buffer[:start], le.buffer[le.cursor:]...)
+		le.cursor = start
+	case tcell.KeyDelete:
+		end := le.nextStop(le.cursor)
+		le.buffer = append(le.buffer[:le.cursor], le.buffer[end:]...)
+	case tcell.KeyInsert:
+		le.overwrite = !le.overwrite
+	case tcell.KeyRune:
+		le.typeRune(ev_key.Rune())
+	}
+
+	return
+}
+
+// typeRune inserts a rune at the cursor, or replaces the cluster at the
+// cursor if overwriting, and moves the cursor past it. Combining marks
+// are always inserted, to combine with the rune before them.
+func (le *LineEditor) typeRune(r rune) {
+	end := le.cursor
+	if le.overwrite && runeWidth(r) > 0 {
+		end = le.nextStop(le.cursor)
+	}
+
+	buffer := append([]rune{}, le.buffer[:le.cursor]...)
+	buffer = append(buffer, r)
+	le.buffer = append(buffer, le.buffer[end:]...)
+	le.cursor++
+}
+
+// Draw draws the text into a row of a screen, width cells wide from x, y,
+// filling the rest of the row with spaces, and shows the cursor in it.
+// The text is scrolled horizontally as needed to keep the cursor in view.
+// The screen must be shown for the changes to be visible.
+func (le *LineEditor) Draw(screen tcell.Screen, x, y, width int) {
+	if width <= 0 {
+		return
+	}
+
+	// Scroll so that the cursor, and a cell for it, fits in the row.
+	le.scroll = min(le.scroll, le.cursor)
+	for le.scroll < le.cursor && le.cells(le.scroll, le.cursor)+1 > width {
+		le.scroll = le.nextStop(le.scroll)
+	}
+
+	cx := 0
+	for n := le.scroll; n < len(le.buffer); {
+		end := le.nextStop(n)
+		w := max(1, runeWidth(le.buffer[n]))
+		if cx+w > width {
+			break
+		}
+		screen.SetContent(x+cx, y, le.buffer[n], le.buffer[n+1:end], le.Style)
+		cx += w
+		n = end
+	}
+
+	for ; cx < width; cx++ {
+		screen.SetContent(x+cx, y, ' ', nil, le.Style)
+	}
+
+	screen.ShowCursor(x+le.cells(le.scroll, le.cursor), y)
+}
+
+// cells returns the number of cells the runes from start to end are drawn in.
+func (le *LineEditor) cells(start, end int) (cells int) {
+	for n := start; n < end; n = le.nextStop(n) {
+		cells += max(1, runeWidth(le.buffer[n]))
+	}
+
+	return
+}
+
+// nextStop returns the index of the cluster after that at a rune index.
+func (le *LineEditor) nextStop(n int) int {
+	n = min(n+1, len(le.buffer))
+	for n < len(le.buffer) && runeWidth(le.buffer[n]) == 0 {
+		n++
+	}
+
+	return n
+}
+
+// prevStop returns the index of the cluster before that at a rune index.
+func (le *LineEditor) prevStop(n int) int {
+	n = max(n-1, 0)
+	for n > 0 && runeWidth(le.buffer[n]) == 0 {
+		n--
+	}
+
+	return n
+}
+
+// runeWidth returns the width of a rune, in cells, as
+// tcell_ebiten.DefaultRuneWidth measures it.
+func runeWidth(r rune) int {
+	return min(uniseg.StringWidth(string(r)), 2)
+}
@@ -0,0 +1,162 @@
+package lineedit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func key(k tcell.Key) *tcell.EventKey {
+	return tcell.NewEventKey(k, 0, tcell.ModNone)
+}
+
+func typed(s string) (events []tcell.Event) {
+	for _, r := range s {
+		events = append(events, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+
+	return
+}
+
+func TestLineEditorEditing(t *testing.T) {
+	assert := assert.New(t)
+
+	le := NewLineEditor("hello")
+	assert.Equal("hello", le.Text())
+	assert.Equal(5, le.Cursor())
+
+	for _, ev := range typed(" world") {
+		le.HandleEvent(ev)
+	}
+	assert.Equal("hello world", le.Text())
+
+	le.HandleEvent(key(tcell.KeyHome))
+	assert.Equal(0, le.Cursor())
+	le.HandleEvent(key(tcell.KeyDelete))
+	assert.Equal("ello world", le.Text())
+	le.HandleEvent(typed("J")[0])
+	assert.Equal("Jello world", le.Text())
+
+	le.HandleEvent(key(tcell.KeyEnd))
+	le.HandleEvent(key(tcell.KeyBackspace2))
+	le.HandleEvent(key(tcell.KeyBackspace))
+	assert.Equal("Jello wor", le.Text())
+
+	le.HandleEvent(key(tcell.KeyLeft))
+	le.HandleEvent(key(tcell.KeyLeft))
+	le.HandleEvent(key(tcell.KeyRight))
+	assert.Equal(8, le.Cursor())
+
+	// Edges are safe.
+	le.HandleEvent(key(tcell.KeyCtrlE))
+	le.HandleEvent(key(tcell.KeyRight))
+	le.HandleEvent(key(tcell.KeyDelete))
+	assert.Equal(9, le.Cursor())
+	le.HandleEvent(key(tcell.KeyCtrlA))
+	le.HandleEvent(key(tcell.KeyLeft))
+	le.HandleEvent(key(tcell.KeyBackspace2))
+	assert.Equal(0, le.Cursor())
+	assert.Equal("Jello wor", le.Text())
+
+	// Other events are ignored.
+	text, done := le.HandleEvent(tcell.NewEventInterrupt(nil))
+	assert.False(done)
+	assert.Equal("", text)
+
+	text, done = le.HandleEvent(key(tcell.KeyEnter))
+	assert.True(done)
+	assert.Equal("Jello wor", text)
+}
+
+func TestLineEditorOverwrite(t *testing.T) {
+	assert := assert.New(t)
+
+	le := NewLineEditor("abcd")
+	le.SetCursor(1)
+	assert.False(le.Overwrite())
+
+	le.HandleEvent(key(tcell.KeyInsert))
+	assert.True(le.Overwrite())
+	for _, ev := range typed("XYZW") {
+		le.HandleEvent(ev)
+	}
+	assert.Equal("aXYZW", le.Text())
+	assert.Equal(5, le.Cursor())
+
+	le.SetOverwrite(false)
+	le.SetCursor(1)
+	le.HandleEvent(typed("-")[0])
+	assert.Equal("a-XYZW", le.Text())
+
+	le.SetCursor(100)
+	assert.Equal(6, le.Cursor())
+}
+
+func TestLineEditorClusters(t *testing.T) {
+	assert := assert.New(t)
+
+	le := NewLineEditor("ae\u0301b")
+	le.HandleEvent(key(tcell.KeyLeft))
+	le.HandleEvent(key(tcell.KeyLeft))
+	assert.Equal(1, le.Cursor())
+
+	le.HandleEvent(key(tcell.KeyRight))
+	assert.Equal(3, le.Cursor())
+
+	le.HandleEvent(key(tcell.KeyBackspace2))
+	assert.Equal("ab", le.Text())
+
+	// Combining marks combine, even when overwriting.
+	le.SetOverwrite(true)
+	le.SetCursor(1)
+	le.HandleEvent(typed("\u0301")[0])
+	assert.Equal("a\u0301b", le.Text())
+	le.HandleEvent(key(tcell.KeyDelete))
+	assert.Equal("a\u0301", le.Text())
+}
+
+func TestLineEditorDraw(t *testing.T) {
+	assert := assert.New(t)
+
+	screen := tcell.NewSimulationScreen("")
+	assert.NoError(screen.Init())
+	defer screen.Fini()
+	screen.SetSize(10, 3)
+
+	row := func() (text string) {
+		for x := 0; x < 10; x++ {
+			r, _, _, _ := screen.GetContent(x, 1)
+			text += string(r)
+		}
+		return
+	}
+
+	le := NewLineEditor("abc")
+	le.Draw(screen, 2, 1, 6)
+	assert.Equal("  abc     ", row())
+	x, y, visible := screen.GetCursor()
+	assert.True(visible)
+	assert.Equal(5, x)
+	assert.Equal(1, y)
+
+	// The text scrolls to keep the cursor in view.
+	le.SetText("abcdefghij")
+	le.Draw(screen, 2, 1, 6)
+	assert.Equal("  fghij   ", row())
+	x, _, _ = screen.GetCursor()
+	assert.Equal(7, x)
+
+	le.HandleEvent(key(tcell.KeyHome))
+	le.Draw(screen, 2, 1, 6)
+	assert.Equal("  abcdef  ", row())
+	x, _, _ = screen.GetCursor()
+	assert.Equal(2, x)
+
+	// Wide runes take two cells.
+	le.SetText("世界")
+	le.Draw(screen, 2, 1, 6)
+	x, _, _ = screen.GetCursor()
+	assert.Equal(6, x)
+}
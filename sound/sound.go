@@ -0,0 +1,171 @@
+// Copyright 2024, Jason S. McMullan <jason.mcmullan@gmail.com>
+
+// Package sound plays named alert sounds through the ebiten audio context,
+// for applications that want more than a single bell, such as distinct
+// error and notification sounds. Hook a Sounds to the screen's Beep() with
+// ETCell.OnBeep:
+//
+//	sounds := sound.NewSounds(0)
+//	sounds.RegisterFunc("error", 200*time.Millisecond, sound.Tone(220))
+//	et.OnBeep(sounds.Beep)
+//
+// # Audio context lifecycle
+//
+// ebiten allows a single audio context per process, which can not be
+// closed. A Sounds uses the current context, or creates one at its sample
+// rate on the first Play, which then lives for the rest of the process.
+// Applications with their own audio should create the context first, and
+// create their Sounds with the same sample rate.
+package sound
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// DefaultSampleRate is the sample rate of a Sounds created with a rate of 0.
+const DefaultSampleRate = 48000
+
+// BeepSound is the name of the sound played by Beep.
+const BeepSound = "beep"
+
+// ErrUnknownSound is returned when playing a sound that is not registered.
+var ErrUnknownSound = errors.New("unknown sound")
+
+// ErrSampleRate is returned when playing a sound with an audio context of
+// a different sample rate.
+var ErrSampleRate = errors.New("audio context sample rate mismatch")
+
+// context_lock serializes creating the audio context, which panics if one
+// already exists.
+var context_lock sync.Mutex
+
+// Sounds is a registry of named sounds. It is safe for concurrent use.
+type Sounds struct {
+	sample_rate int
+
+	lock   sync.Mutex
+	sounds map[string][]byte // PCM of each sound.
+}
+
+// NewSounds returns a registry of sounds at a sample rate, or at
+// DefaultSampleRate if it is 0, with BeepSound registered as a short
+// 880Hz tone.
+func NewSounds(sample_rate int) (s *Sounds) {
+	if sample_rate <= 0 {
+		sample_rate = DefaultSampleRate
+	}
+
+	s = &Sounds{
+		sample_rate: sample_rate,
+		sounds:      map[string][]byte{},
+	}
+	s.RegisterFunc(BeepSound, 100*time.Millisecond, Tone(880))
+
+	return
+}
+
+// SampleRate returns the sample rate of the sounds.
+func (s *Sounds) SampleRate() int {
+	return s.sample_rate
+}
+
+// Register registers a sound by name, replacing any of the same name.
+// The PCM is in the format of ebiten audio players: signed 16-bit little
+// endian stereo samples, at the sample rate of the sounds. A nil PCM
+// unregisters the sound.
+func (s *Sounds) Register(name string, pcm []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if pcm == nil {
+		delete(s.sounds, name)
+		return
+	}
+
+	s.sounds[name] = pcm
+}
+
+// RegisterFunc registers a sound by name, generated for a duration from a
+// function of the time in seconds, returning samples from -1 to 1.
+func (s *Sounds) RegisterFunc(name string, duration time.Duration, fn func(t float64) float64) {
+	samples := int(duration.Seconds() * float64(s.sample_rate))
+
+	pcm := make([]byte, 4*samples)
+	for n := 0; n < samples; n++ {
+		value := max(-1, min(fn(float64(n)/float64(s.sample_rate)), 1))
+		sample := uint16(int16(math.Round(value * math.MaxInt16)))
+		binary.LittleEndian.PutUint16(pcm[4*n:], sample)
+		binary.LittleEndian.PutUint16(pcm[4*n+2:], sample)
+	}
+
+	s.Register(name, pcm)
+}
+
+// Sound returns the PCM of a registered sound.
+func (s *Sounds) Sound(name string) (pcm []byte, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pcm, ok = s.sounds[name]
+
+	return
+}
+
+// Names returns the names of the registered sounds, sorted.
+func (s *Sounds) Names() (names []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for name := range s.sounds {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return
+}
+
+// Play starts playing a registered sound, and returns without waiting for
+// it to finish. See the package documentation for the audio context used.
+func (s *Sounds) Play(name string) (err error) {
+	pcm, ok := s.Sound(name)
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrUnknownSound, name)
+		return
+	}
+
+	context_lock.Lock()
+	context := audio.CurrentContext()
+	if context == nil {
+		context = audio.NewContext(s.sample_rate)
+	}
+	context_lock.Unlock()
+
+	if context.SampleRate() != s.sample_rate {
+		err = fmt.Errorf("%w: %v, not %v", ErrSampleRate, context.SampleRate(), s.sample_rate)
+		return
+	}
+
+	context.NewPlayerFromBytes(pcm).Play()
+
+	return
+}
+
+// Beep plays BeepSound, for ETCell.OnBeep.
+func (s *Sounds) Beep() error {
+	return s.Play(BeepSound)
+}
+
+// Tone returns a sine wave of a frequency, in Hz, for RegisterFunc.
+func Tone(frequency float64) func(t float64) float64 {
+	return func(t float64) float64 {
+		return math.Sin(2 * math.Pi * frequency * t)
+	}
+}
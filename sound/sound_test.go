@@ -0,0 +1,72 @@
+package sound
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoundsRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewSounds(0)
+	assert.Equal(DefaultSampleRate, s.SampleRate())
+	assert.Equal([]string{BeepSound}, s.Names())
+
+	pcm := []byte{1, 2, 3, 4}
+	s.Register("notify", pcm)
+	got, ok := s.Sound("notify")
+	assert.True(ok)
+	assert.Equal(pcm, got)
+	assert.Equal([]string{BeepSound, "notify"}, s.Names())
+
+	// Nil unregisters.
+	s.Register("notify", nil)
+	_, ok = s.Sound("notify")
+	assert.False(ok)
+
+	// Unknown sounds are not played.
+	err := s.Play("missing")
+	assert.True(errors.Is(err, ErrUnknownSound))
+}
+
+func TestSoundsRegisterFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewSounds(1000)
+
+	beep, ok := s.Sound(BeepSound)
+	assert.True(ok)
+	assert.Len(beep, 4*100)
+
+	// Samples are clamped, and the same in both channels.
+	s.RegisterFunc("square", 10*time.Millisecond, func(t float64) float64 {
+		if t < 0.005 {
+			return 2
+		}
+		return -0.5
+	})
+	pcm, ok := s.Sound("square")
+	assert.True(ok)
+	assert.Len(pcm, 4*10)
+
+	sample := func(n, channel int) int16 {
+		return int16(binary.LittleEndian.Uint16(pcm[4*n+2*channel:]))
+	}
+	assert.Equal(int16(32767), sample(0, 0))
+	assert.Equal(int16(32767), sample(0, 1))
+	assert.Equal(int16(-16384), sample(9, 0))
+	assert.Equal(int16(-16384), sample(9, 1))
+}
+
+func TestTone(t *testing.T) {
+	assert := assert.New(t)
+
+	tone := Tone(1)
+	assert.InDelta(0, tone(0), 1e-9)
+	assert.InDelta(1, tone(0.25), 1e-9)
+	assert.InDelta(-1, tone(0.75), 1e-9)
+}